@@ -0,0 +1,193 @@
+// Copyright 2014 The Azul3D Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package gfx
+
+import (
+	"image"
+	"strconv"
+
+	"azul3d.org/clock.v1"
+	"azul3d.org/lmath.v1"
+)
+
+// The names of the built-in shader inputs, which renderers provide to every
+// shader program that declares them. A shader input of the same name (see
+// Shader.Inputs and Object.InputOverrides) overrides the built-in one.
+//
+// The GLSL declaration of each is:
+//  uniform mat4 Model;        // Local-to-world matrix of the object.
+//  uniform mat4 View;         // World-to-camera matrix, Y up (Camera.View).
+//  uniform mat4 Projection;   // Projection matrix of the camera.
+//  uniform mat4 MVP;          // Model * View * Projection.
+//  uniform mat3 NormalMatrix; // Inverse transpose of mat3(Model * View).
+//  uniform float Time;        // Seconds since the renderer's clock started.
+//  uniform vec4 Viewport;     // Draw rectangle: x, y, width, height.
+const (
+	InputModel        = "Model"
+	InputView         = "View"
+	InputProjection   = "Projection"
+	InputMVP          = "MVP"
+	InputNormalMatrix = "NormalMatrix"
+	InputTime         = "Time"
+	InputViewport     = "Viewport"
+)
+
+// BuiltinInputs is a read-only slice of the names of all built-in shader
+// inputs.
+var BuiltinInputs = []string{
+	InputModel,
+	InputView,
+	InputProjection,
+	InputMVP,
+	InputNormalMatrix,
+	InputTime,
+	InputViewport,
+}
+
+// The names of the built-in vertex attributes, which renderers feed from the
+// data slices of a mesh to every shader program that declares them. A vertex
+// attribute of the same name (see Mesh.Attribs) overrides the built-in one.
+//
+// The GLSL declaration of each is:
+//  attribute vec3 Vertex;    // Mesh.Vertices
+//  attribute vec3 Normal;    // Mesh.Normals
+//  attribute vec4 Tangent;   // Mesh.Tangents
+//  attribute vec4 Color;     // Mesh.Colors
+//  attribute vec3 Bary;      // Mesh.Bary
+//  attribute vec2 TexCoord0; // Mesh.TexCoords[0], see TexCoordAttrib.
+const (
+	AttribVertex  = "Vertex"
+	AttribNormal  = "Normal"
+	AttribTangent = "Tangent"
+	AttribColor   = "Color"
+	AttribBary    = "Bary"
+)
+
+// TexCoordAttrib returns the name of the built-in vertex attribute that is fed
+// from the texture coordinate set at the given index of a mesh's TexCoords
+// slice, e.g.:
+//  TexCoordAttrib(1) == "TexCoord1"
+func TexCoordAttrib(index int) string {
+	return "TexCoord" + strconv.Itoa(index)
+}
+
+// BuiltinAttribs returns the names of the built-in vertex attributes that are
+// fed from the data slices of the given mesh, in order: Vertex, Normal,
+// Tangent, Color, Bary, and one for each texture coordinate set. Only the
+// names of non-empty data slices are returned, note that the data slices of a
+// loaded mesh are cleared unless it's KeepDataOnLoad field is set.
+//
+// The mesh's read lock must be held for this function to operate safely.
+func BuiltinAttribs(m *Mesh) []string {
+	var names []string
+	if len(m.Vertices) > 0 {
+		names = append(names, AttribVertex)
+	}
+	if len(m.Normals) > 0 {
+		names = append(names, AttribNormal)
+	}
+	if len(m.Tangents) > 0 {
+		names = append(names, AttribTangent)
+	}
+	if len(m.Colors) > 0 {
+		names = append(names, AttribColor)
+	}
+	if len(m.Bary) > 0 {
+		names = append(names, AttribBary)
+	}
+	for i, set := range m.TexCoords {
+		if len(set.Slice) > 0 {
+			names = append(names, TexCoordAttrib(i))
+		}
+	}
+	return names
+}
+
+// Builtins holds the values of the built-in shader inputs for a single draw
+// operation.
+//
+// Note that matrices follow the row-vector convention of the lmath package,
+// that is a vertex is transformed by the MVP matrix as:
+//  gl_Position = vec4(Vertex, 1.0) * MVP;
+type Builtins struct {
+	Model        Mat4    `gfx:"Model"`
+	View         Mat4    `gfx:"View"`
+	Projection   Mat4    `gfx:"Projection"`
+	MVP          Mat4    `gfx:"MVP"`
+	NormalMatrix Mat3    `gfx:"NormalMatrix"`
+	Time         float32 `gfx:"Time"`
+	Viewport     Vec4    `gfx:"Viewport"`
+}
+
+// Apply stores each built-in input into the given map of shader inputs, under
+// the names listed in BuiltinInputs.
+func (b *Builtins) Apply(inputs map[string]interface{}) {
+	builtinBinding.Apply(inputs, b)
+}
+
+var builtinBinding *InputBinding
+
+func init() {
+	var err error
+	builtinBinding, err = NewInputBinding(Builtins{})
+	if err != nil {
+		panic(err)
+	}
+}
+
+// NewBuiltins computes the built-in shader inputs for drawing the object onto
+// the given rectangle of the canvas (the entire canvas if it is empty), as
+// seen by the camera. If the camera is nil, the View and Projection matrices
+// are identity matrices. If the clock is nil, Time is zero.
+//
+// The object's read lock must be held for this function to operate safely.
+// The camera is properly read-locked by this function.
+func NewBuiltins(r image.Rectangle, o *Object, cam *Camera, canvas Canvas, clk *clock.Clock) *Builtins {
+	view := lmath.Mat4Identity
+	projection := lmath.Mat4Identity
+	if cam != nil {
+		cam.RLock()
+		view = cam.View()
+		projection = cam.Projection.Mat4()
+		cam.RUnlock()
+	}
+
+	model := lmath.Mat4Identity
+	if o.Transform != nil {
+		model = o.Transform.Mat4()
+	}
+	modelView := model.Mul(view)
+
+	// The normal matrix is the inverse transpose of the model-view matrix,
+	// such that normals remain perpendicular under non-uniform scaling.
+	normal := modelView.UpperMat3()
+	if inv, ok := normal.Inverse(); ok {
+		normal = inv.Transposed()
+	}
+
+	bounds := canvas.Bounds()
+	if r.Empty() {
+		r = bounds
+	}
+	r = r.Intersect(bounds)
+
+	b := &Builtins{
+		Model:        ConvertMat4(model),
+		View:         ConvertMat4(view),
+		Projection:   ConvertMat4(projection),
+		MVP:          ConvertMat4(modelView.Mul(projection)),
+		NormalMatrix: ConvertMat3(normal),
+		Viewport: Vec4{
+			X: float32(r.Min.X),
+			Y: float32(r.Min.Y),
+			Z: float32(r.Dx()),
+			W: float32(r.Dy()),
+		},
+	}
+	if clk != nil {
+		b.Time = float32(clk.Time().Seconds())
+	}
+	return b
+}
@@ -0,0 +1,65 @@
+// Copyright 2014 The Azul3D Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package gfx
+
+import (
+	"image"
+	"testing"
+
+	"azul3d.org/lmath.v1"
+)
+
+func TestBuiltins(t *testing.T) {
+	r := Nil()
+
+	o := NewObject()
+	o.Transform.SetPos(lmath.Vec3{1, 2, 3})
+	o.Transform.SetScale(lmath.Vec3{2, 2, 2})
+
+	cam := NewCamera()
+	cam.SetPersp(r.Bounds(), 75, 0.1, 100)
+	cam.Transform.SetPos(lmath.Vec3{0, -10, 0})
+
+	b := NewBuiltins(image.Rect(10, 20, 110, 70), o, cam, r, r.Clock())
+
+	model := o.Transform.Mat4()
+	view := cam.View()
+	mvp := model.Mul(view).Mul(cam.Projection.Mat4())
+	if !b.Model.Mat4().AlmostEquals(model, 1e-6) || !b.View.Mat4().AlmostEquals(view, 1e-6) {
+		t.Fatal("got model", b.Model, "view", b.View)
+	}
+	if !b.MVP.Mat4().AlmostEquals(mvp, 1e-5) {
+		t.Fatal("got MVP", b.MVP, "want", mvp)
+	}
+	if b.Viewport != (Vec4{10, 20, 100, 50}) {
+		t.Fatal("got viewport", b.Viewport)
+	}
+
+	// Uniform scaling by two is undone by the normal matrix.
+	n := b.NormalMatrix.Mat3()
+	if !lmath.AlmostEqual(n[0][0], 0.5, 1e-6) {
+		t.Fatal("got normal matrix", n)
+	}
+
+	inputs := make(map[string]interface{})
+	b.Apply(inputs)
+	if len(inputs) != len(BuiltinInputs) {
+		t.Fatal("got inputs", inputs)
+	}
+	for _, name := range BuiltinInputs {
+		if _, ok := inputs[name]; !ok {
+			t.Error("missing built-in input", name)
+		}
+	}
+	if inputs[InputMVP] != b.MVP {
+		t.Error("got MVP input", inputs[InputMVP])
+	}
+
+	// Without a camera the view and projection are identity matrices.
+	b = NewBuiltins(image.Rectangle{}, o, nil, r, nil)
+	if b.Projection != ConvertMat4(lmath.Mat4Identity) || b.Viewport != (Vec4{0, 0, 640, 480}) || b.Time != 0 {
+		t.Fatal("got", b)
+	}
+}
@@ -72,6 +72,18 @@ func (c *Camera) SetPersp(view image.Rectangle, fov, near, far float64) {
 	c.Projection = ConvertMat4(m)
 }
 
+// View returns the view matrix of this camera, which transforms world
+// coordinates into camera coordinates. It is the inverse of the camera's
+// local-to-world transformation, converted from the Z up right-handed
+// coordinate system into the Y up right-handed one expected by the
+// projection matrix.
+//
+// The camera's read lock must be held for this method to operate safely.
+func (c *Camera) View() lmath.Mat4 {
+	cameraInv, _ := c.Object.Transform.Mat4().Inverse()
+	return cameraInv.Mul(zUpRightToYUpRight)
+}
+
 // Project returns a 2D point in normalized device space coordinates given a 3D
 // point in the world.
 //
@@ -80,11 +92,8 @@ func (c *Camera) SetPersp(view image.Rectangle, fov, near, far float64) {
 //
 // The camera's read lock must be held for this method to operate safely.
 func (c *Camera) Project(p3 lmath.Vec3) (p2 lmath.Vec2, ok bool) {
-	cameraInv, _ := c.Object.Transform.Mat4().Inverse()
-	cameraInv = cameraInv.Mul(zUpRightToYUpRight)
-
 	projection := c.Projection.Mat4()
-	vp := cameraInv.Mul(projection)
+	vp := c.View().Mul(projection)
 
 	p2, ok = vp.Project(p3)
 	return
@@ -0,0 +1,187 @@
+// Copyright 2014 The Azul3D Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package glsl
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+
+	"azul3d.org/gfx.v1"
+)
+
+// inputTypes maps each GLSL type to the Go type of a shader input (or the
+// element type of a vertex attribute's data slice) that feeds it. Whether the
+// Go type is actually supported is told by gfx.ValidInput and gfx.ValidAttrib.
+var inputTypes = map[string]reflect.Type{
+	"bool":  reflect.TypeOf(false),
+	"float": reflect.TypeOf(float32(0)),
+	"int":   reflect.TypeOf(int32(0)),
+	"vec2":  reflect.TypeOf(gfx.Vec2{}),
+	"vec3":  reflect.TypeOf(gfx.Vec3{}),
+	"vec4":  reflect.TypeOf(gfx.Vec4{}),
+	"ivec2": reflect.TypeOf(gfx.IVec2{}),
+	"ivec3": reflect.TypeOf(gfx.IVec3{}),
+	"ivec4": reflect.TypeOf(gfx.IVec4{}),
+	"mat3":  reflect.TypeOf(gfx.Mat3{}),
+	"mat4":  reflect.TypeOf(gfx.Mat4{}),
+}
+
+// InputType returns the Go type of the shader input that feeds the variable,
+// e.g. gfx.Vec3 for a vec3 or []gfx.Vec3 for a vec3 array. If no supported Go
+// type feeds the variable's GLSL type, ok is false.
+func (v Var) InputType() (t reflect.Type, ok bool) {
+	t, ok = inputTypes[v.Type]
+	if !ok {
+		return nil, false
+	}
+	if v.IsArray() {
+		t = reflect.SliceOf(t)
+	}
+	if !gfx.ValidInput(reflect.Zero(t).Interface()) {
+		return nil, false
+	}
+	return t, true
+}
+
+// AttribType returns the Go type of the vertex attribute data that feeds the
+// variable, e.g. []gfx.Vec3 for a vec3 or [][]gfx.Vec3 for a vec3 array. If no
+// supported Go type feeds the variable's GLSL type, ok is false.
+func (v Var) AttribType() (t reflect.Type, ok bool) {
+	t, ok = inputTypes[v.Type]
+	if !ok {
+		return nil, false
+	}
+	if v.IsArray() {
+		t = reflect.SliceOf(t)
+	}
+	t = reflect.SliceOf(t)
+	if !gfx.ValidAttrib(reflect.Zero(t).Interface()) {
+		return nil, false
+	}
+	return t, true
+}
+
+// CheckError describes a shader input or vertex attribute that does not match
+// the declaration of the variable that it feeds.
+type CheckError struct {
+	// The declared variable.
+	Var Var
+
+	// The value fed to the variable, or nil if it is missing.
+	Value interface{}
+
+	// A description of the mismatch.
+	Msg string
+}
+
+// Error implements the error interface.
+func (e *CheckError) Error() string {
+	return fmt.Sprintf("glsl: %v: %s", e.Var, e.Msg)
+}
+
+func has(names []string, name string) bool {
+	for _, n := range names {
+		if n == name {
+			return true
+		}
+	}
+	return false
+}
+
+// check checks that the value matches the Go type expected by the variable,
+// and returns an error if it does not.
+func check(v Var, value interface{}, want reflect.Type, ok bool) error {
+	if !ok {
+		return &CheckError{v, value, fmt.Sprintf("no Go type feeds GLSL type %s, got %T", v.Type, value)}
+	}
+	got := reflect.TypeOf(value)
+	if got != want {
+		return &CheckError{v, value, fmt.Sprintf("got %T, want %v", value, want)}
+	}
+	return nil
+}
+
+// CheckInputs checks the given shader inputs (see Shader.Inputs) against the
+// uniforms of the program. An error is returned for each input whose type does
+// not match the uniform it feeds, each array input with more elements than
+// the uniform declares, and each uniform that has no input (unless it's name
+// is one of the provided names, e.g. inputs provided by the renderer).
+//
+// Samplers are fed by textures instead of inputs and are not checked. Inputs
+// that feed no uniform are not errors, as compilers remove unused uniforms.
+func (p *Program) CheckInputs(inputs map[string]interface{}, provided ...string) []error {
+	var errs []error
+	for _, u := range p.Uniforms {
+		value, ok := inputs[u.Name]
+		if !ok {
+			if !has(provided, u.Name) {
+				errs = append(errs, &CheckError{u, nil, "missing input"})
+			}
+			continue
+		}
+		want, ok := u.InputType()
+		if err := check(u, value, want, ok); err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		if u.ArraySize > 0 {
+			if n := reflect.ValueOf(value).Len(); n > u.ArraySize {
+				errs = append(errs, &CheckError{u, value, fmt.Sprintf("got %d elements, want at most %d", n, u.ArraySize)})
+			}
+		}
+	}
+	return errs
+}
+
+// CheckAttribs checks the given vertex attributes (see Mesh.Attribs) against
+// the attributes of the program. An error is returned for each vertex
+// attribute whose data type does not match the attribute it feeds, and each
+// attribute that has no vertex attribute (unless it's name is one of the
+// provided names, e.g. attributes provided by the renderer from the mesh's
+// Vertices or Colors).
+func (p *Program) CheckAttribs(attribs map[string]gfx.VertexAttrib, provided ...string) []error {
+	var errs []error
+	for _, a := range p.Attributes {
+		attrib, ok := attribs[a.Name]
+		if !ok {
+			if !has(provided, a.Name) {
+				errs = append(errs, &CheckError{a, nil, "missing vertex attribute"})
+			}
+			continue
+		}
+		want, ok := a.AttribType()
+		if err := check(a, attrib.Data, want, ok); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errs
+}
+
+// CheckTextures checks the textures of the given object (see Object.Texture)
+// against the samplers of the program. An error is returned for each sampler
+// that has no texture bound to it, and each named texture binding (see
+// Object.Samplers) whose name is not a sampler declared by the program.
+//
+// The object's read lock must be held for this method to operate safely.
+func (p *Program) CheckTextures(o *gfx.Object) []error {
+	var errs []error
+	for _, s := range p.Samplers {
+		if o.Texture(s.Name) == nil {
+			errs = append(errs, &CheckError{s, nil, "missing texture"})
+		}
+	}
+	var names []string
+	for name := range o.Samplers {
+		if _, ok := lookup(p.Samplers, name); !ok {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		errs = append(errs, fmt.Errorf("glsl: texture bound to undeclared sampler %q", name))
+	}
+	return errs
+}
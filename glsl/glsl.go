@@ -0,0 +1,478 @@
+// Copyright 2014 The Azul3D Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package glsl reflects the declarations of GLSL shader programs.
+//
+// It parses the GLSLVert and GLSLFrag sources of a gfx.Shader and lists the
+// uniforms, samplers, attributes, and varyings that the program declares,
+// along with their GLSL types and array sizes:
+//  shader.RLock()
+//  prog, err := glsl.Reflect(shader)
+//  shader.RUnlock()
+//  for _, u := range prog.Uniforms {
+//      fmt.Println(u) // e.g. "uniform mat4 MVP"
+//  }
+//
+// The program can then be used to type-check the values that would be fed to
+// it (see the CheckInputs and CheckAttribs methods), which renderers would
+// otherwise silently ignore.
+//
+// Shader sources that share code may be built using a Preprocessor, which
+// resolves #include directives and produces a shader variant for each set of
+// #define directives.
+//
+// The parser is not a full GLSL compiler: only top-level declarations are
+// inspected, function bodies and preprocessor directives (other than #line)
+// are skipped, and interface blocks and struct definitions are ignored.
+package glsl
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"azul3d.org/gfx.v1"
+)
+
+// Stage is a programmable stage of the graphics pipeline.
+type Stage uint8
+
+const (
+	// The vertex shader stage (i.e. Shader.GLSLVert).
+	Vertex Stage = iota
+
+	// The fragment shader stage (i.e. Shader.GLSLFrag).
+	Fragment
+)
+
+// String returns a string representation of this stage.
+// e.g. Vertex -> "vertex"
+func (s Stage) String() string {
+	switch s {
+	case Vertex:
+		return "vertex"
+	case Fragment:
+		return "fragment"
+	}
+	return fmt.Sprintf("Stage(%d)", s)
+}
+
+// Qualifier describes how a declared variable is fed to the program.
+type Qualifier uint8
+
+const (
+	// A uniform variable, fed by a shader input (or a texture for samplers).
+	Uniform Qualifier = iota
+
+	// A per-vertex attribute variable (declared using either the attribute
+	// or in qualifiers inside the vertex shader), fed by mesh data.
+	Attribute
+
+	// A varying variable, passed from the vertex shader to the fragment
+	// shader (declared using either the varying or in/out qualifiers).
+	Varying
+)
+
+// String returns the GLSL keyword of this qualifier.
+// e.g. Attribute -> "attribute"
+func (q Qualifier) String() string {
+	switch q {
+	case Uniform:
+		return "uniform"
+	case Attribute:
+		return "attribute"
+	case Varying:
+		return "varying"
+	}
+	return fmt.Sprintf("Qualifier(%d)", q)
+}
+
+// Var describes a single declared variable.
+type Var struct {
+	// The qualifier of the variable.
+	Qualifier Qualifier
+
+	// The GLSL type of the variable, e.g. "vec3" or "sampler2D".
+	Type string
+
+	// The name of the variable.
+	Name string
+
+	// The array size of the variable. Zero if the variable is not an array,
+	// or -1 if it is an array whose size is not an integer literal (e.g. a
+	// preprocessor macro) or is not specified.
+	ArraySize int
+
+	// The stage and source line where the variable is (first) declared.
+	Stage Stage
+	Line  int
+}
+
+// IsArray tells if the variable is an array.
+func (v Var) IsArray() bool {
+	return v.ArraySize != 0
+}
+
+// IsSampler tells if the variable is of a sampler type (e.g. sampler2D).
+func (v Var) IsSampler() bool {
+	t := strings.TrimLeft(v.Type, "iu")
+	return strings.HasPrefix(t, "sampler")
+}
+
+// String returns the GLSL declaration of the variable.
+// e.g. "uniform vec3 Lights[4]"
+func (v Var) String() string {
+	s := fmt.Sprintf("%s %s %s", v.Qualifier, v.Type, v.Name)
+	switch {
+	case v.ArraySize > 0:
+		s += fmt.Sprintf("[%d]", v.ArraySize)
+	case v.ArraySize < 0:
+		s += "[]"
+	}
+	return s
+}
+
+// SyntaxError describes a malformed declaration.
+type SyntaxError struct {
+	Stage Stage
+	Line  int
+	Msg   string
+}
+
+// Error implements the error interface.
+func (e *SyntaxError) Error() string {
+	return fmt.Sprintf("glsl: %s shader: line %d: %s", e.Stage, e.Line, e.Msg)
+}
+
+// Qualifiers that may precede or follow the storage qualifier, and that are
+// simply skipped.
+var skipQualifiers = map[string]bool{
+	"invariant":     true,
+	"precise":       true,
+	"centroid":      true,
+	"sample":        true,
+	"flat":          true,
+	"smooth":        true,
+	"noperspective": true,
+	"lowp":          true,
+	"mediump":       true,
+	"highp":         true,
+}
+
+// parser parses the top-level declarations of a single stage.
+type parser struct {
+	stage Stage
+	toks  []token
+	vars  []Var
+}
+
+func (p *parser) errorf(line int, format string, args ...interface{}) error {
+	return &SyntaxError{Stage: p.stage, Line: line, Msg: fmt.Sprintf(format, args...)}
+}
+
+// qualifier returns the qualifier of the given storage keyword for the
+// parser's stage. If the declaration is not one that is reflected (e.g. a
+// fragment shader output) then ok is false.
+func (p *parser) qualifier(t token) (q Qualifier, ok bool, err error) {
+	switch t.text {
+	case "uniform":
+		return Uniform, true, nil
+	case "attribute":
+		if p.stage != Vertex {
+			return 0, false, p.errorf(t.line, "attribute qualifier outside of vertex shader")
+		}
+		return Attribute, true, nil
+	case "varying":
+		return Varying, true, nil
+	case "in":
+		if p.stage == Vertex {
+			return Attribute, true, nil
+		}
+		return Varying, true, nil
+	case "out":
+		if p.stage == Vertex {
+			return Varying, true, nil
+		}
+		return 0, false, nil
+	}
+	return 0, false, nil
+}
+
+// arraySize parses an array size starting at toks[i] (which must be '['), and
+// returns the size and the index of the token following the closing ']'.
+func (p *parser) arraySize(toks []token, i int) (size, next int, err error) {
+	open := toks[i]
+	i++
+	var expr []token
+	for ; i < len(toks) && toks[i].text != "]"; i++ {
+		expr = append(expr, toks[i])
+	}
+	if i == len(toks) {
+		return 0, 0, p.errorf(open.line, "missing ] in array size")
+	}
+	size = -1
+	if len(expr) == 1 && expr[0].kind == tNumber {
+		lit := strings.TrimRight(expr[0].text, "uU")
+		if n, err := strconv.ParseInt(lit, 0, 32); err == nil && n > 0 {
+			size = int(n)
+		}
+	}
+	return size, i + 1, nil
+}
+
+// decl parses a single top-level statement (without the trailing semicolon).
+func (p *parser) decl(toks []token) error {
+	var (
+		i       int
+		q       Qualifier
+		storage bool
+	)
+	for ; i < len(toks); i++ {
+		t := toks[i]
+		if t.text == "layout" {
+			// Skip the layout qualifier's parenthesized list.
+			for i < len(toks) && toks[i].text != ")" {
+				i++
+			}
+			continue
+		}
+		if skipQualifiers[t.text] {
+			continue
+		}
+		if storage {
+			break
+		}
+		var (
+			ok  bool
+			err error
+		)
+		q, ok, err = p.qualifier(t)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			// Not a declaration that we reflect (e.g. a precision statement
+			// or a global variable).
+			return nil
+		}
+		storage = true
+	}
+	if !storage {
+		return nil
+	}
+
+	// The type.
+	if i >= len(toks) || toks[i].kind != tIdent {
+		return p.errorf(toks[len(toks)-1].line, "expected type after %s qualifier", q)
+	}
+	typ := toks[i].text
+	i++
+	typeSize := 0
+	if i < len(toks) && toks[i].text == "[" {
+		var err error
+		typeSize, i, err = p.arraySize(toks, i)
+		if err != nil {
+			return err
+		}
+	}
+
+	// The declarators.
+	for {
+		if i >= len(toks) || toks[i].kind != tIdent {
+			line := toks[len(toks)-1].line
+			if i < len(toks) {
+				line = toks[i].line
+			}
+			return p.errorf(line, "expected name in %s %s declaration", q, typ)
+		}
+		v := Var{
+			Qualifier: q,
+			Type:      typ,
+			Name:      toks[i].text,
+			ArraySize: typeSize,
+			Stage:     p.stage,
+			Line:      toks[i].line,
+		}
+		i++
+		if i < len(toks) && toks[i].text == "[" {
+			var err error
+			v.ArraySize, i, err = p.arraySize(toks, i)
+			if err != nil {
+				return err
+			}
+		}
+		p.vars = append(p.vars, v)
+
+		// Skip any initializer.
+		depth := 0
+		for ; i < len(toks); i++ {
+			switch toks[i].text {
+			case "(", "[":
+				depth++
+			case ")", "]":
+				depth--
+			}
+			if depth == 0 && toks[i].text == "," {
+				break
+			}
+		}
+		if i >= len(toks) {
+			return nil
+		}
+		i++ // Skip the comma.
+	}
+}
+
+// Parse parses the GLSL source code of the given stage, and returns the
+// variables it declares in the order in which they are declared.
+//
+// Fragment shader outputs, global variables, and the members of interface
+// blocks are not returned.
+func Parse(src []byte, stage Stage) ([]Var, error) {
+	p := &parser{stage: stage}
+	s := newScanner(src)
+
+	var (
+		stmt    []token
+		depth   int
+		discard bool
+	)
+	for {
+		t, ok := s.next()
+		if !ok {
+			break
+		}
+		switch t.text {
+		case "{":
+			if depth == 0 {
+				// A function definition, struct, or interface block; none of
+				// which are reflected.
+				discard = true
+			}
+			depth++
+			continue
+		case "}":
+			depth--
+			if depth < 0 {
+				return nil, p.errorf(t.line, "unexpected }")
+			}
+			if depth == 0 && len(stmt) > 0 && stmt[len(stmt)-1].text == ")" {
+				// The end of a function definition, which has no trailing
+				// semicolon.
+				stmt = stmt[:0]
+				discard = false
+			}
+			continue
+		}
+		if depth > 0 {
+			continue
+		}
+		if t.text == ";" {
+			if !discard && len(stmt) > 0 {
+				if err := p.decl(stmt); err != nil {
+					return nil, err
+				}
+			}
+			stmt = stmt[:0]
+			discard = false
+			continue
+		}
+		stmt = append(stmt, t)
+	}
+	if depth > 0 {
+		return nil, p.errorf(s.line, "missing }")
+	}
+	return p.vars, nil
+}
+
+// Program describes the variables declared by a shader program.
+type Program struct {
+	// The uniforms declared by either stage, excluding samplers.
+	Uniforms []Var
+
+	// The sampler uniforms declared by either stage.
+	Samplers []Var
+
+	// The attributes declared by the vertex shader.
+	Attributes []Var
+
+	// The varyings declared by either stage.
+	Varyings []Var
+}
+
+func lookup(vars []Var, name string) (Var, bool) {
+	for _, v := range vars {
+		if v.Name == name {
+			return v, true
+		}
+	}
+	return Var{}, false
+}
+
+// Uniform returns the uniform (or sampler) with the given name.
+func (p *Program) Uniform(name string) (Var, bool) {
+	if v, ok := lookup(p.Uniforms, name); ok {
+		return v, true
+	}
+	return lookup(p.Samplers, name)
+}
+
+// Attribute returns the attribute with the given name.
+func (p *Program) Attribute(name string) (Var, bool) {
+	return lookup(p.Attributes, name)
+}
+
+// add adds the variable to the program, variables declared by both stages must
+// have the same type.
+func (p *Program) add(v Var) error {
+	list := &p.Uniforms
+	switch {
+	case v.Qualifier == Attribute:
+		list = &p.Attributes
+	case v.Qualifier == Varying:
+		list = &p.Varyings
+	case v.IsSampler():
+		list = &p.Samplers
+	}
+	if prev, ok := lookup(*list, v.Name); ok {
+		if prev.Type != v.Type || prev.ArraySize != v.ArraySize {
+			return &SyntaxError{
+				Stage: v.Stage,
+				Line:  v.Line,
+				Msg:   fmt.Sprintf("%v conflicts with %v declared by the %s shader", v, prev, prev.Stage),
+			}
+		}
+		return nil
+	}
+	*list = append(*list, v)
+	return nil
+}
+
+// Reflect parses the vertex and fragment shader sources of the given shader
+// and returns the variables they declare. It must be called before the shader
+// is loaded (or KeepDataOnLoad must be set), as the sources are otherwise
+// cleared.
+//
+// The shader's read lock must be held for this method to operate safely.
+func Reflect(s *gfx.Shader) (*Program, error) {
+	prog := new(Program)
+	for _, stage := range []struct {
+		Stage
+		src []byte
+	}{
+		{Vertex, s.GLSLVert},
+		{Fragment, s.GLSLFrag},
+	} {
+		vars, err := Parse(stage.src, stage.Stage)
+		if err != nil {
+			return nil, err
+		}
+		for _, v := range vars {
+			if err := prog.add(v); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return prog, nil
+}
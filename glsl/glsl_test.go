@@ -0,0 +1,152 @@
+// Copyright 2014 The Azul3D Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package glsl
+
+import (
+	"testing"
+
+	"azul3d.org/gfx.v1"
+)
+
+var vert = []byte(`
+#version 120
+#define MAX_LIGHTS 4
+
+attribute vec3 Vertex;
+attribute vec4 Color;
+attribute float Weights[2]; // Skinning weights.
+
+uniform mat4 MVP;
+uniform vec3 Lights[MAX_LIGHTS], Ambient;
+uniform highp float Time = 0.0;
+
+varying vec4 frontColor;
+
+/* A function, whose body is skipped:
+   uniform float NotAUniform; */
+vec4 tint(vec4 c) {
+	if (c.a > 0.5) {
+		return c;
+	}
+	return vec4(1.0);
+}
+
+void main()
+{
+	frontColor = tint(Color);
+	gl_Position = MVP * vec4(Vertex, 1.0);
+}
+`)
+
+var frag = []byte(`
+#version 130
+precision mediump float;
+
+in vec4 frontColor;
+out vec4 fragColor;
+uniform sampler2D Texture0;
+uniform float Time;
+uniform Material {
+	vec4 diffuse;
+};
+
+void main() {
+	fragColor = frontColor * texture(Texture0, vec2(0.0));
+}
+`)
+
+func TestReflect(t *testing.T) {
+	s := gfx.NewShader("test")
+	s.GLSLVert = vert
+	s.GLSLFrag = frag
+	prog, err := Reflect(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := func(kind string, got []Var, decls ...string) {
+		if len(got) != len(decls) {
+			t.Fatalf("got %s %v, want %v", kind, got, decls)
+		}
+		for i, v := range got {
+			if v.String() != decls[i] {
+				t.Errorf("got %s %q, want %q", kind, v, decls[i])
+			}
+		}
+	}
+	want("uniforms", prog.Uniforms, "uniform mat4 MVP", "uniform vec3 Lights[]", "uniform vec3 Ambient", "uniform float Time")
+	want("samplers", prog.Samplers, "uniform sampler2D Texture0")
+	want("attributes", prog.Attributes, "attribute vec3 Vertex", "attribute vec4 Color", "attribute float Weights[2]")
+	want("varyings", prog.Varyings, "varying vec4 frontColor")
+
+	if v, _ := prog.Attribute("Weights"); v.Line != 7 {
+		t.Errorf("got Weights declared on line %d, want 7", v.Line)
+	}
+}
+
+func TestReflectConflict(t *testing.T) {
+	s := gfx.NewShader("conflict")
+	s.GLSLVert = []byte("uniform vec3 Tint;")
+	s.GLSLFrag = []byte("\nuniform vec4 Tint;")
+	_, err := Reflect(s)
+	serr, ok := err.(*SyntaxError)
+	if !ok || serr.Stage != Fragment || serr.Line != 2 {
+		t.Fatal("got error", err)
+	}
+}
+
+func TestCheck(t *testing.T) {
+	vars, err := Parse(vert, Vertex)
+	if err != nil {
+		t.Fatal(err)
+	}
+	prog := new(Program)
+	for _, v := range vars {
+		prog.add(v)
+	}
+
+	errs := prog.CheckInputs(map[string]interface{}{
+		"MVP":     []gfx.Vec3{{}},
+		"Lights":  []gfx.Vec3{{}, {}},
+		"Ambient": gfx.Vec3{},
+	}, "Time")
+	if len(errs) != 1 || errs[0].(*CheckError).Var.Name != "MVP" {
+		t.Fatal("got input errors", errs)
+	}
+
+	errs = prog.CheckAttribs(map[string]gfx.VertexAttrib{
+		"Weights": {Data: [][]float32{{1, 2}}},
+	}, "Vertex")
+	if len(errs) != 1 || errs[0].(*CheckError).Var.Name != "Color" || errs[0].(*CheckError).Value != nil {
+		t.Fatal("got attribute errors", errs)
+	}
+}
+
+func TestCheckTextures(t *testing.T) {
+	vars, err := Parse(frag, Fragment)
+	if err != nil {
+		t.Fatal(err)
+	}
+	prog := new(Program)
+	for _, v := range vars {
+		prog.add(v)
+	}
+
+	o := gfx.NewObject()
+	errs := prog.CheckTextures(o)
+	if len(errs) != 1 || errs[0].(*CheckError).Var.Name != "Texture0" {
+		t.Fatal("got missing texture errors", errs)
+	}
+
+	o.Textures = []*gfx.Texture{gfx.NewTexture()}
+	if errs := prog.CheckTextures(o); len(errs) != 0 {
+		t.Fatal("got positional texture errors", errs)
+	}
+
+	o.Samplers["NormalMap"] = gfx.NewTexture()
+	if errs := prog.CheckTextures(o); len(errs) != 1 {
+		t.Fatal("got undeclared sampler errors", errs)
+	}
+}
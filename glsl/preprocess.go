@@ -0,0 +1,319 @@
+// Copyright 2014 The Azul3D Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package glsl
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"azul3d.org/gfx.v1"
+)
+
+// FileSystem is a virtual file system that shader sources are read from.
+type FileSystem interface {
+	// ReadFile returns the contents of the file with the given slash
+	// separated name.
+	ReadFile(name string) ([]byte, error)
+}
+
+// MapFS is a FileSystem backed by a map of file names to their contents.
+type MapFS map[string][]byte
+
+// ReadFile implements the FileSystem interface.
+func (m MapFS) ReadFile(name string) ([]byte, error) {
+	data, ok := m[path.Clean(name)]
+	if !ok {
+		return nil, &os.PathError{Op: "open", Path: name, Err: os.ErrNotExist}
+	}
+	return data, nil
+}
+
+// variant is a cached shader, along with the names of the files that it's
+// sources were built from (indexed by GLSL source string number).
+type variant struct {
+	shader *gfx.Shader
+	files  []string
+}
+
+// Preprocessor builds shaders from source files, resolving #include
+// directives and injecting #define directives. Each distinct set of defines
+// produces a single cached shader variant:
+//  p := glsl.NewPreprocessor(glsl.MapFS{
+//      "common.glsl": common,
+//      "lit.vert":    vert,
+//      "lit.frag":    frag,
+//  })
+//  skinned, err := p.Shader("lit", "lit.vert", "lit.frag",
+//      "SKINNED", "MAX_BONES=64")
+//
+// A file may include another using either of:
+//  #include "common.glsl"
+//  #include <common.glsl>
+// Names are resolved relative to the directory of the including file. A file
+// containing a #pragma once directive is only included once per source.
+//
+// The built sources contain #line directives whose source string numbers
+// identify the original files, such that compiler errors can be mapped back to
+// the original files and lines (see MapError).
+//
+// All methods are safe to call from multiple goroutines.
+type Preprocessor struct {
+	fs FileSystem
+
+	access   sync.Mutex
+	variants map[string]*variant
+	files    map[*gfx.Shader][]string
+}
+
+// Source preprocesses the given file using the given defines, and returns the
+// resulting GLSL source code. The names of the files that the source code was
+// built from are appended to the files slice, the index of each file name
+// being it's GLSL source string number. For example:
+//  var files []string
+//  src, err := p.Source("lit.vert", []string{"SKINNED"}, &files)
+func (p *Preprocessor) Source(file string, defines []string, files *[]string) ([]byte, error) {
+	e := &expander{fs: p.fs, files: files, once: make(map[string]bool)}
+	data, err := p.fs.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	lines := splitLines(data)
+
+	// The #version directive (and anything preceding it) must come before
+	// any other directive, including our defines.
+	version := -1
+	for i, line := range lines {
+		if d, args := directive(line); d == "version" {
+			version = i
+			e.oldLine = oldLineDirective(append(args, ""))
+			break
+		}
+	}
+	e.oldLine = e.oldLine || version < 0
+	for _, line := range lines[:version+1] {
+		e.out.Write(line)
+		e.out.WriteByte('\n')
+	}
+	for _, d := range defines {
+		name, value := d, "1"
+		if i := strings.Index(d, "="); i >= 0 {
+			name, value = d[:i], d[i+1:]
+		}
+		fmt.Fprintf(&e.out, "#define %s %s\n", name, value)
+	}
+
+	if err := e.expand(path.Clean(file), lines, version+1, nil); err != nil {
+		return nil, err
+	}
+	return e.out.Bytes(), nil
+}
+
+// Shader returns the shader variant built from the given vertex and fragment
+// shader files using the given defines (each either of the form "NAME" or
+// "NAME=VALUE"). The shader is cached, such that the same shader is returned
+// for each distinct name and set of defines, regardless of their order.
+//
+// The shader's name is the given name followed by the sorted defines, e.g.
+// "lit [MAX_BONES=64 SKINNED]".
+func (p *Preprocessor) Shader(name, vert, frag string, defines ...string) (*gfx.Shader, error) {
+	defines = normalizeDefines(defines)
+	key := strings.Join(append([]string{name, vert, frag}, defines...), "\x00")
+
+	p.access.Lock()
+	defer p.access.Unlock()
+	if v, ok := p.variants[key]; ok {
+		return v.shader, nil
+	}
+
+	var files []string
+	vertSrc, err := p.Source(vert, defines, &files)
+	if err != nil {
+		return nil, err
+	}
+	fragSrc, err := p.Source(frag, defines, &files)
+	if err != nil {
+		return nil, err
+	}
+	if len(defines) > 0 {
+		name = fmt.Sprintf("%s [%s]", name, strings.Join(defines, " "))
+	}
+	s := gfx.NewShader(name)
+	s.GLSLVert = vertSrc
+	s.GLSLFrag = fragSrc
+	p.variants[key] = &variant{shader: s, files: files}
+	p.files[s] = files
+	return s, nil
+}
+
+// errorLocation matches the source string number and line at the start of a
+// line of a compiler error log, in the common formats:
+//  0:12(5): error: ...
+//  ERROR: 0:12: ...
+//  0(12) : error C0000: ...
+var errorLocation = regexp.MustCompile(`(?m)^((?:[A-Za-z]+:\s*)?)(\d+)(?::(\d+)|\((\d+)\))`)
+
+// MapError rewrites the error log (see Shader.Error) of a shader returned by
+// the Shader method, such that source string numbers are replaced by the
+// names of the original files. For example:
+//  0:12(5): error: ...
+//  ERROR: 0:12: ...
+// Would become:
+//  lighting.glsl:12(5): error: ...
+//  ERROR: lighting.glsl:12: ...
+//
+// The shader's write lock must be held for this method to operate safely.
+func (p *Preprocessor) MapError(s *gfx.Shader) {
+	p.access.Lock()
+	files, ok := p.files[s]
+	p.access.Unlock()
+	if !ok {
+		return
+	}
+	s.Error = errorLocation.ReplaceAllFunc(s.Error, func(m []byte) []byte {
+		sub := errorLocation.FindSubmatch(m)
+		n, err := strconv.Atoi(string(sub[2]))
+		if err != nil || n >= len(files) {
+			return m
+		}
+		line := sub[3]
+		if line == nil {
+			line = sub[4]
+		}
+		return []byte(fmt.Sprintf("%s%s:%s", sub[1], files[n], line))
+	})
+}
+
+// NewPreprocessor returns a new preprocessor that reads source files from the
+// given file system.
+func NewPreprocessor(fs FileSystem) *Preprocessor {
+	return &Preprocessor{
+		fs:       fs,
+		variants: make(map[string]*variant),
+		files:    make(map[*gfx.Shader][]string),
+	}
+}
+
+// normalizeDefines returns the sorted set of defines.
+func normalizeDefines(defines []string) []string {
+	seen := make(map[string]bool, len(defines))
+	var set []string
+	for _, d := range defines {
+		d = strings.TrimSpace(d)
+		if d == "" || seen[d] {
+			continue
+		}
+		seen[d] = true
+		set = append(set, d)
+	}
+	sort.Strings(set)
+	return set
+}
+
+// splitLines splits the data into lines, without their line endings.
+func splitLines(data []byte) [][]byte {
+	lines := bytes.Split(data, []byte("\n"))
+	for i, line := range lines {
+		lines[i] = bytes.TrimSuffix(line, []byte("\r"))
+	}
+	if n := len(lines); n > 0 && len(lines[n-1]) == 0 {
+		lines = lines[:n-1]
+	}
+	return lines
+}
+
+// directive returns the name and arguments of the preprocessor directive on the
+// given line, if any.
+func directive(line []byte) (name string, args []string) {
+	line = bytes.TrimSpace(line)
+	if len(line) == 0 || line[0] != '#' {
+		return "", nil
+	}
+	if i := bytes.Index(line, []byte("//")); i >= 0 {
+		line = line[:i]
+	}
+	fields := strings.Fields(string(line[1:]))
+	if len(fields) == 0 {
+		return "", nil
+	}
+	return fields[0], fields[1:]
+}
+
+// expander expands the #include directives of a single source.
+type expander struct {
+	fs      FileSystem
+	out     bytes.Buffer
+	files   *[]string
+	once    map[string]bool
+	oldLine bool
+}
+
+// lineDirective writes a #line directive, such that the next line is the
+// given line of the given source string.
+func (e *expander) lineDirective(line, source int) {
+	if e.oldLine {
+		line--
+	}
+	fmt.Fprintf(&e.out, "#line %d %d\n", line, source)
+}
+
+// expand writes the given lines of the named file, starting at the given line
+// index, to the output. The stack contains the names of the files that are
+// currently being included.
+func (e *expander) expand(name string, lines [][]byte, start int, stack []string) error {
+	for _, s := range stack {
+		if s == name {
+			return fmt.Errorf("glsl: %s: include cycle: %s -> %s", name, strings.Join(stack, " -> "), name)
+		}
+	}
+	stack = append(stack, name)
+
+	source := len(*e.files)
+	*e.files = append(*e.files, name)
+	e.lineDirective(start+1, source)
+
+	for i := start; i < len(lines); i++ {
+		line := lines[i]
+		d, args := directive(line)
+		switch {
+		case d == "pragma" && len(args) == 1 && args[0] == "once":
+			e.once[name] = true
+			e.out.WriteByte('\n')
+			continue
+		case d == "version" && len(stack) > 1:
+			// Only the including file may specify the version.
+			e.out.WriteByte('\n')
+			continue
+		case d != "include":
+			e.out.Write(line)
+			e.out.WriteByte('\n')
+			continue
+		}
+
+		if len(args) != 1 || len(args[0]) < 2 || !((args[0][0] == '"' && args[0][len(args[0])-1] == '"') || (args[0][0] == '<' && args[0][len(args[0])-1] == '>')) {
+			return fmt.Errorf("glsl: %s:%d: malformed #include directive", name, i+1)
+		}
+		inc := path.Join(path.Dir(name), args[0][1:len(args[0])-1])
+		if e.once[inc] {
+			e.out.WriteByte('\n')
+			continue
+		}
+		data, err := e.fs.ReadFile(inc)
+		if err != nil {
+			return fmt.Errorf("glsl: %s:%d: %v", name, i+1, err)
+		}
+		if err := e.expand(inc, splitLines(data), 0, stack); err != nil {
+			return err
+		}
+		e.lineDirective(i+2, source)
+	}
+	return nil
+}
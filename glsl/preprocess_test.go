@@ -0,0 +1,110 @@
+// Copyright 2014 The Azul3D Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package glsl
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+var fs = MapFS{
+	"lit.vert": []byte(`// Lit vertex shader.
+#version 120
+#include "lib/common.glsl"
+#include <lib/common.glsl>
+
+attribute vec3 Vertex;
+uniform mat4 MVP;
+
+void main() {
+	gl_Position = MVP * vec4(Vertex, 1.0);
+}
+`),
+	"lit.frag": []byte(`#version 120
+#include "lib/skin.glsl"
+uniform vec4 Tint;
+
+void main() {
+	gl_FragColor = Tint;
+}
+`),
+	"lib/common.glsl": []byte(`#pragma once
+uniform float Time;
+`),
+	"lib/skin.glsl": []byte(`#ifdef SKINNED
+#include "common.glsl"
+uniform mat4 Bones[MAX_BONES];
+#endif
+`),
+	"cycle.vert": []byte(`#include "cycle.glsl"`),
+	"cycle.glsl": []byte(`#include "cycle.vert"`),
+}
+
+func TestPreprocess(t *testing.T) {
+	p := NewPreprocessor(fs)
+	s, err := p.Shader("lit", "lit.vert", "lit.frag", "SKINNED", "MAX_BONES=64")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.Name != "lit [MAX_BONES=64 SKINNED]" {
+		t.Fatalf("got name %q", s.Name)
+	}
+
+	// The defines follow the #version directive.
+	lines := strings.Split(string(s.GLSLVert), "\n")
+	if lines[1] != "#version 120" || lines[2] != "#define MAX_BONES 64" || lines[3] != "#define SKINNED 1" {
+		t.Fatalf("got vertex source:\n%s", s.GLSLVert)
+	}
+
+	// The common file is included only once.
+	if n := bytes.Count(s.GLSLVert, []byte("uniform float Time;")); n != 1 {
+		t.Fatalf("common.glsl included %d times", n)
+	}
+
+	// Declarations are reported at their original lines.
+	prog, err := Reflect(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, line := range map[string]int{"Time": 2, "MVP": 7, "Bones": 3, "Tint": 3} {
+		v, ok := prog.Uniform(name)
+		if !ok || v.Line != line {
+			t.Errorf("got %v at line %d, want line %d", v, v.Line, line)
+		}
+	}
+
+	// The same variant is returned regardless of the order of defines.
+	s2, err := p.Shader("lit", "lit.vert", "lit.frag", "MAX_BONES=64", "SKINNED", "SKINNED")
+	if err != nil || s2 != s {
+		t.Fatal("got a different variant", s2, err)
+	}
+	s3, err := p.Shader("lit", "lit.vert", "lit.frag")
+	if err != nil || s3 == s {
+		t.Fatal("got the same variant without defines", err)
+	}
+	s4, err := p.Shader("unlit", "lit.vert", "lit.frag", "SKINNED", "MAX_BONES=64")
+	if err != nil || s4 == s || s4.Name != "unlit [MAX_BONES=64 SKINNED]" {
+		t.Fatal("got the same variant with another name", err)
+	}
+
+	// Errors are mapped back to the original files.
+	s.Error = []byte("0:7(12): error: `Foo' undeclared\nERROR: 3:3: 'MAX_BONES' : undeclared identifier\n")
+	p.MapError(s)
+	want := "lit.vert:7(12): error: `Foo' undeclared\nERROR: lib/skin.glsl:3: 'MAX_BONES' : undeclared identifier\n"
+	if string(s.Error) != want {
+		t.Fatalf("got error log:\n%s\nwant:\n%s", s.Error, want)
+	}
+}
+
+func TestPreprocessErrors(t *testing.T) {
+	p := NewPreprocessor(fs)
+	if _, err := p.Shader("cycle", "cycle.vert", "lit.frag"); err == nil || !strings.Contains(err.Error(), "include cycle") {
+		t.Fatal("got error", err)
+	}
+	if _, err := p.Shader("missing", "missing.vert", "lit.frag"); err == nil {
+		t.Fatal("expected error")
+	}
+}
@@ -0,0 +1,167 @@
+// Copyright 2014 The Azul3D Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package glsl
+
+import (
+	"strconv"
+	"strings"
+)
+
+// tokenKind is the kind of a single token.
+type tokenKind uint8
+
+const (
+	tIdent tokenKind = iota
+	tNumber
+	tPunct
+)
+
+// token is a single token of GLSL source code.
+type token struct {
+	kind tokenKind
+	text string
+	line int
+}
+
+// scanner splits GLSL source code into tokens. Comments are skipped, and so
+// are preprocessor directives (except for #line, which is honored).
+type scanner struct {
+	src  []byte
+	pos  int
+	line int
+
+	// Whether or not only whitespace has been seen on the current line.
+	lineStart bool
+
+	// Whether or not #line directives use the semantics of older versions of
+	// GLSL, see oldLineDirective.
+	oldLine bool
+}
+
+func isIdent(c byte, first bool) bool {
+	switch {
+	case c == '_', c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z':
+		return true
+	case c >= '0' && c <= '9':
+		return !first
+	}
+	return false
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+// directive skips the preprocessor directive starting at the current position
+// (a '#' character), honoring #line directives.
+func (s *scanner) directive() {
+	start := s.pos + 1
+	var text []byte
+	for s.pos < len(s.src) && s.src[s.pos] != '\n' {
+		if s.src[s.pos] == '\\' && s.pos+1 < len(s.src) && s.src[s.pos+1] == '\n' {
+			// Line continuation.
+			text = append(text, s.src[start:s.pos]...)
+			s.pos += 2
+			s.line++
+			start = s.pos
+			continue
+		}
+		s.pos++
+	}
+	text = append(text, s.src[start:s.pos]...)
+
+	fields := strings.Fields(string(text))
+	if len(fields) < 2 {
+		return
+	}
+	switch fields[0] {
+	case "version":
+		s.oldLine = oldLineDirective(fields[1:])
+	case "line":
+		if n, err := strconv.Atoi(fields[1]); err == nil {
+			// The line following the directive is line n (or n+1 for older
+			// versions of GLSL).
+			s.line = n - 1
+			if s.oldLine {
+				s.line = n
+			}
+		}
+	}
+}
+
+// oldLineDirective tells if the given #version directive arguments describe a
+// version of GLSL where the line following a #line directive is line n+1
+// instead of line n (i.e. GLSL before version 3.30, and GLSL ES 1.00).
+func oldLineDirective(version []string) bool {
+	n, err := strconv.Atoi(version[0])
+	if err != nil {
+		return false
+	}
+	es := len(version) > 1 && version[1] == "es"
+	return !es && n < 330
+}
+
+// next returns the next token, or false if the end of the source code has
+// been reached.
+func (s *scanner) next() (token, bool) {
+	for s.pos < len(s.src) {
+		c := s.src[s.pos]
+		switch {
+		case c == '\n':
+			s.line++
+			s.pos++
+			s.lineStart = true
+			continue
+		case c == ' ' || c == '\t' || c == '\r' || c == '\f' || c == '\v':
+			s.pos++
+			continue
+		case c == '#' && s.lineStart:
+			s.directive()
+			continue
+		case c == '/' && s.pos+1 < len(s.src) && s.src[s.pos+1] == '/':
+			for s.pos < len(s.src) && s.src[s.pos] != '\n' {
+				s.pos++
+			}
+			continue
+		case c == '/' && s.pos+1 < len(s.src) && s.src[s.pos+1] == '*':
+			s.pos += 2
+			for s.pos < len(s.src) && !(s.src[s.pos] == '*' && s.pos+1 < len(s.src) && s.src[s.pos+1] == '/') {
+				if s.src[s.pos] == '\n' {
+					s.line++
+				}
+				s.pos++
+			}
+			s.pos += 2
+			continue
+		}
+
+		s.lineStart = false
+		start := s.pos
+		t := token{line: s.line}
+		switch {
+		case isIdent(c, true):
+			for s.pos < len(s.src) && isIdent(s.src[s.pos], false) {
+				s.pos++
+			}
+			t.kind = tIdent
+		case isDigit(c) || (c == '.' && s.pos+1 < len(s.src) && isDigit(s.src[s.pos+1])):
+			for s.pos < len(s.src) && (isIdent(s.src[s.pos], false) || s.src[s.pos] == '.') {
+				s.pos++
+			}
+			t.kind = tNumber
+		default:
+			s.pos++
+			t.kind = tPunct
+		}
+		t.text = string(s.src[start:s.pos])
+		return t, true
+	}
+	return token{}, false
+}
+
+// newScanner returns a new scanner of the given source code.
+func newScanner(src []byte) *scanner {
+	return &scanner{src: src, line: 1, lineStart: true, oldLine: true}
+}
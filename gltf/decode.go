@@ -0,0 +1,364 @@
+// Copyright 2014 The Azul3D Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package gltf
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math"
+	"net/url"
+	"strings"
+
+	"azul3d.org/gfx.v1"
+)
+
+// Magic numbers of the binary (.glb) form.
+const (
+	glbMagic     = 0x46546C67 // "glTF"
+	glbChunkJSON = 0x4E4F534A // "JSON"
+	glbChunkBIN  = 0x004E4942 // "BIN\x00"
+)
+
+// Component types of accessors.
+const (
+	componentByte          = 5120
+	componentUnsignedByte  = 5121
+	componentShort         = 5122
+	componentUnsignedShort = 5123
+	componentUnsignedInt   = 5125
+	componentFloat         = 5126
+)
+
+// componentSizes are the sizes in bytes of each component type.
+var componentSizes = map[int]int{
+	componentByte:          1,
+	componentUnsignedByte:  1,
+	componentShort:         2,
+	componentUnsignedShort: 2,
+	componentUnsignedInt:   4,
+	componentFloat:         4,
+}
+
+// typeComponents are the number of components of each accessor type.
+var typeComponents = map[string]int{
+	"SCALAR": 1,
+	"VEC2":   2,
+	"VEC3":   3,
+	"VEC4":   4,
+	"MAT2":   4,
+	"MAT3":   9,
+	"MAT4":   16,
+}
+
+// decoder decodes a single glTF model, and the files that it references.
+type decoder struct {
+	open    Opener
+	doc     document
+	bin     []byte   // The binary chunk of a .glb file.
+	buffers [][]byte // Buffers loaded so far, by index.
+	model   *Model
+}
+
+// decode decodes the .gltf or .glb file data, and builds the model.
+func (d *decoder) decode(r io.Reader) (*Model, error) {
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	if len(data) >= 4 && binary.LittleEndian.Uint32(data) == glbMagic {
+		if data, err = d.glb(data); err != nil {
+			return nil, err
+		}
+	}
+	if err := json.Unmarshal(data, &d.doc); err != nil {
+		return nil, fmt.Errorf("gltf: %v", err)
+	}
+	version := d.doc.Asset.Version
+	if d.doc.Asset.MinVersion != "" {
+		version = d.doc.Asset.MinVersion
+	}
+	if !strings.HasPrefix(version, "2.") {
+		return nil, fmt.Errorf("gltf: unsupported version %q", version)
+	}
+	if len(d.doc.ExtensionsRequired) > 0 {
+		return nil, fmt.Errorf("gltf: unsupported required extension %q", d.doc.ExtensionsRequired[0])
+	}
+	d.buffers = make([][]byte, len(d.doc.Buffers))
+	d.model = &Model{}
+
+	steps := []func() error{
+		d.textures,
+		d.materials,
+		d.meshes,
+		d.nodes,
+		d.skins,
+		d.animations,
+		d.scenes,
+	}
+	for _, step := range steps {
+		if err := step(); err != nil {
+			return nil, err
+		}
+	}
+	return d.model, nil
+}
+
+// glb splits the .glb file data into it's chunks, returning the JSON chunk
+// and storing the binary one.
+func (d *decoder) glb(data []byte) ([]byte, error) {
+	if len(data) < 12 {
+		return nil, errors.New("gltf: truncated header")
+	}
+	if v := binary.LittleEndian.Uint32(data[4:]); v != 2 {
+		return nil, fmt.Errorf("gltf: unsupported binary container version %d", v)
+	}
+	if n := binary.LittleEndian.Uint32(data[8:]); int64(n) <= int64(len(data)) {
+		data = data[:n]
+	}
+	var doc []byte
+	for data = data[12:]; len(data) > 0; {
+		if len(data) < 8 {
+			return nil, errors.New("gltf: truncated chunk header")
+		}
+		n, typ := binary.LittleEndian.Uint32(data), binary.LittleEndian.Uint32(data[4:])
+		data = data[8:]
+		if int64(n) > int64(len(data)) {
+			return nil, errors.New("gltf: truncated chunk")
+		}
+		switch {
+		case typ == glbChunkJSON && doc == nil:
+			doc = data[:n]
+		case typ == glbChunkBIN && d.bin == nil:
+			d.bin = data[:n]
+		}
+		data = data[n:]
+	}
+	if doc == nil {
+		return nil, errors.New("gltf: missing JSON chunk")
+	}
+	return doc, nil
+}
+
+// checkRef returns an error if i is not a valid index into n elements of the
+// given kind.
+func checkRef(kind string, i, n int) error {
+	if i < 0 || i >= n {
+		return fmt.Errorf("gltf: %s %d does not exist", kind, i)
+	}
+	return nil
+}
+
+// uri returns the data of the given URI, either embedded as a data URI or
+// opened as a file.
+func (d *decoder) uri(uri string) ([]byte, error) {
+	if strings.HasPrefix(uri, "data:") {
+		i := strings.IndexByte(uri, ',')
+		if i < 0 || !strings.HasSuffix(uri[:i], ";base64") {
+			return nil, errors.New("gltf: data URI is not base64 encoded")
+		}
+		data, err := base64.StdEncoding.DecodeString(uri[i+1:])
+		if err != nil {
+			return nil, fmt.Errorf("gltf: data URI: %v", err)
+		}
+		return data, nil
+	}
+	if d.open == nil {
+		return nil, fmt.Errorf("gltf: no opener for %q", uri)
+	}
+	name, err := url.PathUnescape(uri)
+	if err != nil {
+		return nil, fmt.Errorf("gltf: %v", err)
+	}
+	f, err := d.open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return ioutil.ReadAll(f)
+}
+
+// buffer returns the data of the buffer with the given index, loading it if
+// it was not already.
+func (d *decoder) buffer(i int) ([]byte, error) {
+	if err := checkRef("buffer", i, len(d.doc.Buffers)); err != nil {
+		return nil, err
+	}
+	if d.buffers[i] != nil {
+		return d.buffers[i], nil
+	}
+	b := d.doc.Buffers[i]
+	var (
+		data []byte
+		err  error
+	)
+	if b.URI == "" {
+		if i != 0 || d.bin == nil {
+			return nil, fmt.Errorf("gltf: buffer %d has no data", i)
+		}
+		data = d.bin
+	} else if data, err = d.uri(b.URI); err != nil {
+		return nil, err
+	}
+	if len(data) < b.ByteLength {
+		return nil, fmt.Errorf("gltf: buffer %d is truncated", i)
+	}
+	d.buffers[i] = data[:b.ByteLength]
+	return d.buffers[i], nil
+}
+
+// bufferView returns the data of the buffer view with the given index, and
+// it's stride (zero if the data is tightly packed).
+func (d *decoder) bufferView(i int) ([]byte, int, error) {
+	if err := checkRef("buffer view", i, len(d.doc.BufferViews)); err != nil {
+		return nil, 0, err
+	}
+	v := d.doc.BufferViews[i]
+	data, err := d.buffer(v.Buffer)
+	if err != nil {
+		return nil, 0, err
+	}
+	if v.ByteOffset < 0 || v.ByteLength < 0 || v.ByteOffset+v.ByteLength > len(data) {
+		return nil, 0, fmt.Errorf("gltf: buffer view %d is out of range of it's buffer", i)
+	}
+	return data[v.ByteOffset : v.ByteOffset+v.ByteLength], v.ByteStride, nil
+}
+
+// component decodes a single component of the given type, converting it into
+// the range of zero to one (or minus one to one, for signed types) if it is
+// normalized.
+func component(b []byte, typ int, normalized bool) float64 {
+	var v, max float64
+	switch typ {
+	case componentByte:
+		v, max = float64(int8(b[0])), math.MaxInt8
+	case componentUnsignedByte:
+		v, max = float64(b[0]), math.MaxUint8
+	case componentShort:
+		v, max = float64(int16(binary.LittleEndian.Uint16(b))), math.MaxInt16
+	case componentUnsignedShort:
+		v, max = float64(binary.LittleEndian.Uint16(b)), math.MaxUint16
+	case componentUnsignedInt:
+		return float64(binary.LittleEndian.Uint32(b))
+	case componentFloat:
+		return float64(math.Float32frombits(binary.LittleEndian.Uint32(b)))
+	}
+	if normalized {
+		return math.Max(v/max, -1)
+	}
+	return v
+}
+
+// read decodes len(v) / n elements of n components of the given type from the
+// buffer view into v.
+func (d *decoder) read(v []float64, view, offset, typ int, normalized bool, n int) error {
+	count := len(v) / n
+	if count == 0 {
+		return nil
+	}
+	data, stride, err := d.bufferView(view)
+	if err != nil {
+		return err
+	}
+	size := componentSizes[typ]
+	if stride == 0 {
+		stride = size * n
+	}
+	if offset < 0 || offset+(count-1)*stride+size*n > len(data) {
+		return fmt.Errorf("gltf: data is out of range of buffer view %d", view)
+	}
+	for e := 0; e < count; e++ {
+		b := data[offset+e*stride:]
+		for c := 0; c < n; c++ {
+			v[e*n+c] = component(b[c*size:], typ, normalized)
+		}
+	}
+	return nil
+}
+
+// accessor decodes the elements of the accessor with the given index, and
+// returns their components one after another along with the number of
+// components of each element.
+func (d *decoder) accessor(i int) ([]float64, int, error) {
+	if err := checkRef("accessor", i, len(d.doc.Accessors)); err != nil {
+		return nil, 0, err
+	}
+	a := d.doc.Accessors[i]
+	n, ok := typeComponents[a.Type]
+	if _, typeOk := componentSizes[a.ComponentType]; !ok || !typeOk {
+		return nil, 0, fmt.Errorf("gltf: accessor %d has an invalid type", i)
+	}
+	if a.Count < 0 {
+		return nil, 0, fmt.Errorf("gltf: accessor %d has a negative count", i)
+	}
+	v := make([]float64, a.Count*n)
+	if a.BufferView != nil {
+		if err := d.read(v, *a.BufferView, a.ByteOffset, a.ComponentType, a.Normalized, n); err != nil {
+			return nil, 0, err
+		}
+	}
+	if s := a.Sparse; s != nil && s.Count > 0 {
+		switch s.Indices.ComponentType {
+		case componentUnsignedByte, componentUnsignedShort, componentUnsignedInt:
+		default:
+			return nil, 0, fmt.Errorf("gltf: accessor %d has invalid sparse indices", i)
+		}
+		indices := make([]float64, s.Count)
+		if err := d.read(indices, s.Indices.BufferView, s.Indices.ByteOffset, s.Indices.ComponentType, false, 1); err != nil {
+			return nil, 0, err
+		}
+		values := make([]float64, s.Count*n)
+		if err := d.read(values, s.Values.BufferView, s.Values.ByteOffset, a.ComponentType, a.Normalized, n); err != nil {
+			return nil, 0, err
+		}
+		for j, e := range indices {
+			if int(e) >= a.Count {
+				return nil, 0, fmt.Errorf("gltf: accessor %d has a sparse index out of range", i)
+			}
+			copy(v[int(e)*n:int(e+1)*n], values[j*n:(j+1)*n])
+		}
+	}
+	return v, n, nil
+}
+
+// accessorOf is like accessor, but returns an error unless the elements have
+// n components.
+func (d *decoder) accessorOf(i, n int) ([]float64, error) {
+	v, got, err := d.accessor(i)
+	if err == nil && got != n {
+		err = fmt.Errorf("gltf: accessor %d has %d components, want %d", i, got, n)
+	}
+	return v, err
+}
+
+// image returns a reader of the data of the image with the given index.
+func (d *decoder) image(i int) (io.Reader, error) {
+	if err := checkRef("image", i, len(d.doc.Images)); err != nil {
+		return nil, err
+	}
+	img := d.doc.Images[i]
+	if img.BufferView != nil {
+		data, _, err := d.bufferView(*img.BufferView)
+		if err != nil {
+			return nil, err
+		}
+		return bytes.NewReader(data), nil
+	}
+	data, err := d.uri(img.URI)
+	if err != nil {
+		return nil, err
+	}
+	return bytes.NewReader(data), nil
+}
+
+// premultiply returns the straight alpha color in premultiplied alpha form.
+func premultiply(r, g, b, a float64) gfx.Color {
+	return gfx.Color{R: float32(r * a), G: float32(g * a), B: float32(b * a), A: float32(a)}
+}
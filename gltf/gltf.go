@@ -0,0 +1,416 @@
+// Copyright 2014 The Azul3D Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package gltf loads glTF 2.0 models, in either the JSON (.gltf) or the binary
+// (.glb) form, into meshes, textures, and transforms of the gfx package.
+//
+// Each node of the model has a gfx.Transform, whose parent is the transform of
+// the node's parent such that the node hierarchy forms chains of transforms.
+// Each primitive of a mesh becomes a gfx.Mesh, and each texture a gfx.Texture
+// using the wrap modes and filters of it's sampler. For example, to draw the
+// default scene of a model with the textured shader:
+//  model, err := gltf.Load("robot.glb")
+//  if err != nil {
+//      log.Fatal(err)
+//  }
+//  for _, o := range model.Objects(shaders.Textured(shaders.GLSL120)) {
+//      r.Draw(r.Bounds(), o, camera)
+//  }
+//
+// Skins and animations are not applied to the meshes or transforms, instead
+// they are exposed as data (see the Skin and Animation types) for use by the
+// application.
+//
+// Texture images are decoded with the image package, so the packages of the
+// image formats in use must be imported, e.g.:
+//  import (
+//      _ "image/jpeg"
+//      _ "image/png"
+//  )
+//
+// glTF models use the right handed Y up coordinate system. The data is
+// converted into the right handed Z up coordinate system of the gfx package,
+// that is positions, directions, and the axes of rotations become (x, -z, y),
+// and scales become (x, z, y). Texture coordinates of glTF already have their
+// origin at the top-left corner, and are kept as-is. Colors are converted into
+// premultiplied alpha form.
+//
+// Morph targets, cameras, and extensions are not supported and are ignored,
+// except that models requiring an extension fail to load.
+package gltf
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+
+	"azul3d.org/gfx.v1"
+	"azul3d.org/gfx.v1/shaders"
+	"azul3d.org/lmath.v1"
+)
+
+// Opener opens the files that a glTF model references, that is buffers and
+// images, given their (unescaped) URI as written in the model.
+type Opener func(name string) (io.ReadCloser, error)
+
+// DirOpener returns an Opener that opens files relative to the given
+// directory.
+func DirOpener(dir string) Opener {
+	return func(name string) (io.ReadCloser, error) {
+		name = filepath.FromSlash(name)
+		if !filepath.IsAbs(name) {
+			name = filepath.Join(dir, name)
+		}
+		return os.Open(name)
+	}
+}
+
+// Scene is a scene of a model, that is a set of root nodes.
+type Scene struct {
+	// The name of the scene.
+	Name string
+
+	// The root nodes of the scene.
+	Nodes []*Node
+}
+
+// Node is a node of a model's node hierarchy.
+type Node struct {
+	// The name of the node.
+	Name string
+
+	// The transform of the node, whose parent is the transform of the parent
+	// node (or nil for root nodes).
+	Transform *gfx.Transform
+
+	// The parent node, or nil for root nodes.
+	Parent *Node
+
+	// The child nodes.
+	Children []*Node
+
+	// The mesh of the node, or nil if it has none.
+	Mesh *Mesh
+
+	// The skin that deforms the mesh of the node, or nil if it has none.
+	Skin *Skin
+}
+
+// Mesh is a mesh of a model, made of one or more primitives.
+type Mesh struct {
+	// The name of the mesh.
+	Name string
+
+	// The primitives of the mesh. Their vertex attributes are stored as
+	// follows:
+	//  POSITION    Vertices
+	//  NORMAL      Normals
+	//  TANGENT     Tangents
+	//  TEXCOORD_n  TexCoords[n]
+	//  COLOR_0     Colors
+	//
+	// Other attributes are stored in the Attribs map, named after the
+	// attribute with the index suffix joined, e.g. JOINTS_0 becomes Joints0
+	// and WEIGHTS_0 becomes Weights0 (application specific attributes, which
+	// begin with an underscore, keep their name as-is). Their data is of the
+	// float32, gfx.Vec2, gfx.Vec3, gfx.Vec4, gfx.Mat3, or gfx.Mat4 types, or
+	// of the int32 and integer vector types for integers that are not
+	// normalized. Unlike positions and directions, their data is not
+	// converted into the Z up coordinate system.
+	Primitives []*gfx.Mesh
+
+	// The material of each primitive, or nil for the default material.
+	Materials []*Material
+}
+
+// Material is a physically based material of a model, using the metallic
+// roughness model.
+type Material struct {
+	// The name of the material.
+	Name string
+
+	// The base color of the material, in premultiplied alpha form.
+	BaseColor gfx.Color
+
+	// The metalness and roughness of the material, from zero to one.
+	Metallic, Roughness float64
+
+	// The emissive color of the material, opaque.
+	Emissive gfx.Color
+
+	// The scale of the normal map's X and Y components, and the strength of
+	// the occlusion map from zero (none) to one (full).
+	NormalScale, OcclusionStrength float64
+
+	// The texture maps of the material, or nil if absent. The metallic
+	// roughness map stores the roughness in it's green channel and the
+	// metalness in it's blue channel, the occlusion map stores the occlusion
+	// in it's red channel.
+	//
+	// All maps are expected to be sampled with the first texture coordinate
+	// set of the primitives, the sets chosen by the model are ignored.
+	BaseColorMap, MetallicRoughnessMap, NormalMap, OcclusionMap, EmissiveMap *gfx.Texture
+
+	// The alpha mode of the material: NoAlpha for the opaque mode,
+	// BinaryAlpha for the mask mode, and AlphaBlend for the blend mode.
+	AlphaMode gfx.AlphaMode
+
+	// The alpha value below which fragments are discarded, when the alpha
+	// mode is BinaryAlpha.
+	AlphaCutoff float64
+
+	// Whether or not back faces are visible.
+	DoubleSided bool
+}
+
+// newMaterial returns a new material with the default values of the glTF
+// format.
+func newMaterial(name string) *Material {
+	return &Material{
+		Name:              name,
+		BaseColor:         gfx.Color{R: 1, G: 1, B: 1, A: 1},
+		Metallic:          1,
+		Roughness:         1,
+		Emissive:          gfx.Color{R: 0, G: 0, B: 0, A: 1},
+		NormalScale:       1,
+		OcclusionStrength: 1,
+		AlphaCutoff:       0.5,
+	}
+}
+
+// Skin is a skin of a model, which deforms meshes by the transforms of a set
+// of joint nodes.
+type Skin struct {
+	// The name of the skin.
+	Name string
+
+	// The joint nodes of the skin, whose index is referenced by the Joints0
+	// vertex attribute of skinned meshes.
+	Joints []*Node
+
+	// The inverse bind matrix of each joint, which transforms the mesh into
+	// the local space of the joint.
+	InverseBindMatrices []lmath.Mat4
+
+	// The node that is the common root of the joints, or nil if unspecified.
+	Skeleton *Node
+}
+
+// Path is the property of a node that an animation channel animates.
+type Path uint8
+
+const (
+	// The position of the node, each keyframe value has three components.
+	Translation Path = iota
+
+	// The quaternion rotation of the node, each keyframe value has four
+	// components in the order of lmath.Quat (W, X, Y, Z).
+	Rotation
+
+	// The scale of the node, each keyframe value has three components.
+	Scale
+
+	// The morph target weights of the node's mesh, each keyframe value has
+	// one component for each morph target.
+	Weights
+)
+
+// Interpolation is the interpolation used between the keyframes of an
+// animation channel.
+type Interpolation uint8
+
+const (
+	// The values are linearly interpolated (spherically, for rotations).
+	Linear Interpolation = iota
+
+	// The value of each keyframe is held until the next one.
+	Step
+
+	// The values are interpolated with a cubic spline. Each keyframe has
+	// three values: the in-tangent, the value, and the out-tangent.
+	CubicSpline
+)
+
+// Channel is a channel of an animation, which animates a single property of a
+// node.
+type Channel struct {
+	// The animated node, and property of it.
+	Node *Node
+	Path Path
+
+	// The interpolation between keyframes.
+	Interpolation Interpolation
+
+	// The time of each keyframe in seconds, in increasing order.
+	Times []float64
+
+	// The values of the keyframes, converted into the Z up coordinate system
+	// and laid out one after another (see Path and Interpolation for the
+	// values of each keyframe).
+	Values []float64
+}
+
+// Animation is an animation of a model, made of channels that are played
+// together.
+type Animation struct {
+	// The name of the animation.
+	Name string
+
+	// The channels of the animation.
+	Channels []*Channel
+}
+
+// Duration returns the time of the last keyframe of the animation, in seconds.
+func (a *Animation) Duration() float64 {
+	var d float64
+	for _, c := range a.Channels {
+		if n := len(c.Times); n > 0 && c.Times[n-1] > d {
+			d = c.Times[n-1]
+		}
+	}
+	return d
+}
+
+// Model is a model loaded from a glTF file.
+type Model struct {
+	// The scenes of the model, and the default one (or nil if the model does
+	// not specify it).
+	Scenes []*Scene
+	Scene  *Scene
+
+	// The nodes, meshes, materials, textures, skins, and animations of the
+	// model, in the order that they are declared.
+	Nodes      []*Node
+	Meshes     []*Mesh
+	Materials  []*Material
+	Textures   []*gfx.Texture
+	Skins      []*Skin
+	Animations []*Animation
+}
+
+// Names of the shader inputs and samplers that the Objects method assigns the
+// data of each material to.
+const (
+	// Colors of the material, as gfx.Vec4 values.
+	InputBaseColor = "BaseColor"
+	InputEmissive  = "Emissive"
+
+	// Factors of the material, as float32 values.
+	InputMetallic          = "Metallic"
+	InputRoughness         = "Roughness"
+	InputNormalScale       = "NormalScale"
+	InputOcclusionStrength = "OcclusionStrength"
+
+	// Texture maps of the material other than the base color one, which is
+	// bound to the first texture of the object (see gfx.TextureSampler).
+	SamplerMetallicRoughnessMap = "MetallicRoughnessMap"
+	SamplerNormalMap            = "NormalMap"
+	SamplerOcclusionMap         = "OcclusionMap"
+	SamplerEmissiveMap          = "EmissiveMap"
+)
+
+// Objects returns a new object for each primitive of the meshes of the
+// model's default scene (or of it's first scene, if there is no default one),
+// drawn with the given shader. The parent of each object's transform is the
+// transform of the primitive's node, and the data of each primitive's
+// material is assigned to the object as follows, except for texture maps that
+// are nil:
+//  o.Textures = []*gfx.Texture{m.BaseColorMap}
+//  o.Samplers[SamplerNormalMap] = m.NormalMap            // And other maps.
+//  o.InputOverrides[InputBaseColor] = gfx.Vec4{...}      // And emissive.
+//  o.InputOverrides[InputMetallic] = float32(m.Metallic) // And other factors.
+//  o.AlphaMode = m.AlphaMode
+//
+// Objects of materials in the BinaryAlpha alpha mode have their alpha cutoff
+// assigned to the shaders.InputAlphaCutoff input, and those of double sided
+// materials do not use face culling.
+func (m *Model) Objects(shader *gfx.Shader) []*gfx.Object {
+	scene := m.Scene
+	if scene == nil && len(m.Scenes) > 0 {
+		scene = m.Scenes[0]
+	}
+	if scene == nil {
+		return nil
+	}
+	var (
+		objects []*gfx.Object
+		walk    func(n *Node)
+	)
+	walk = func(n *Node) {
+		if n.Mesh != nil {
+			for i, prim := range n.Mesh.Primitives {
+				o := gfx.NewObject()
+				o.Shader = shader
+				o.Meshes = []*gfx.Mesh{prim}
+				o.Transform.SetParent(n.Transform)
+				if mat := n.Mesh.Materials[i]; mat != nil {
+					mat.apply(o)
+				}
+				objects = append(objects, o)
+			}
+		}
+		for _, c := range n.Children {
+			walk(c)
+		}
+	}
+	for _, n := range scene.Nodes {
+		walk(n)
+	}
+	return objects
+}
+
+// apply assigns the data of the material to the object, see Model.Objects.
+func (m *Material) apply(o *gfx.Object) {
+	if m.BaseColorMap != nil {
+		o.Textures = []*gfx.Texture{m.BaseColorMap}
+	}
+	maps := map[string]*gfx.Texture{
+		SamplerMetallicRoughnessMap: m.MetallicRoughnessMap,
+		SamplerNormalMap:            m.NormalMap,
+		SamplerOcclusionMap:         m.OcclusionMap,
+		SamplerEmissiveMap:          m.EmissiveMap,
+	}
+	for name, t := range maps {
+		if t != nil {
+			o.Samplers[name] = t
+		}
+	}
+	colors := map[string]gfx.Color{
+		InputBaseColor: m.BaseColor,
+		InputEmissive:  m.Emissive,
+	}
+	for name, c := range colors {
+		o.InputOverrides[name] = gfx.Vec4{X: c.R, Y: c.G, Z: c.B, W: c.A}
+	}
+	o.InputOverrides[InputMetallic] = float32(m.Metallic)
+	o.InputOverrides[InputRoughness] = float32(m.Roughness)
+	o.InputOverrides[InputNormalScale] = float32(m.NormalScale)
+	o.InputOverrides[InputOcclusionStrength] = float32(m.OcclusionStrength)
+	o.AlphaMode = m.AlphaMode
+	if m.AlphaMode == gfx.BinaryAlpha {
+		o.InputOverrides[shaders.InputAlphaCutoff] = float32(m.AlphaCutoff)
+	}
+	if m.DoubleSided {
+		o.FaceCulling = gfx.NoFaceCulling
+	}
+}
+
+// Load loads the model from the named .gltf or .glb file, and it's buffers and
+// images relative to the directory of the file (see DirOpener).
+func Load(name string) (*Model, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return Decode(f, DirOpener(filepath.Dir(name)))
+}
+
+// Decode decodes the model from the given .gltf or .glb file data. Buffers and
+// images that are not embedded into the data are opened using the given
+// opener, if it is nil then models referencing such files fail to load.
+func Decode(r io.Reader, open Opener) (*Model, error) {
+	d := &decoder{open: open}
+	return d.decode(r)
+}
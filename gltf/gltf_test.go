@@ -0,0 +1,353 @@
+// Copyright 2014 The Azul3D Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package gltf
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"image"
+	"image/png"
+	"math"
+	"strings"
+	"testing"
+
+	"azul3d.org/gfx.v1"
+	"azul3d.org/gfx.v1/shaders"
+	"azul3d.org/lmath.v1"
+)
+
+// builder builds the binary buffer, buffer views, and accessors of a test
+// model.
+type builder struct {
+	buf       bytes.Buffer
+	views     []map[string]interface{}
+	accessors []map[string]interface{}
+}
+
+// view appends the data as a new buffer view, and returns it's index.
+func (b *builder) view(data interface{}) int {
+	for b.buf.Len()%4 != 0 {
+		b.buf.WriteByte(0)
+	}
+	off := b.buf.Len()
+	binary.Write(&b.buf, binary.LittleEndian, data)
+	b.views = append(b.views, map[string]interface{}{
+		"buffer":     0,
+		"byteOffset": off,
+		"byteLength": b.buf.Len() - off,
+	})
+	return len(b.views) - 1
+}
+
+// accessor appends the data as a new accessor, and returns it's index.
+func (b *builder) accessor(typ string, componentType, count int, normalized bool, data interface{}) int {
+	b.accessors = append(b.accessors, map[string]interface{}{
+		"bufferView":    b.view(data),
+		"componentType": componentType,
+		"count":         count,
+		"type":          typ,
+		"normalized":    normalized,
+	})
+	return len(b.accessors) - 1
+}
+
+// testModel returns the data of a test model, in the binary form or with the
+// buffer embedded as a data URI.
+func testModel(t *testing.T, glb bool) []byte {
+	b := new(builder)
+	var img bytes.Buffer
+	if err := png.Encode(&img, image.NewRGBA(image.Rect(0, 0, 4, 2))); err != nil {
+		t.Fatal(err)
+	}
+	imgView := b.view(img.Bytes())
+
+	identity := []float32{1, 0, 0, 0, 0, 1, 0, 0, 0, 0, 1, 0, 0, 0, 0, 1}
+	inverseBind := append([]float32(nil), identity...)
+	inverseBind[13] = 1 // Translation along Y.
+
+	type m map[string]interface{}
+	doc := m{
+		"asset": m{"version": "2.0"},
+		"scene": 0,
+		"scenes": []m{
+			{"name": "main", "nodes": []int{0}},
+		},
+		"nodes": []m{
+			{
+				"name":        "root",
+				"children":    []int{1, 2},
+				"translation": []float64{1, 2, 3},
+				"rotation":    []float64{0, math.Sqrt2 / 2, 0, math.Sqrt2 / 2},
+				"scale":       []float64{1, 2, 3},
+			},
+			{
+				"name":   "child",
+				"mesh":   0,
+				"skin":   0,
+				"matrix": []float64{2, 0, 0, 0, 0, 2, 0, 0, 0, 0, 2, 0, 4, 5, 6, 1},
+			},
+			{"name": "joint"},
+		},
+		"meshes": []m{{
+			"name": "triangle",
+			"primitives": []m{
+				{
+					"attributes": m{
+						"POSITION":     b.accessor("VEC3", componentFloat, 3, false, []float32{0, 0, 0, 1, 0, 0, 0, 1, 0}),
+						"NORMAL":       b.accessor("VEC3", componentFloat, 3, false, []float32{0, 0, 1, 0, 0, 1, 0, 0, 1}),
+						"TEXCOORD_0":   b.accessor("VEC2", componentFloat, 3, false, []float32{0, 0, 1, 0, 0, 1}),
+						"COLOR_0":      b.accessor("VEC4", componentUnsignedByte, 3, true, []uint8{255, 0, 0, 255, 0, 255, 0, 255, 255, 255, 255, 0}),
+						"JOINTS_0":     b.accessor("VEC4", componentUnsignedByte, 3, false, []uint8{0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0}),
+						"WEIGHTS_0":    b.accessor("VEC4", componentFloat, 3, false, []float32{1, 0, 0, 0, 1, 0, 0, 0, 1, 0, 0, 0}),
+						"_TEMPERATURE": b.accessor("SCALAR", componentFloat, 3, false, []float32{10, 20, 30}),
+					},
+					"indices":  b.accessor("SCALAR", componentUnsignedShort, 3, false, []uint16{0, 1, 2}),
+					"material": 0,
+				},
+				{
+					"attributes": m{"POSITION": 0},
+					"mode":       2,
+				},
+			},
+		}},
+		"materials": []m{{
+			"name": "mask",
+			"pbrMetallicRoughness": m{
+				"baseColorFactor":  []float64{1, 0.5, 0, 0.5},
+				"baseColorTexture": m{"index": 0},
+				"metallicFactor":   0,
+				"roughnessFactor":  0.5,
+			},
+			"normalTexture": m{"index": 0, "scale": 2},
+			"alphaMode":     "MASK",
+			"alphaCutoff":   0.25,
+			"doubleSided":   true,
+		}},
+		"images": []m{
+			{"bufferView": imgView, "mimeType": "image/png"},
+		},
+		"samplers": []m{
+			{"wrapS": 33071, "wrapT": 33648, "magFilter": 9728, "minFilter": 9986},
+		},
+		"textures": []m{
+			{"sampler": 0, "source": 0},
+		},
+		"skins": []m{{
+			"joints":              []int{2},
+			"inverseBindMatrices": b.accessor("MAT4", componentFloat, 1, false, inverseBind),
+		}},
+		"animations": []m{{
+			"name": "spin",
+			"channels": []m{
+				{"sampler": 0, "target": m{"node": 2, "path": "rotation"}},
+				{"sampler": 1, "target": m{"node": 2, "path": "translation"}},
+			},
+			"samplers": []m{
+				{
+					"input":  b.accessor("SCALAR", componentFloat, 2, false, []float32{0, 2}),
+					"output": b.accessor("VEC4", componentFloat, 2, false, []float32{0, 0, 0, 1, 0, 1, 0, 0}),
+				},
+				{
+					"input":         b.accessor("SCALAR", componentFloat, 2, false, []float32{0, 1}),
+					"output":        b.accessor("VEC3", componentFloat, 2, false, []float32{0, 1, 0, 0, 2, 0}),
+					"interpolation": "STEP",
+				},
+			},
+		}},
+	}
+	for b.buf.Len()%4 != 0 {
+		b.buf.WriteByte(0)
+	}
+	buffer := m{"byteLength": b.buf.Len()}
+	if !glb {
+		buffer["uri"] = "data:application/octet-stream;base64," + base64.StdEncoding.EncodeToString(b.buf.Bytes())
+	}
+	doc["buffers"] = []m{buffer}
+	doc["bufferViews"] = b.views
+	doc["accessors"] = b.accessors
+
+	data, err := json.Marshal(doc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !glb {
+		return data
+	}
+	for len(data)%4 != 0 {
+		data = append(data, ' ')
+	}
+	var out bytes.Buffer
+	le := binary.LittleEndian
+	binary.Write(&out, le, []uint32{glbMagic, 2, uint32(12 + 8 + len(data) + 8 + b.buf.Len())})
+	binary.Write(&out, le, []uint32{uint32(len(data)), glbChunkJSON})
+	out.Write(data)
+	binary.Write(&out, le, []uint32{uint32(b.buf.Len()), glbChunkBIN})
+	out.Write(b.buf.Bytes())
+	return out.Bytes()
+}
+
+func TestDecode(t *testing.T) {
+	for _, glb := range []bool{false, true} {
+		model, err := Decode(bytes.NewReader(testModel(t, glb)), nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(model.Nodes) != 3 || len(model.Meshes) != 1 || model.Scene != model.Scenes[0] {
+			t.Fatalf("glb=%v: got %d nodes and %d meshes", glb, len(model.Nodes), len(model.Meshes))
+		}
+
+		// Y up to Z up.
+		mesh := model.Meshes[0].Primitives[0]
+		if mesh.Vertices[2] != (gfx.Vec3{0, 0, 1}) || mesh.Normals[0] != (gfx.Vec3{0, -1, 0}) {
+			t.Errorf("glb=%v: got vertex %v and normal %v", glb, mesh.Vertices[2], mesh.Normals[0])
+		}
+		if mesh.AABB.Max != (lmath.Vec3{1, 0, 1}) {
+			t.Errorf("glb=%v: got AABB %v", glb, mesh.AABB)
+		}
+		if len(mesh.Indices) != 3 || mesh.Indices[2] != 2 || mesh.TexCoords[0].Slice[2] != (gfx.TexCoord{0, 1}) {
+			t.Errorf("glb=%v: got indices %v and texture coordinates %v", glb, mesh.Indices, mesh.TexCoords)
+		}
+		if c := mesh.Colors[2]; c != (gfx.Color{0, 0, 0, 0}) {
+			t.Errorf("glb=%v: got color %v, want a premultiplied transparent one", glb, c)
+		}
+		if _, ok := mesh.Attribs["Joints0"].Data.([]gfx.IVec4); !ok {
+			t.Errorf("glb=%v: got joints %T", glb, mesh.Attribs["Joints0"].Data)
+		}
+		if _, ok := mesh.Attribs["Weights0"].Data.([]gfx.Vec4); !ok {
+			t.Errorf("glb=%v: got weights %T", glb, mesh.Attribs["Weights0"].Data)
+		}
+		if temp, ok := mesh.Attribs["_TEMPERATURE"].Data.([]float32); !ok || temp[1] != 20 {
+			t.Errorf("glb=%v: got custom attribute %v", glb, mesh.Attribs["_TEMPERATURE"].Data)
+		}
+		lines := model.Meshes[0].Primitives[1]
+		if lines.Primitive != gfx.Lines || len(lines.Indices) != 6 || lines.Indices[5] != 0 {
+			t.Errorf("glb=%v: got line loop %v %v", glb, lines.Primitive, lines.Indices)
+		}
+	}
+}
+
+func TestDecodeNodes(t *testing.T) {
+	model, err := Decode(bytes.NewReader(testModel(t, false)), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	root, child, joint := model.Nodes[0], model.Nodes[1], model.Nodes[2]
+	if root.Name != "root" || root.Parent != nil || len(root.Children) != 2 || joint.Parent != root {
+		t.Fatal("invalid node hierarchy")
+	}
+	if child.Transform.Parent() != root.Transform {
+		t.Error("child transform is not parented to it's node's parent")
+	}
+	if p := root.Transform.Pos(); p != (lmath.Vec3{1, -3, 2}) {
+		t.Errorf("got root position %v, want {1 -3 2}", p)
+	}
+	if s := root.Transform.Scale(); s != (lmath.Vec3{1, 3, 2}) {
+		t.Errorf("got root scale %v, want {1 3 2}", s)
+	}
+	if q := root.Transform.Quat(); q != (lmath.Quat{W: math.Sqrt2 / 2, Z: math.Sqrt2 / 2}) {
+		t.Errorf("got root rotation %v, want one about the Z axis", q)
+	}
+	if p, s := child.Transform.Pos(), child.Transform.Scale(); p != (lmath.Vec3{4, -6, 5}) || s != (lmath.Vec3{2, 2, 2}) {
+		t.Errorf("got child position %v and scale %v", p, s)
+	}
+
+	skin := model.Skins[0]
+	if child.Skin != skin || len(skin.Joints) != 1 || skin.Joints[0] != joint {
+		t.Fatal("invalid skin")
+	}
+	if row := skin.InverseBindMatrices[0][3]; row != [4]float64{0, 0, 1, 1} {
+		t.Errorf("got inverse bind translation %v, want {0 0 1 1}", row)
+	}
+
+	anim := model.Animations[0]
+	if anim.Name != "spin" || len(anim.Channels) != 2 || anim.Duration() != 2 {
+		t.Fatalf("got animation %q with %d channels", anim.Name, len(anim.Channels))
+	}
+	rot, pos := anim.Channels[0], anim.Channels[1]
+	if rot.Node != joint || rot.Path != Rotation || rot.Interpolation != Linear {
+		t.Error("invalid rotation channel")
+	}
+	if want := []float64{1, 0, 0, 0, 0, 0, 0, 1}; !floatsEqual(rot.Values, want) {
+		t.Errorf("got rotations %v, want %v", rot.Values, want)
+	}
+	if pos.Path != Translation || pos.Interpolation != Step || !floatsEqual(pos.Values, []float64{0, 0, 1, 0, 0, 2}) {
+		t.Errorf("got translations %v", pos.Values)
+	}
+}
+
+func TestDecodeMaterials(t *testing.T) {
+	model, err := Decode(bytes.NewReader(testModel(t, true)), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tex := model.Textures[0]
+	if tex.Bounds.Dx() != 4 || tex.Bounds.Dy() != 2 {
+		t.Errorf("got texture bounds %v", tex.Bounds)
+	}
+	if tex.WrapU != gfx.Clamp || tex.WrapV != gfx.Mirror || tex.MinFilter != gfx.NearestMipmapLinear || tex.MagFilter != gfx.Nearest {
+		t.Errorf("got wrap modes %v %v and filters %v %v", tex.WrapU, tex.WrapV, tex.MinFilter, tex.MagFilter)
+	}
+	mat := model.Materials[0]
+	if mat.BaseColor != (gfx.Color{0.5, 0.25, 0, 0.5}) || mat.Metallic != 0 || mat.Roughness != 0.5 || mat.NormalScale != 2 {
+		t.Errorf("got base color %v, metallic %v, roughness %v", mat.BaseColor, mat.Metallic, mat.Roughness)
+	}
+	if mat.BaseColorMap != tex || mat.NormalMap != tex || mat.EmissiveMap != nil || model.Meshes[0].Materials[0] != mat {
+		t.Error("invalid material maps")
+	}
+
+	objects := model.Objects(nil)
+	if len(objects) != 2 {
+		t.Fatalf("got %d objects, want 2", len(objects))
+	}
+	o := objects[0]
+	if o.Transform.Parent() != model.Nodes[1].Transform || o.Meshes[0] != model.Meshes[0].Primitives[0] {
+		t.Error("object not at it's node")
+	}
+	if o.AlphaMode != gfx.BinaryAlpha || o.InputOverrides[shaders.InputAlphaCutoff] != float32(0.25) || o.FaceCulling != gfx.NoFaceCulling {
+		t.Errorf("got alpha mode %v and face culling %v", o.AlphaMode, o.FaceCulling)
+	}
+	if len(o.Textures) != 1 || o.Textures[0] != tex || o.Samplers[SamplerNormalMap] != tex {
+		t.Error("material textures not applied")
+	}
+	if objects[1].Textures != nil {
+		t.Error("default material has textures")
+	}
+}
+
+func TestDecodeErrors(t *testing.T) {
+	tests := []struct {
+		doc, want string
+	}{
+		{`{"asset": {"version": "1.0"}}`, "unsupported version"},
+		{`{"asset": {"version": "2.0"}, "extensionsRequired": ["KHR_draco_mesh_compression"]}`, "required extension"},
+		{`{"asset": {"version": "2.0"}, "nodes": [{"children": [1]}, {"children": [0]}]}`, "own ancestor"},
+		{`{"asset": {"version": "2.0"}, "nodes": [{"mesh": 0}]}`, "mesh 0 does not exist"},
+		{`{"asset": {"version": "2.0"}, "buffers": [{"uri": "missing.bin", "byteLength": 4}],
+			"bufferViews": [{"buffer": 0, "byteLength": 4}],
+			"accessors": [{"bufferView": 0, "componentType": 5126, "count": 1, "type": "SCALAR"}],
+			"meshes": [{"primitives": [{"attributes": {"POSITION": 0}}]}]}`, "no opener"},
+	}
+	for _, tst := range tests {
+		_, err := Decode(strings.NewReader(tst.doc), nil)
+		if err == nil || !strings.Contains(err.Error(), tst.want) {
+			t.Errorf("got error %v, want one containing %q", err, tst.want)
+		}
+	}
+}
+
+// floatsEqual tells if the two slices are approximately equal.
+func floatsEqual(a, b []float64) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if !lmath.AlmostEqual(a[i], b[i], 1e-6) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,157 @@
+// Copyright 2014 The Azul3D Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package gltf
+
+// The JSON document of a glTF model. Only the properties that are used by
+// this package are declared, and optional references are pointers such that
+// they are nil when absent.
+
+type document struct {
+	Asset struct {
+		Version    string `json:"version"`
+		MinVersion string `json:"minVersion"`
+	} `json:"asset"`
+	ExtensionsRequired []string        `json:"extensionsRequired"`
+	Scene              *int            `json:"scene"`
+	Scenes             []docScene      `json:"scenes"`
+	Nodes              []docNode       `json:"nodes"`
+	Meshes             []docMesh       `json:"meshes"`
+	Accessors          []docAccessor   `json:"accessors"`
+	BufferViews        []docBufferView `json:"bufferViews"`
+	Buffers            []docBuffer     `json:"buffers"`
+	Images             []docImage      `json:"images"`
+	Samplers           []docSampler    `json:"samplers"`
+	Textures           []docTexture    `json:"textures"`
+	Materials          []docMaterial   `json:"materials"`
+	Skins              []docSkin       `json:"skins"`
+	Animations         []docAnimation  `json:"animations"`
+}
+
+type docScene struct {
+	Name  string `json:"name"`
+	Nodes []int  `json:"nodes"`
+}
+
+type docNode struct {
+	Name        string       `json:"name"`
+	Children    []int        `json:"children"`
+	Mesh        *int         `json:"mesh"`
+	Skin        *int         `json:"skin"`
+	Matrix      *[16]float64 `json:"matrix"`
+	Translation *[3]float64  `json:"translation"`
+	Rotation    *[4]float64  `json:"rotation"`
+	Scale       *[3]float64  `json:"scale"`
+}
+
+type docMesh struct {
+	Name       string         `json:"name"`
+	Primitives []docPrimitive `json:"primitives"`
+}
+
+type docPrimitive struct {
+	Attributes map[string]int `json:"attributes"`
+	Indices    *int           `json:"indices"`
+	Material   *int           `json:"material"`
+	Mode       *int           `json:"mode"`
+}
+
+type docAccessor struct {
+	BufferView    *int   `json:"bufferView"`
+	ByteOffset    int    `json:"byteOffset"`
+	ComponentType int    `json:"componentType"`
+	Normalized    bool   `json:"normalized"`
+	Count         int    `json:"count"`
+	Type          string `json:"type"`
+	Sparse        *struct {
+		Count   int `json:"count"`
+		Indices struct {
+			BufferView    int `json:"bufferView"`
+			ByteOffset    int `json:"byteOffset"`
+			ComponentType int `json:"componentType"`
+		} `json:"indices"`
+		Values struct {
+			BufferView int `json:"bufferView"`
+			ByteOffset int `json:"byteOffset"`
+		} `json:"values"`
+	} `json:"sparse"`
+}
+
+type docBufferView struct {
+	Buffer     int `json:"buffer"`
+	ByteOffset int `json:"byteOffset"`
+	ByteLength int `json:"byteLength"`
+	ByteStride int `json:"byteStride"`
+}
+
+type docBuffer struct {
+	URI        string `json:"uri"`
+	ByteLength int    `json:"byteLength"`
+}
+
+type docImage struct {
+	Name       string `json:"name"`
+	URI        string `json:"uri"`
+	MimeType   string `json:"mimeType"`
+	BufferView *int   `json:"bufferView"`
+}
+
+type docSampler struct {
+	MagFilter int `json:"magFilter"`
+	MinFilter int `json:"minFilter"`
+	WrapS     int `json:"wrapS"`
+	WrapT     int `json:"wrapT"`
+}
+
+type docTexture struct {
+	Sampler *int `json:"sampler"`
+	Source  *int `json:"source"`
+}
+
+type docTextureRef struct {
+	Index    int      `json:"index"`
+	Scale    *float64 `json:"scale"`
+	Strength *float64 `json:"strength"`
+}
+
+type docMaterial struct {
+	Name                 string `json:"name"`
+	PBRMetallicRoughness *struct {
+		BaseColorFactor          *[4]float64    `json:"baseColorFactor"`
+		BaseColorTexture         *docTextureRef `json:"baseColorTexture"`
+		MetallicFactor           *float64       `json:"metallicFactor"`
+		RoughnessFactor          *float64       `json:"roughnessFactor"`
+		MetallicRoughnessTexture *docTextureRef `json:"metallicRoughnessTexture"`
+	} `json:"pbrMetallicRoughness"`
+	NormalTexture    *docTextureRef `json:"normalTexture"`
+	OcclusionTexture *docTextureRef `json:"occlusionTexture"`
+	EmissiveTexture  *docTextureRef `json:"emissiveTexture"`
+	EmissiveFactor   *[3]float64    `json:"emissiveFactor"`
+	AlphaMode        string         `json:"alphaMode"`
+	AlphaCutoff      *float64       `json:"alphaCutoff"`
+	DoubleSided      bool           `json:"doubleSided"`
+}
+
+type docSkin struct {
+	Name                string `json:"name"`
+	InverseBindMatrices *int   `json:"inverseBindMatrices"`
+	Skeleton            *int   `json:"skeleton"`
+	Joints              []int  `json:"joints"`
+}
+
+type docAnimation struct {
+	Name     string `json:"name"`
+	Channels []struct {
+		Sampler int `json:"sampler"`
+		Target  struct {
+			Node *int   `json:"node"`
+			Path string `json:"path"`
+		} `json:"target"`
+	} `json:"channels"`
+	Samplers []struct {
+		Input         int    `json:"input"`
+		Output        int    `json:"output"`
+		Interpolation string `json:"interpolation"`
+	} `json:"samplers"`
+}
@@ -0,0 +1,147 @@
+// Copyright 2014 The Azul3D Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package gltf
+
+import (
+	"fmt"
+	"image"
+
+	"azul3d.org/gfx.v1"
+)
+
+// Wrap modes of glTF samplers.
+var wrapModes = map[int]gfx.TexWrap{
+	10497: gfx.Repeat,
+	33071: gfx.Clamp,
+	33648: gfx.Mirror,
+}
+
+// Filters of glTF samplers, only the first two may be used for magnification.
+var filters = map[int]gfx.TexFilter{
+	9728: gfx.Nearest,
+	9729: gfx.Linear,
+	9984: gfx.NearestMipmapNearest,
+	9985: gfx.LinearMipmapNearest,
+	9986: gfx.NearestMipmapLinear,
+	9987: gfx.LinearMipmapLinear,
+}
+
+// textures builds the textures of the model, decoding each image once.
+func (d *decoder) textures() error {
+	images := make([]image.Image, len(d.doc.Images))
+	for i, dt := range d.doc.Textures {
+		if dt.Source == nil {
+			return fmt.Errorf("gltf: texture %d has no image", i)
+		}
+		src := *dt.Source
+		if err := checkRef("image", src, len(images)); err != nil {
+			return err
+		}
+		if images[src] == nil {
+			r, err := d.image(src)
+			if err != nil {
+				return err
+			}
+			if images[src], _, err = image.Decode(r); err != nil {
+				return fmt.Errorf("gltf: image %d: %v", src, err)
+			}
+		}
+
+		t := gfx.NewTexture()
+		t.Source = images[src]
+		t.Bounds = images[src].Bounds()
+		t.WrapU, t.WrapV = gfx.Repeat, gfx.Repeat
+		t.MinFilter, t.MagFilter = gfx.LinearMipmapLinear, gfx.Linear
+		if dt.Sampler != nil {
+			if err := checkRef("sampler", *dt.Sampler, len(d.doc.Samplers)); err != nil {
+				return err
+			}
+			s := d.doc.Samplers[*dt.Sampler]
+			if w, ok := wrapModes[s.WrapS]; ok {
+				t.WrapU = w
+			}
+			if w, ok := wrapModes[s.WrapT]; ok {
+				t.WrapV = w
+			}
+			if f, ok := filters[s.MinFilter]; ok {
+				t.MinFilter = f
+			}
+			if f, ok := filters[s.MagFilter]; ok && !f.Mipmapped() {
+				t.MagFilter = f
+			}
+		}
+		d.model.Textures = append(d.model.Textures, t)
+	}
+	return nil
+}
+
+// texture returns the texture referenced by the given material property, or
+// nil if it is absent.
+func (d *decoder) texture(ref *docTextureRef) (*gfx.Texture, error) {
+	if ref == nil {
+		return nil, nil
+	}
+	if err := checkRef("texture", ref.Index, len(d.model.Textures)); err != nil {
+		return nil, err
+	}
+	return d.model.Textures[ref.Index], nil
+}
+
+// materials builds the materials of the model.
+func (d *decoder) materials() error {
+	for i, dm := range d.doc.Materials {
+		m := newMaterial(dm.Name)
+		maps := map[**gfx.Texture]*docTextureRef{
+			&m.NormalMap:    dm.NormalTexture,
+			&m.OcclusionMap: dm.OcclusionTexture,
+			&m.EmissiveMap:  dm.EmissiveTexture,
+		}
+		if pbr := dm.PBRMetallicRoughness; pbr != nil {
+			if c := pbr.BaseColorFactor; c != nil {
+				m.BaseColor = premultiply(c[0], c[1], c[2], c[3])
+			}
+			if pbr.MetallicFactor != nil {
+				m.Metallic = *pbr.MetallicFactor
+			}
+			if pbr.RoughnessFactor != nil {
+				m.Roughness = *pbr.RoughnessFactor
+			}
+			maps[&m.BaseColorMap] = pbr.BaseColorTexture
+			maps[&m.MetallicRoughnessMap] = pbr.MetallicRoughnessTexture
+		}
+		for t, ref := range maps {
+			var err error
+			if *t, err = d.texture(ref); err != nil {
+				return err
+			}
+		}
+		if r := dm.NormalTexture; r != nil && r.Scale != nil {
+			m.NormalScale = *r.Scale
+		}
+		if r := dm.OcclusionTexture; r != nil && r.Strength != nil {
+			m.OcclusionStrength = *r.Strength
+		}
+		if c := dm.EmissiveFactor; c != nil {
+			m.Emissive = gfx.Color{R: float32(c[0]), G: float32(c[1]), B: float32(c[2]), A: 1}
+		}
+
+		switch dm.AlphaMode {
+		case "", "OPAQUE":
+			m.AlphaMode = gfx.NoAlpha
+		case "MASK":
+			m.AlphaMode = gfx.BinaryAlpha
+		case "BLEND":
+			m.AlphaMode = gfx.AlphaBlend
+		default:
+			return fmt.Errorf("gltf: material %d has an invalid alpha mode %q", i, dm.AlphaMode)
+		}
+		if dm.AlphaCutoff != nil {
+			m.AlphaCutoff = *dm.AlphaCutoff
+		}
+		m.DoubleSided = dm.DoubleSided
+		d.model.Materials = append(d.model.Materials, m)
+	}
+	return nil
+}
@@ -0,0 +1,301 @@
+// Copyright 2014 The Azul3D Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package gltf
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"azul3d.org/gfx.v1"
+)
+
+// Primitive modes of glTF, other than LINE_LOOP (2) which is converted into
+// lines.
+var modes = map[int]gfx.Primitive{
+	0: gfx.Points,
+	1: gfx.Lines,
+	3: gfx.LineStrip,
+	4: gfx.Triangles,
+	5: gfx.TriangleStrip,
+	6: gfx.TriangleFan,
+}
+
+const modeLineLoop = 2
+
+// attribName returns the name of the Attribs entry of the given glTF vertex
+// attribute, see Mesh.Primitives.
+func attribName(name string) string {
+	if strings.HasPrefix(name, "_") {
+		return name
+	}
+	base, index := name, ""
+	if i := strings.LastIndex(name, "_"); i >= 0 {
+		if _, err := strconv.Atoi(name[i+1:]); err == nil {
+			base, index = name[:i], name[i+1:]
+		}
+	}
+	base = strings.ToLower(base)
+	return strings.ToUpper(base[:1]) + base[1:] + index
+}
+
+// attribData converts the components of the accessor with the given index
+// into the data of a gfx.VertexAttrib.
+func (d *decoder) attribData(acc int, v []float64, n int) (interface{}, error) {
+	a := d.doc.Accessors[acc]
+	if a.ComponentType != componentFloat && !a.Normalized {
+		switch a.Type {
+		case "SCALAR":
+			data := make([]int32, len(v))
+			for i := range data {
+				data[i] = int32(v[i])
+			}
+			return data, nil
+		case "VEC2":
+			data := make([]gfx.IVec2, len(v)/2)
+			for i := range data {
+				data[i] = gfx.IVec2{X: int32(v[i*2]), Y: int32(v[i*2+1])}
+			}
+			return data, nil
+		case "VEC3":
+			data := make([]gfx.IVec3, len(v)/3)
+			for i := range data {
+				data[i] = gfx.IVec3{X: int32(v[i*3]), Y: int32(v[i*3+1]), Z: int32(v[i*3+2])}
+			}
+			return data, nil
+		case "VEC4":
+			data := make([]gfx.IVec4, len(v)/4)
+			for i := range data {
+				data[i] = gfx.IVec4{X: int32(v[i*4]), Y: int32(v[i*4+1]), Z: int32(v[i*4+2]), W: int32(v[i*4+3])}
+			}
+			return data, nil
+		}
+	} else {
+		switch a.Type {
+		case "SCALAR":
+			data := make([]float32, len(v))
+			for i := range data {
+				data[i] = float32(v[i])
+			}
+			return data, nil
+		case "VEC2":
+			data := make([]gfx.Vec2, len(v)/2)
+			for i := range data {
+				data[i] = gfx.Vec2{X: float32(v[i*2]), Y: float32(v[i*2+1])}
+			}
+			return data, nil
+		case "VEC3":
+			data := make([]gfx.Vec3, len(v)/3)
+			for i := range data {
+				data[i] = gfx.Vec3{X: float32(v[i*3]), Y: float32(v[i*3+1]), Z: float32(v[i*3+2])}
+			}
+			return data, nil
+		case "VEC4":
+			data := make([]gfx.Vec4, len(v)/4)
+			for i := range data {
+				data[i] = gfx.Vec4{X: float32(v[i*4]), Y: float32(v[i*4+1]), Z: float32(v[i*4+2]), W: float32(v[i*4+3])}
+			}
+			return data, nil
+		case "MAT3":
+			// Column major matrices of column vectors, read row by row, are
+			// the row major matrices of row vectors used by the gfx package.
+			data := make([]gfx.Mat3, len(v)/9)
+			for i := range data {
+				for j := 0; j < 9; j++ {
+					data[i][j/3][j%3] = float32(v[i*9+j])
+				}
+			}
+			return data, nil
+		case "MAT4":
+			data := make([]gfx.Mat4, len(v)/16)
+			for i := range data {
+				for j := 0; j < 16; j++ {
+					data[i][j/4][j%4] = float32(v[i*16+j])
+				}
+			}
+			return data, nil
+		}
+	}
+	return nil, fmt.Errorf("gltf: accessor %d has an unsupported vertex attribute type", acc)
+}
+
+// primitive builds the mesh of the given primitive.
+func (d *decoder) primitive(p docPrimitive) (*gfx.Mesh, error) {
+	pos, ok := p.Attributes["POSITION"]
+	if !ok {
+		return nil, errors.New("gltf: primitive without positions")
+	}
+	if err := checkRef("accessor", pos, len(d.doc.Accessors)); err != nil {
+		return nil, err
+	}
+	count := d.doc.Accessors[pos].Count
+
+	m := gfx.NewMesh()
+	for name, acc := range p.Attributes {
+		if err := checkRef("accessor", acc, len(d.doc.Accessors)); err != nil {
+			return nil, err
+		}
+		if d.doc.Accessors[acc].Count != count {
+			return nil, fmt.Errorf("gltf: vertex attribute %s has %d elements, want %d", name, d.doc.Accessors[acc].Count, count)
+		}
+		switch {
+		case name == "POSITION":
+			v, err := d.accessorOf(acc, 3)
+			if err != nil {
+				return nil, err
+			}
+			m.Vertices = make([]gfx.Vec3, count)
+			for i := range m.Vertices {
+				m.Vertices[i] = gfx.ConvertVec3(zUp(v[i*3], v[i*3+1], v[i*3+2]))
+			}
+
+		case name == "NORMAL":
+			v, err := d.accessorOf(acc, 3)
+			if err != nil {
+				return nil, err
+			}
+			m.Normals = make([]gfx.Vec3, count)
+			for i := range m.Normals {
+				m.Normals[i] = gfx.ConvertVec3(zUp(v[i*3], v[i*3+1], v[i*3+2]))
+			}
+
+		case name == "TANGENT":
+			v, err := d.accessorOf(acc, 4)
+			if err != nil {
+				return nil, err
+			}
+			m.Tangents = make([]gfx.Vec4, count)
+			for i := range m.Tangents {
+				t := zUp(v[i*4], v[i*4+1], v[i*4+2])
+				m.Tangents[i] = gfx.Vec4{X: float32(t.X), Y: float32(t.Y), Z: float32(t.Z), W: float32(v[i*4+3])}
+			}
+
+		case name == "COLOR_0":
+			v, n, err := d.accessor(acc)
+			if err != nil {
+				return nil, err
+			}
+			if n != 3 && n != 4 {
+				return nil, fmt.Errorf("gltf: accessor %d has %d components, want 3 or 4", acc, n)
+			}
+			m.Colors = make([]gfx.Color, count)
+			for i := range m.Colors {
+				c := v[i*n : (i+1)*n]
+				if n == 3 {
+					m.Colors[i] = premultiply(c[0], c[1], c[2], 1)
+				} else {
+					m.Colors[i] = premultiply(c[0], c[1], c[2], c[3])
+				}
+			}
+
+		case strings.HasPrefix(name, "TEXCOORD_"):
+			set, err := strconv.Atoi(strings.TrimPrefix(name, "TEXCOORD_"))
+			if err != nil || set < 0 {
+				return nil, fmt.Errorf("gltf: invalid vertex attribute %s", name)
+			}
+			v, err := d.accessorOf(acc, 2)
+			if err != nil {
+				return nil, err
+			}
+			uv := make([]gfx.TexCoord, count)
+			for i := range uv {
+				uv[i] = gfx.TexCoord{U: float32(v[i*2]), V: float32(v[i*2+1])}
+			}
+			for len(m.TexCoords) <= set {
+				m.TexCoords = append(m.TexCoords, gfx.TexCoordSet{})
+			}
+			m.TexCoords[set].Slice = uv
+
+		default:
+			v, n, err := d.accessor(acc)
+			if err != nil {
+				return nil, err
+			}
+			data, err := d.attribData(acc, v, n)
+			if err != nil {
+				return nil, err
+			}
+			m.Attribs[attribName(name)] = gfx.VertexAttrib{Data: data}
+		}
+	}
+	for set, s := range m.TexCoords {
+		if s.Slice == nil {
+			return nil, fmt.Errorf("gltf: missing vertex attribute TEXCOORD_%d", set)
+		}
+	}
+
+	if p.Indices != nil {
+		v, err := d.accessorOf(*p.Indices, 1)
+		if err != nil {
+			return nil, err
+		}
+		m.Indices = make([]uint32, len(v))
+		for i, index := range v {
+			if int(index) >= count {
+				return nil, fmt.Errorf("gltf: accessor %d has an index out of range", *p.Indices)
+			}
+			m.Indices[i] = uint32(index)
+		}
+	}
+
+	mode := 4
+	if p.Mode != nil {
+		mode = *p.Mode
+	}
+	if mode == modeLineLoop {
+		lineLoop(m, count)
+		mode = 1
+	}
+	var known bool
+	if m.Primitive, known = modes[mode]; !known {
+		return nil, fmt.Errorf("gltf: unsupported primitive mode %d", mode)
+	}
+	m.CalculateBounds()
+	return m, nil
+}
+
+// lineLoop converts the line loop mesh with the given number of vertices into
+// separate line segments, by indexing it.
+func lineLoop(m *gfx.Mesh, count int) {
+	loop := m.Indices
+	if loop == nil {
+		loop = make([]uint32, count)
+		for i := range loop {
+			loop[i] = uint32(i)
+		}
+	}
+	m.Indices = make([]uint32, 0, len(loop)*2)
+	if len(loop) < 2 {
+		return
+	}
+	for i, index := range loop {
+		m.Indices = append(m.Indices, index, loop[(i+1)%len(loop)])
+	}
+}
+
+// meshes builds the meshes of the model.
+func (d *decoder) meshes() error {
+	for i, dm := range d.doc.Meshes {
+		mesh := &Mesh{Name: dm.Name}
+		for _, p := range dm.Primitives {
+			prim, err := d.primitive(p)
+			if err != nil {
+				return fmt.Errorf("%v (in mesh %d)", err, i)
+			}
+			var mat *Material
+			if p.Material != nil {
+				if err := checkRef("material", *p.Material, len(d.model.Materials)); err != nil {
+					return err
+				}
+				mat = d.model.Materials[*p.Material]
+			}
+			mesh.Primitives = append(mesh.Primitives, prim)
+			mesh.Materials = append(mesh.Materials, mat)
+		}
+		d.model.Meshes = append(d.model.Meshes, mesh)
+	}
+	return nil
+}
@@ -0,0 +1,280 @@
+// Copyright 2014 The Azul3D Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package gltf
+
+import (
+	"fmt"
+
+	"azul3d.org/gfx.v1"
+	"azul3d.org/lmath.v1"
+)
+
+// zUp converts the Y up position or direction into the Z up coordinate system.
+func zUp(x, y, z float64) lmath.Vec3 {
+	return lmath.Vec3{X: x, Y: -z, Z: y}
+}
+
+// zUpScale converts the Y up scale into the Z up coordinate system.
+func zUpScale(x, y, z float64) lmath.Vec3 {
+	return lmath.Vec3{X: x, Y: z, Z: y}
+}
+
+// zUpQuat converts the Y up quaternion, in the component order of glTF, into
+// the Z up coordinate system.
+func zUpQuat(x, y, z, w float64) lmath.Quat {
+	return lmath.Quat{W: w, X: x, Y: -z, Z: y}
+}
+
+// zUpAxes are the Y up axes (and their sign) that each axis of the Z up
+// coordinate system is made of, see zUp.
+var zUpAxes = [4]struct {
+	axis int
+	sign float64
+}{{0, 1}, {2, -1}, {1, 1}, {3, 1}}
+
+// zUpMat4 converts the Y up, column major matrix of column vectors into a Z up
+// matrix of row vectors.
+func zUpMat4(v []float64) lmath.Mat4 {
+	var m lmath.Mat4
+	for i, r := range zUpAxes {
+		for j, c := range zUpAxes {
+			// Read row by row, that is transposed.
+			m[i][j] = r.sign * c.sign * v[r.axis*4+c.axis]
+		}
+	}
+	return m
+}
+
+// nodes builds the node hierarchy of the model.
+func (d *decoder) nodes() error {
+	nodes := make([]*Node, len(d.doc.Nodes))
+	for i, dn := range d.doc.Nodes {
+		nodes[i] = &Node{Name: dn.Name, Transform: gfx.NewTransform()}
+	}
+	for i, dn := range d.doc.Nodes {
+		n := nodes[i]
+		for _, c := range dn.Children {
+			if err := checkRef("node", c, len(nodes)); err != nil {
+				return err
+			}
+			child := nodes[c]
+			if child.Parent != nil || child == n {
+				return fmt.Errorf("gltf: node %d has more than one parent", c)
+			}
+			child.Parent = n
+			child.Transform.SetParent(n.Transform)
+			n.Children = append(n.Children, child)
+		}
+		if dn.Mesh != nil {
+			if err := checkRef("mesh", *dn.Mesh, len(d.model.Meshes)); err != nil {
+				return err
+			}
+			n.Mesh = d.model.Meshes[*dn.Mesh]
+		}
+
+		if dn.Matrix != nil {
+			n.Transform.SetMat4(zUpMat4(dn.Matrix[:]))
+			continue
+		}
+		if v := dn.Translation; v != nil {
+			n.Transform.SetPos(zUp(v[0], v[1], v[2]))
+		}
+		if v := dn.Rotation; v != nil {
+			n.Transform.SetQuat(zUpQuat(v[0], v[1], v[2], v[3]))
+		}
+		if v := dn.Scale; v != nil {
+			n.Transform.SetScale(zUpScale(v[0], v[1], v[2]))
+		}
+	}
+
+	// Each node has at most one parent, so the hierarchy has a cycle if
+	// walking up from a node takes more steps than there are nodes.
+	for i, n := range nodes {
+		steps := 0
+		for p := n.Parent; p != nil; p = p.Parent {
+			if steps++; steps > len(nodes) {
+				return fmt.Errorf("gltf: node %d is it's own ancestor", i)
+			}
+		}
+	}
+	d.model.Nodes = nodes
+	return nil
+}
+
+// node returns the node with the given index.
+func (d *decoder) node(i int) (*Node, error) {
+	if err := checkRef("node", i, len(d.model.Nodes)); err != nil {
+		return nil, err
+	}
+	return d.model.Nodes[i], nil
+}
+
+// skins builds the skins of the model, and assigns them to their nodes.
+func (d *decoder) skins() error {
+	for i, ds := range d.doc.Skins {
+		s := &Skin{Name: ds.Name}
+		for _, j := range ds.Joints {
+			joint, err := d.node(j)
+			if err != nil {
+				return err
+			}
+			s.Joints = append(s.Joints, joint)
+		}
+		if ds.Skeleton != nil {
+			var err error
+			if s.Skeleton, err = d.node(*ds.Skeleton); err != nil {
+				return err
+			}
+		}
+		s.InverseBindMatrices = make([]lmath.Mat4, len(s.Joints))
+		if ds.InverseBindMatrices == nil {
+			for j := range s.InverseBindMatrices {
+				s.InverseBindMatrices[j] = lmath.Mat4Identity
+			}
+		} else {
+			v, err := d.accessorOf(*ds.InverseBindMatrices, 16)
+			if err != nil {
+				return err
+			}
+			if len(v) < len(s.Joints)*16 {
+				return fmt.Errorf("gltf: skin %d has less inverse bind matrices than joints", i)
+			}
+			for j := range s.InverseBindMatrices {
+				s.InverseBindMatrices[j] = zUpMat4(v[j*16 : (j+1)*16])
+			}
+		}
+		d.model.Skins = append(d.model.Skins, s)
+	}
+	for i, dn := range d.doc.Nodes {
+		if dn.Skin != nil {
+			if err := checkRef("skin", *dn.Skin, len(d.model.Skins)); err != nil {
+				return err
+			}
+			d.model.Nodes[i].Skin = d.model.Skins[*dn.Skin]
+		}
+	}
+	return nil
+}
+
+// paths are the animation channel paths of glTF, and the number of components
+// of their keyframe values (zero for a variable number).
+var paths = map[string]struct {
+	path Path
+	n    int
+}{
+	"translation": {Translation, 3},
+	"rotation":    {Rotation, 4},
+	"scale":       {Scale, 3},
+	"weights":     {Weights, 0},
+}
+
+// interpolations are the animation sampler interpolations of glTF.
+var interpolations = map[string]Interpolation{
+	"":            Linear,
+	"LINEAR":      Linear,
+	"STEP":        Step,
+	"CUBICSPLINE": CubicSpline,
+}
+
+// animations builds the animations of the model. Channels targeting
+// properties that are not supported (e.g. of extensions) are skipped.
+func (d *decoder) animations() error {
+	for i, da := range d.doc.Animations {
+		a := &Animation{Name: da.Name}
+		for _, dc := range da.Channels {
+			p, ok := paths[dc.Target.Path]
+			if !ok || dc.Target.Node == nil {
+				continue
+			}
+			if err := checkRef("animation sampler", dc.Sampler, len(da.Samplers)); err != nil {
+				return err
+			}
+			s := da.Samplers[dc.Sampler]
+			c := &Channel{Path: p.path}
+			var err error
+			if c.Node, err = d.node(*dc.Target.Node); err != nil {
+				return err
+			}
+			if c.Interpolation, ok = interpolations[s.Interpolation]; !ok {
+				return fmt.Errorf("gltf: unsupported interpolation %q", s.Interpolation)
+			}
+			if c.Times, err = d.accessorOf(s.Input, 1); err != nil {
+				return err
+			}
+			if c.Values, err = d.channelValues(s.Output, p.path, p.n); err != nil {
+				return err
+			}
+
+			keys := len(c.Times)
+			if c.Interpolation == CubicSpline {
+				keys *= 3
+			}
+			n := p.n
+			if n == 0 && keys > 0 {
+				// One component for each morph target.
+				n = len(c.Values) / keys
+			}
+			if len(c.Values) != keys*n {
+				return fmt.Errorf("gltf: animation %d has a channel with mismatched keyframes", i)
+			}
+			a.Channels = append(a.Channels, c)
+		}
+		d.model.Animations = append(d.model.Animations, a)
+	}
+	return nil
+}
+
+// channelValues decodes the keyframe values of the given accessor for the
+// path, whose values have n components (or one, if n is zero), converting them
+// into the Z up coordinate system.
+func (d *decoder) channelValues(acc int, path Path, n int) ([]float64, error) {
+	if n == 0 {
+		return d.accessorOf(acc, 1)
+	}
+	v, err := d.accessorOf(acc, n)
+	if err != nil {
+		return nil, err
+	}
+	for i := 0; i < len(v); i += n {
+		e := v[i : i+n]
+		switch path {
+		case Translation:
+			p := zUp(e[0], e[1], e[2])
+			e[0], e[1], e[2] = p.X, p.Y, p.Z
+		case Rotation:
+			q := zUpQuat(e[0], e[1], e[2], e[3])
+			e[0], e[1], e[2], e[3] = q.W, q.X, q.Y, q.Z
+		case Scale:
+			s := zUpScale(e[0], e[1], e[2])
+			e[0], e[1], e[2] = s.X, s.Y, s.Z
+		}
+	}
+	return v, nil
+}
+
+// scenes builds the scenes of the model.
+func (d *decoder) scenes() error {
+	for _, ds := range d.doc.Scenes {
+		s := &Scene{Name: ds.Name}
+		for _, i := range ds.Nodes {
+			n, err := d.node(i)
+			if err != nil {
+				return err
+			}
+			if n.Parent != nil {
+				return fmt.Errorf("gltf: scene %q has a root node with a parent", ds.Name)
+			}
+			s.Nodes = append(s.Nodes, n)
+		}
+		d.model.Scenes = append(d.model.Scenes, s)
+	}
+	if d.doc.Scene != nil {
+		if err := checkRef("scene", *d.doc.Scene, len(d.model.Scenes)); err != nil {
+			return err
+		}
+		d.model.Scene = d.model.Scenes[*d.doc.Scene]
+	}
+	return nil
+}
@@ -0,0 +1,241 @@
+// Copyright 2014 The Azul3D Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package gfx
+
+import (
+	"bytes"
+	"encoding/binary"
+	"reflect"
+)
+
+// remapSlice returns a new slice whose i-th element is the src[i]-th element of
+// the given slice (or the zero value if out of range). Each slice of a slice of
+// slices is remapped. Empty slices are returned as-is.
+func remapSlice(data interface{}, src []uint32) interface{} {
+	v := reflect.ValueOf(data)
+	if v.Kind() != reflect.Slice || v.Len() == 0 {
+		return data
+	}
+	if v.Type().Elem().Kind() == reflect.Slice {
+		// A slice of data slices, e.g. [][]Vec3.
+		out := reflect.MakeSlice(v.Type(), v.Len(), v.Len())
+		for i := 0; i < v.Len(); i++ {
+			out.Index(i).Set(reflect.ValueOf(remapSlice(v.Index(i).Interface(), src)))
+		}
+		return out.Interface()
+	}
+	out := reflect.MakeSlice(v.Type(), len(src), len(src))
+	for i, s := range src {
+		if int(s) < v.Len() {
+			out.Index(i).Set(v.Index(int(s)))
+		}
+	}
+	return out.Interface()
+}
+
+// remap replaces each non-empty per-vertex data slice of the mesh (Vertices,
+// Normals, Tangents, Colors, Bary, TexCoords, and Attribs) by one whose i-th
+// element is the src[i]-th element of the original, and marks each of them as
+// changed. Indices are left untouched.
+//
+// The mesh's write lock must be held for this method to operate safely.
+func (m *Mesh) remap(src []uint32) {
+	m.Vertices = remapSlice(m.Vertices, src).([]Vec3)
+	m.VerticesChanged = true
+	m.Normals = remapSlice(m.Normals, src).([]Vec3)
+	m.NormalsChanged = true
+	m.Tangents = remapSlice(m.Tangents, src).([]Vec4)
+	m.TangentsChanged = true
+	m.Colors = remapSlice(m.Colors, src).([]Color)
+	m.ColorsChanged = true
+	m.Bary = remapSlice(m.Bary, src).([]Vec3)
+	m.BaryChanged = true
+	for i, set := range m.TexCoords {
+		m.TexCoords[i] = TexCoordSet{
+			Slice:   remapSlice(set.Slice, src).([]TexCoord),
+			Changed: true,
+		}
+	}
+	for name, a := range m.Attribs {
+		if !attribTypes[reflect.TypeOf(a.Data)] {
+			continue
+		}
+		m.Attribs[name] = VertexAttrib{
+			Data:    remapSlice(a.Data, src),
+			Changed: true,
+		}
+	}
+}
+
+// split duplicates the vertices of an indexed mesh, such that all of the
+// indices referring to a single vertex have the same key (as returned by the
+// given function for each index of m.Indices). The first key found for each
+// vertex keeps the original vertex.
+//
+// The mesh's write lock must be held for this method to operate safely.
+func (m *Mesh) split(key func(e int) interface{}) {
+	type dup struct {
+		vertex uint32
+		key    interface{}
+	}
+	n := uint32(len(m.Vertices))
+	src := make([]uint32, n)
+	for i := range src {
+		src[i] = uint32(i)
+	}
+	first := make(map[uint32]interface{}, n)
+	dups := make(map[dup]uint32)
+	for e, v := range m.Indices {
+		if v >= n {
+			continue
+		}
+		k := key(e)
+		if fk, ok := first[v]; !ok {
+			first[v] = k
+			continue
+		} else if fk == k {
+			continue
+		}
+		d, ok := dups[dup{v, k}]
+		if !ok {
+			d = uint32(len(src))
+			src = append(src, v)
+			dups[dup{v, k}] = d
+		}
+		m.Indices[e] = d
+	}
+	if len(src) > int(n) {
+		m.remap(src)
+		m.IndicesChanged = true
+	}
+}
+
+// elements returns the number of elements (vertices, or indices if the mesh is
+// indexed) and a function that returns the vertex index of each element, or
+// false if it is out of range.
+//
+// The mesh's read lock must be held for this method to operate safely.
+func (m *Mesh) elements() (n int, vertex func(e int) (uint32, bool)) {
+	if len(m.Indices) > 0 {
+		return len(m.Indices), func(e int) (uint32, bool) {
+			v := m.Indices[e]
+			return v, int(v) < len(m.Vertices)
+		}
+	}
+	return len(m.Vertices), func(e int) (uint32, bool) {
+		return uint32(e), true
+	}
+}
+
+// vertexKeys returns a function that returns a key identifying the data of a
+// vertex in each non-empty per-vertex data slice of the mesh, such that two
+// vertices have equal keys only if all of their data is identical.
+//
+// The mesh's read lock must be held for this method (and the returned
+// function) to operate safely.
+func (m *Mesh) vertexKeys() func(v uint32) string {
+	var (
+		streams []reflect.Value
+		add     func(data interface{})
+	)
+	add = func(data interface{}) {
+		v := reflect.ValueOf(data)
+		if v.Kind() != reflect.Slice || v.Len() == 0 {
+			return
+		}
+		if v.Type().Elem().Kind() == reflect.Slice {
+			for i := 0; i < v.Len(); i++ {
+				add(v.Index(i).Interface())
+			}
+			return
+		}
+		streams = append(streams, v)
+	}
+	add(m.Vertices)
+	add(m.Normals)
+	add(m.Tangents)
+	add(m.Colors)
+	add(m.Bary)
+	for _, set := range m.TexCoords {
+		add(set.Slice)
+	}
+	for _, a := range m.Attribs {
+		if attribTypes[reflect.TypeOf(a.Data)] {
+			add(a.Data)
+		}
+	}
+
+	var buf bytes.Buffer
+	return func(v uint32) string {
+		buf.Reset()
+		for _, s := range streams {
+			if int(v) >= s.Len() {
+				buf.WriteByte(0)
+				continue
+			}
+			buf.WriteByte(1)
+			binary.Write(&buf, binary.LittleEndian, s.Index(int(v)).Interface())
+		}
+		return buf.String()
+	}
+}
+
+// Deindex converts this indexed mesh into a non-indexed one, by expanding each
+// per-vertex data slice (Vertices, Normals, Tangents, Colors, Bary, TexCoords,
+// and Attribs) such that each index refers to it's own vertex. For non-indexed
+// meshes this method does nothing.
+//
+// The mesh's write lock must be held for this method to operate safely.
+func (m *Mesh) Deindex() {
+	if len(m.Indices) == 0 {
+		return
+	}
+	m.remap(m.Indices)
+	m.Indices = nil
+	m.IndicesChanged = true
+}
+
+// Reindex converts this mesh into an indexed mesh of unique vertices: the
+// vertices whose data is identical in each per-vertex data slice (Vertices,
+// Normals, Tangents, Colors, Bary, TexCoords, and Attribs) are merged into a
+// single one, and vertices that are not referenced by any index are removed.
+// The primitives of the mesh are left unchanged, and are drawn in the same
+// order.
+//
+// As barycentric coordinates are compared like any other data, a mesh may be
+// re-indexed after generating them while keeping the vertices of each
+// triangle distinct:
+//  m.GenerateBary()
+//  m.Reindex()
+//
+// If any index is out of range of the vertices, this method does nothing.
+//
+// The mesh's write lock must be held for this method to operate safely.
+func (m *Mesh) Reindex() {
+	n, vertex := m.elements()
+	key := m.vertexKeys()
+	var (
+		indices = make([]uint32, n)
+		src     []uint32
+		unique  = make(map[string]uint32)
+	)
+	for e := range indices {
+		v, ok := vertex(e)
+		if !ok {
+			return
+		}
+		k := key(v)
+		u, ok := unique[k]
+		if !ok {
+			u = uint32(len(src))
+			src = append(src, v)
+			unique[k] = u
+		}
+		indices[e] = u
+	}
+	m.remap(src)
+	m.Indices = indices
+	m.IndicesChanged = true
+}
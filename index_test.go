@@ -0,0 +1,90 @@
+// Copyright 2014 The Azul3D Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package gfx
+
+import "testing"
+
+// quad returns an indexed quad of two triangles sharing two vertices, with
+// texture coordinates and a custom attribute.
+func quad() *Mesh {
+	m := NewMesh()
+	m.Vertices = []Vec3{{0, 0, 0}, {1, 0, 0}, {1, 1, 0}, {0, 1, 0}}
+	m.Indices = []uint32{0, 1, 2, 0, 2, 3}
+	m.TexCoords = []TexCoordSet{{Slice: []TexCoord{{0, 1}, {1, 1}, {1, 0}, {0, 0}}}}
+	m.Attribs = map[string]VertexAttrib{
+		"Weight": {Data: []float32{0, 1, 2, 3}},
+	}
+	return m
+}
+
+func TestDeindex(t *testing.T) {
+	m := quad()
+	want := quad()
+	m.Deindex()
+	if len(m.Indices) != 0 || !m.IndicesChanged {
+		t.Fatalf("got %d indices (changed %v), want 0 (changed true)", len(m.Indices), m.IndicesChanged)
+	}
+	weights := m.Attribs["Weight"].Data.([]float32)
+	for e, v := range want.Indices {
+		if m.Vertices[e] != want.Vertices[v] {
+			t.Errorf("Vertices[%d] = %v, want %v", e, m.Vertices[e], want.Vertices[v])
+		}
+		if m.TexCoords[0].Slice[e] != want.TexCoords[0].Slice[v] {
+			t.Errorf("TexCoords[0].Slice[%d] = %v, want %v", e, m.TexCoords[0].Slice[e], want.TexCoords[0].Slice[v])
+		}
+		if weights[e] != float32(v) {
+			t.Errorf("Attribs[\"Weight\"].Data[%d] = %v, want %v", e, weights[e], v)
+		}
+	}
+}
+
+func TestReindex(t *testing.T) {
+	m := quad()
+	m.Deindex()
+	m.Reindex()
+	if len(m.Vertices) != 4 || len(m.Indices) != 6 {
+		t.Fatalf("got %d vertices and %d indices, want 4 and 6", len(m.Vertices), len(m.Indices))
+	}
+	if len(m.TexCoords[0].Slice) != 4 || len(m.Attribs["Weight"].Data.([]float32)) != 4 {
+		t.Fatal("per-vertex data not re-indexed")
+	}
+
+	// Vertices are only merged if all of their data is identical.
+	m = quad()
+	m.Attribs["Weight"] = VertexAttrib{Data: []float32{0, 1, 2, 3, 4, 5}}
+	m.Vertices = append(m.Vertices, m.Vertices[0], m.Vertices[2])
+	m.TexCoords[0].Slice = append(m.TexCoords[0].Slice, m.TexCoords[0].Slice[0], m.TexCoords[0].Slice[2])
+	m.Indices = []uint32{0, 1, 2, 4, 5, 3}
+	m.Reindex()
+	if len(m.Vertices) != 6 {
+		t.Fatalf("got %d vertices, want 6", len(m.Vertices))
+	}
+	m.Attribs["Weight"] = VertexAttrib{Data: []float32{0, 0, 0, 0, 0, 0}}
+	m.Reindex()
+	if len(m.Vertices) != 4 {
+		t.Fatalf("got %d vertices, want 4", len(m.Vertices))
+	}
+}
+
+func TestGenerateBaryIndexed(t *testing.T) {
+	m := quad()
+	m.GenerateBary()
+	if len(m.Indices) != 0 || len(m.Bary) != 6 {
+		t.Fatalf("got %d indices and %d barycentric coordinates, want 0 and 6", len(m.Indices), len(m.Bary))
+	}
+
+	// Re-indexing keeps the vertices of each triangle distinct, though the
+	// shared ones need not all be duplicated.
+	m.Reindex()
+	for i := 0; i < len(m.Indices); i += 3 {
+		a, b, c := m.Bary[m.Indices[i]], m.Bary[m.Indices[i+1]], m.Bary[m.Indices[i+2]]
+		if sum := (Vec3{a.X + b.X + c.X, a.Y + b.Y + c.Y, a.Z + b.Z + c.Z}); sum != (Vec3{1, 1, 1}) {
+			t.Errorf("triangle %d: barycentric coordinates %v %v %v are not distinct", i/3, a, b, c)
+		}
+	}
+	if len(m.Vertices) != 5 {
+		t.Errorf("got %d vertices, want 5", len(m.Vertices))
+	}
+}
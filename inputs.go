@@ -0,0 +1,226 @@
+// Copyright 2014 The Azul3D Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package gfx
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"sync"
+)
+
+// inputTypes are the types that may be used as shader inputs, see the
+// documentation of Shader.Inputs.
+var inputTypes = map[reflect.Type]bool{
+	reflect.TypeOf(false):          true,
+	reflect.TypeOf(float32(0)):     true,
+	reflect.TypeOf([]float32(nil)): true,
+	reflect.TypeOf(int32(0)):       true,
+	reflect.TypeOf([]int32(nil)):   true,
+	reflect.TypeOf(Vec2{}):         true,
+	reflect.TypeOf([]Vec2(nil)):    true,
+	reflect.TypeOf(Vec3{}):         true,
+	reflect.TypeOf([]Vec3(nil)):    true,
+	reflect.TypeOf(Vec4{}):         true,
+	reflect.TypeOf([]Vec4(nil)):    true,
+	reflect.TypeOf(IVec2{}):        true,
+	reflect.TypeOf([]IVec2(nil)):   true,
+	reflect.TypeOf(IVec3{}):        true,
+	reflect.TypeOf([]IVec3(nil)):   true,
+	reflect.TypeOf(IVec4{}):        true,
+	reflect.TypeOf([]IVec4(nil)):   true,
+	reflect.TypeOf(Mat3{}):         true,
+	reflect.TypeOf([]Mat3(nil)):    true,
+	reflect.TypeOf(Mat4{}):         true,
+	reflect.TypeOf([]Mat4(nil)):    true,
+}
+
+// ValidInput tells if the value is of a type that may be used as a shader
+// input, see the documentation of Shader.Inputs.
+func ValidInput(v interface{}) bool {
+	return inputTypes[reflect.TypeOf(v)]
+}
+
+// InputTypes returns the types that may be used as shader inputs, sorted by
+// name.
+func InputTypes() []reflect.Type {
+	return sortedTypes(inputTypes)
+}
+
+// sortedTypes returns the types of the set, sorted by name.
+func sortedTypes(set map[reflect.Type]bool) []reflect.Type {
+	byName := make(map[string]reflect.Type, len(set))
+	names := make([]string, 0, len(set))
+	for t := range set {
+		byName[t.String()] = t
+		names = append(names, t.String())
+	}
+	sort.Strings(names)
+	types := make([]reflect.Type, len(names))
+	for i, name := range names {
+		types[i] = byName[name]
+	}
+	return types
+}
+
+// InputError describes a struct field that cannot be bound to a shader input.
+type InputError struct {
+	// The struct type, and the name of it's field.
+	Struct reflect.Type
+	Field  string
+
+	// The name of the shader input (from the field's tag).
+	Input string
+
+	// A description of the error.
+	Msg string
+}
+
+// Error implements the error interface.
+func (e *InputError) Error() string {
+	return fmt.Sprintf("gfx: %v.%s (input %q): %s", e.Struct, e.Field, e.Input, e.Msg)
+}
+
+// inputSlot is a single struct field bound to a shader input.
+type inputSlot struct {
+	name  string
+	index []int
+}
+
+// InputBinding binds the fields of a Go struct type to shader inputs. Each
+// field tagged with the name of a shader input is bound to it, for example:
+//
+//  type Material struct {
+//      Tint  gfx.Vec4   `gfx:"Tint"`
+//      Bones []gfx.Mat4 `gfx:"Bones"`
+//      cache int // Not bound.
+//  }
+//
+// The fields are reflected once (by NewInputBinding), and applying the values
+// of a struct to a map of inputs afterwards is cheap, such that it may be done
+// every frame:
+//
+//  b, err := gfx.NewInputBinding(Material{})
+//  ...
+//  o.Lock()
+//  b.Apply(o.InputOverrides, &material)
+//  o.Unlock()
+type InputBinding struct {
+	typ   reflect.Type
+	slots []inputSlot
+}
+
+// Type returns the struct type that is bound.
+func (b *InputBinding) Type() reflect.Type {
+	return b.typ
+}
+
+// Inputs returns the names of the bound shader inputs, in the order of the
+// fields of the struct.
+func (b *InputBinding) Inputs() []string {
+	names := make([]string, len(b.slots))
+	for i, s := range b.slots {
+		names[i] = s.name
+	}
+	return names
+}
+
+// Apply stores the value of each bound field of v into the given map of shader
+// inputs (e.g. Shader.Inputs or Object.InputOverrides). The value v must be a
+// struct of the bound type or a pointer to one, or else a panic will occur.
+//
+// Slice fields are stored as-is, that is their underlying arrays are shared
+// with the map.
+func (b *InputBinding) Apply(inputs map[string]interface{}, v interface{}) {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() == reflect.Ptr {
+		rv = rv.Elem()
+	}
+	if rv.Type() != b.typ {
+		panic(fmt.Sprintf("InputBinding.Apply(): got %v, want %v", rv.Type(), b.typ))
+	}
+	for _, s := range b.slots {
+		inputs[s.name] = rv.FieldByIndex(s.index).Interface()
+	}
+}
+
+var bindings struct {
+	sync.Mutex
+	types map[reflect.Type]*InputBinding
+}
+
+// NewInputBinding reflects the struct type of v (a struct or a pointer to
+// one) and returns a binding of it's tagged fields to shader inputs. Fields
+// are tagged with the name of the shader input using the "gfx" key, fields
+// without a tag (or with the tag "-") are not bound.
+//
+// An *InputError is returned if a tagged field is unexported, is of a type
+// not supported as a shader input (see Shader.Inputs), or if two fields are
+// tagged with the same name.
+//
+// Bindings are cached by type, such that calling this function multiple times
+// for the same type is cheap.
+func NewInputBinding(v interface{}) (*InputBinding, error) {
+	t := reflect.TypeOf(v)
+	if t != nil && t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t == nil || t.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("gfx: cannot bind shader inputs to %T (not a struct)", v)
+	}
+
+	bindings.Lock()
+	defer bindings.Unlock()
+	if b, ok := bindings.types[t]; ok {
+		return b, nil
+	}
+
+	b := &InputBinding{typ: t}
+	seen := make(map[string]string)
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		name := f.Tag.Get("gfx")
+		if name == "" || name == "-" {
+			continue
+		}
+		fail := func(format string, args ...interface{}) error {
+			return &InputError{Struct: t, Field: f.Name, Input: name, Msg: fmt.Sprintf(format, args...)}
+		}
+		if f.PkgPath != "" {
+			return nil, fail("field is unexported")
+		}
+		if !inputTypes[f.Type] {
+			return nil, fail("unsupported shader input type %v", f.Type)
+		}
+		if other, ok := seen[name]; ok {
+			return nil, fail("input is also bound to field %s", other)
+		}
+		seen[name] = f.Name
+		b.slots = append(b.slots, inputSlot{name: name, index: f.Index})
+	}
+
+	if bindings.types == nil {
+		bindings.types = make(map[reflect.Type]*InputBinding)
+	}
+	bindings.types[t] = b
+	return b, nil
+}
+
+// SetInputs stores the value of each tagged field of the struct v (or pointer
+// to one) into the given map of shader inputs. It is short-hand for:
+//
+//  b, err := NewInputBinding(v)
+//  if err != nil {
+//      return err
+//  }
+//  b.Apply(inputs, v)
+func SetInputs(inputs map[string]interface{}, v interface{}) error {
+	b, err := NewInputBinding(v)
+	if err != nil {
+		return err
+	}
+	b.Apply(inputs, v)
+	return nil
+}
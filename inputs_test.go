@@ -0,0 +1,80 @@
+// Copyright 2014 The Azul3D Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package gfx
+
+import "testing"
+
+type testMaterial struct {
+	Tint    Vec4    `gfx:"Tint"`
+	Bones   []Mat4  `gfx:"Bones"`
+	Shiny   bool    `gfx:"Shiny"`
+	Ignored float64 `gfx:"-"`
+	cache   int
+}
+
+func TestInputBinding(t *testing.T) {
+	b, err := NewInputBinding(&testMaterial{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b2, _ := NewInputBinding(testMaterial{}); b2 != b {
+		t.Fatal("binding not cached")
+	}
+	if names := b.Inputs(); len(names) != 3 || names[0] != "Tint" || names[2] != "Shiny" {
+		t.Fatal("got inputs", names)
+	}
+
+	s := NewShader("material")
+	m := testMaterial{Tint: Vec4{1, 0, 0, 1}, Shiny: true}
+	b.Apply(s.Inputs, &m)
+	if s.Inputs["Tint"] != (Vec4{1, 0, 0, 1}) || s.Inputs["Shiny"] != true {
+		t.Fatal("got inputs", s.Inputs)
+	}
+	if _, ok := s.Inputs["Ignored"]; ok {
+		t.Fatal("ignored field was bound")
+	}
+
+	// Re-applying stores the new values.
+	m.Tint.X = 0
+	b.Apply(s.Inputs, m)
+	if s.Inputs["Tint"] != (Vec4{0, 0, 0, 1}) {
+		t.Fatal("got tint", s.Inputs["Tint"])
+	}
+}
+
+func TestInputBindingErrors(t *testing.T) {
+	var bad struct {
+		Count int `gfx:"Count"`
+	}
+	_, err := NewInputBinding(bad)
+	if ierr, ok := err.(*InputError); !ok || ierr.Field != "Count" || ierr.Input != "Count" {
+		t.Fatal("got error", err)
+	}
+
+	var dup struct {
+		A float32 `gfx:"X"`
+		B float32 `gfx:"X"`
+	}
+	if err := SetInputs(map[string]interface{}{}, &dup); err == nil {
+		t.Fatal("expected error for duplicate inputs")
+	}
+
+	if _, err := NewInputBinding(3); err == nil {
+		t.Fatal("expected error for non-struct")
+	}
+}
+
+func TestValidInput(t *testing.T) {
+	if !ValidInput(Vec3{}) || !ValidInput([]Mat4(nil)) || ValidInput(float64(0)) {
+		t.Fatal("wrong shader input types")
+	}
+	if !ValidAttrib([]Vec3(nil)) || !ValidAttrib([][]float32(nil)) || ValidAttrib(Vec3{}) || ValidAttrib([]bool(nil)) {
+		t.Fatal("wrong vertex attribute types")
+	}
+	types := InputTypes()
+	if len(types) != len(inputTypes) || types[0].String() > types[1].String() {
+		t.Fatal("got input types", types)
+	}
+}
@@ -0,0 +1,144 @@
+// Copyright 2014 The Azul3D Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package gfx
+
+import (
+	"math"
+
+	"azul3d.org/lmath.v1"
+)
+
+// LOD is a single level of detail of an object: a set of meshes that is drawn
+// in place of the object's own meshes once the object is far enough from the
+// camera, or covers little enough of the screen. See the LODs field of Object.
+type LOD struct {
+	// The meshes of this level of detail, drawn in place of the object's
+	// Meshes.
+	Meshes []*Mesh
+
+	// The distance from the camera (see the LODMetrics method of Object) at
+	// or beyond which this level of detail is used, or zero to not switch to
+	// it by distance.
+	Distance float64
+
+	// The screen coverage (see the LODMetrics method of Object) at or below
+	// which this level of detail is used, or zero to not switch to it by
+	// coverage.
+	Coverage float64
+}
+
+// LODMetrics returns the metrics used to select the level of detail of this
+// object (see the LODs field) as seen through the given camera: the distance
+// in world units from the camera to the center of the object's bounding box
+// (see the Bounds method), and the screen coverage of the object's bounding
+// sphere, that is the fraction of the height of the camera's viewport that
+// it's diameter spans once projected (see Camera.Projection).
+//
+// The coverage of an object containing a perspective camera is infinite. A nil
+// camera sees every object with zero distance and infinite coverage.
+//
+// This method properly write-locks the object, the camera's read lock must be
+// held for this method to operate safely.
+func (o *Object) LODMetrics(c *Camera) (distance, coverage float64) {
+	if c == nil {
+		return 0, math.Inf(1)
+	}
+	b := o.Bounds()
+	center := b.Min.Add(b.Max).MulScalar(0.5)
+	radius := b.Max.Sub(b.Min).Length() / 2
+	eye := c.Transform.ConvertPos(lmath.Vec3Zero, LocalToWorld)
+	distance = center.Sub(eye).Length()
+
+	// The W clip coordinate is the depth of the point for perspective
+	// projections, and one for orthographic ones.
+	p := c.Projection.Mat4()
+	w := distance*-p[2][3] + p[3][3]
+	if w <= 0 {
+		return distance, math.Inf(1)
+	}
+	return distance, radius * math.Abs(p[1][1]) / w
+}
+
+// LODLevel returns the level of detail of this object to draw through the
+// given camera, as an index into o.LODs, or -1 for the object's own Meshes.
+// The active parameter is the level of detail currently drawn (-1 if none).
+//
+// The level of detail is the last one in o.LODs whose Distance or Coverage
+// is reached (see the LODMetrics method), such that levels are expected to be
+// listed from the most detailed to the least. To avoid popping back and forth
+// between levels when the object is near a threshold, switching to a level
+// beyond the active one requires the threshold to be passed by the fraction
+// o.LODHysteresis, and switching back to a level before it requires the same
+// fraction in the other direction. For instance with a hysteresis of 0.1 and a
+// level whose Distance is 100, the level is switched to at a distance of 110,
+// and away from at a distance of 90.
+//
+// The result only depends on the object, it's bounding box, and the camera,
+// such that it is deterministic.
+//
+// This method properly write-locks the object, the camera's read lock must be
+// held for this method to operate safely.
+func (o *Object) LODLevel(c *Camera, active int) int {
+	distance, coverage := o.LODMetrics(c)
+	o.RLock()
+	defer o.RUnlock()
+	level := -1
+	for i, lod := range o.LODs {
+		bias := 1 + o.LODHysteresis
+		if i <= active {
+			bias = 1 - o.LODHysteresis
+		}
+		if lod.Distance > 0 && distance >= lod.Distance*bias {
+			level = i
+		} else if lod.Coverage > 0 && coverage <= lod.Coverage/bias {
+			level = i
+		}
+	}
+	return level
+}
+
+// SelectLOD selects the level of detail of this object to draw through the
+// given camera (see the LODLevel method), given the level that was last
+// selected for the camera, and returns it's meshes (or the object's own
+// Meshes if none is). The selected level is stored in o.ActiveLODs.
+//
+// Renderers draw the meshes returned by this method, thus objects without any
+// levels of detail are drawn as-is:
+//  meshes := o.SelectLOD(camera)
+//
+// This method properly write-locks the object, the camera's read lock must be
+// held for this method to operate safely.
+func (o *Object) SelectLOD(c *Camera) []*Mesh {
+	o.RLock()
+	active, ok := o.ActiveLODs[c]
+	lods := len(o.LODs)
+	o.RUnlock()
+	if !ok {
+		active = -1
+	}
+	level := -1
+	if lods > 0 {
+		level = o.LODLevel(c, active)
+	}
+
+	o.Lock()
+	defer o.Unlock()
+	if level >= len(o.LODs) {
+		// The levels changed in the meantime.
+		level = len(o.LODs) - 1
+	}
+	if len(o.LODs) == 0 {
+		delete(o.ActiveLODs, c)
+		return o.Meshes
+	}
+	if o.ActiveLODs == nil {
+		o.ActiveLODs = make(map[*Camera]int)
+	}
+	o.ActiveLODs[c] = level
+	if level < 0 {
+		return o.Meshes
+	}
+	return o.LODs[level].Meshes
+}
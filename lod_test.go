@@ -0,0 +1,110 @@
+// Copyright 2014 The Azul3D Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package gfx
+
+import (
+	"image"
+	"math"
+	"testing"
+
+	"azul3d.org/lmath.v1"
+)
+
+// lodObject returns an object whose meshes span the box from -1 to 1 (with a
+// bounding sphere of radius sqrt(3)), with a level of detail by distance and
+// one by coverage.
+func lodObject() (o *Object, medium, low []*Mesh) {
+	box := func() *Mesh {
+		m := NewMesh()
+		m.Vertices = []Vec3{{-1, -1, -1}, {1, 1, 1}, {1, -1, 1}}
+		return m
+	}
+	medium, low = []*Mesh{box()}, []*Mesh{box()}
+	o = NewObject()
+	o.Meshes = []*Mesh{box()}
+	o.LODs = []LOD{
+		{Meshes: medium, Distance: 100},
+		{Meshes: low, Coverage: 0.01},
+	}
+	return
+}
+
+func TestLODMetrics(t *testing.T) {
+	o, _, _ := lodObject()
+	c := NewCamera()
+	c.SetPersp(image.Rect(0, 0, 100, 100), 90, 0.1, 1000)
+
+	o.Transform.SetPos(lmath.Vec3{0, 50, 0})
+	distance, coverage := o.LODMetrics(c)
+	if math.Abs(distance-50) > 1e-9 {
+		t.Errorf("got distance %v, want 50", distance)
+	}
+	if want := math.Sqrt(3) / 50; math.Abs(coverage-want) > 1e-9 {
+		t.Errorf("got coverage %v, want %v", coverage, want)
+	}
+
+	if _, coverage := o.LODMetrics(nil); !math.IsInf(coverage, 1) {
+		t.Errorf("got coverage %v for nil camera, want +Inf", coverage)
+	}
+}
+
+func TestSelectLOD(t *testing.T) {
+	o, medium, low := lodObject()
+	o.LODHysteresis = 0.1
+	c := NewCamera()
+	c.SetPersp(image.Rect(0, 0, 100, 100), 90, 0.1, 1000)
+	other := NewCamera()
+	other.SetPersp(image.Rect(0, 0, 100, 100), 90, 0.1, 1000)
+
+	// The coverage threshold of 0.01 is reached at a distance of about 173.
+	tests := []struct {
+		distance float64
+		want     []*Mesh
+	}{
+		{50, o.Meshes},
+		{105, o.Meshes}, // Within the hysteresis of the distance.
+		{111, medium},
+		{95, medium}, // Within the hysteresis, in the other direction.
+		{89, o.Meshes},
+		{180, medium}, // Within the hysteresis of the coverage.
+		{200, low},
+		{170, low},
+		{10, o.Meshes},
+	}
+	for _, tst := range tests {
+		o.Transform.SetPos(lmath.Vec3{0, tst.distance, 0})
+		if got := o.SelectLOD(c); got[0] != tst.want[0] {
+			t.Fatalf("distance %v: got level %d", tst.distance, o.ActiveLODs[c])
+		}
+	}
+
+	// Levels are selected independently for each camera.
+	o.Transform.SetPos(lmath.Vec3{0, 105, 0})
+	o.SelectLOD(other)
+	if o.ActiveLODs[other] != -1 || o.ActiveLODs[c] != -1 {
+		t.Fatalf("got levels %d and %d, want -1 and -1", o.ActiveLODs[other], o.ActiveLODs[c])
+	}
+	o.Transform.SetPos(lmath.Vec3{0, 500, 0})
+	o.SelectLOD(other)
+	if o.ActiveLODs[other] != 1 || o.ActiveLODs[c] != -1 {
+		t.Fatalf("got levels %d and %d, want 1 and -1", o.ActiveLODs[other], o.ActiveLODs[c])
+	}
+
+	// Without hysteresis the level only depends on the metrics.
+	o.LODHysteresis = 0
+	o.Transform.SetPos(lmath.Vec3{0, 100, 0})
+	if level := o.LODLevel(c, 1); level != 0 {
+		t.Fatalf("got level %d, want 0", level)
+	}
+
+	cpy := o.Copy()
+	if len(cpy.LODs) != 2 || len(cpy.ActiveLODs) != 0 {
+		t.Fatal("levels of detail not copied")
+	}
+	o.Reset()
+	if len(o.LODs) != 0 || len(o.ActiveLODs) != 0 {
+		t.Fatal("levels of detail not reset")
+	}
+}
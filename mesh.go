@@ -5,6 +5,7 @@
 package gfx
 
 import (
+	"reflect"
 	"sync"
 
 	"azul3d.org/lmath.v1"
@@ -44,12 +45,24 @@ type VertexAttrib struct {
 	// else the attribute may be ignored completely:
 	//  []float32
 	//  [][]float32
+	//  []int32
+	//  [][]int32
+	//  []gfx.Vec2
+	//  [][]gfx.Vec2
 	//  []gfx.Vec3
 	//  [][]gfx.Vec3
-	//  []gfx.Mat4
-	//  [][]gfx.Mat4
 	//  []gfx.Vec4
 	//  [][]gfx.Vec4
+	//  []gfx.IVec2
+	//  [][]gfx.IVec2
+	//  []gfx.IVec3
+	//  [][]gfx.IVec3
+	//  []gfx.IVec4
+	//  [][]gfx.IVec4
+	//  []gfx.Mat3
+	//  [][]gfx.Mat3
+	//  []gfx.Mat4
+	//  [][]gfx.Mat4
 	Data interface{}
 
 	// Weather or not the per-vertex data (see the Data field) has changed
@@ -58,64 +71,66 @@ type VertexAttrib struct {
 	Changed bool
 }
 
+// attribTypes are the types that may be used as vertex attribute data, see the
+// documentation of VertexAttrib.Data.
+var attribTypes = map[reflect.Type]bool{
+	reflect.TypeOf([]float32(nil)):   true,
+	reflect.TypeOf([][]float32(nil)): true,
+	reflect.TypeOf([]int32(nil)):     true,
+	reflect.TypeOf([][]int32(nil)):   true,
+	reflect.TypeOf([]Vec2(nil)):      true,
+	reflect.TypeOf([][]Vec2(nil)):    true,
+	reflect.TypeOf([]Vec3(nil)):      true,
+	reflect.TypeOf([][]Vec3(nil)):    true,
+	reflect.TypeOf([]Vec4(nil)):      true,
+	reflect.TypeOf([][]Vec4(nil)):    true,
+	reflect.TypeOf([]IVec2(nil)):     true,
+	reflect.TypeOf([][]IVec2(nil)):   true,
+	reflect.TypeOf([]IVec3(nil)):     true,
+	reflect.TypeOf([][]IVec3(nil)):   true,
+	reflect.TypeOf([]IVec4(nil)):     true,
+	reflect.TypeOf([][]IVec4(nil)):   true,
+	reflect.TypeOf([]Mat3(nil)):      true,
+	reflect.TypeOf([][]Mat3(nil)):    true,
+	reflect.TypeOf([]Mat4(nil)):      true,
+	reflect.TypeOf([][]Mat4(nil)):    true,
+}
+
+// ValidAttrib tells if the value is of a type that may be used as vertex
+// attribute data, see the documentation of VertexAttrib.Data.
+func ValidAttrib(v interface{}) bool {
+	return attribTypes[reflect.TypeOf(v)]
+}
+
+// AttribTypes returns the types that may be used as vertex attribute data,
+// sorted by name.
+func AttribTypes() []reflect.Type {
+	return sortedTypes(attribTypes)
+}
+
 // Copy returns a new copy of this vertex attribute data set. It makes a deep
 // copy of the underlying Data slice. Explicitly not copied is the Changed
 // boolean.
+//
+// If the data is not of one of the supported types, an empty vertex attribute
+// is returned.
 func (a VertexAttrib) Copy() VertexAttrib {
-	var cpy interface{}
-	switch t := a.Data.(type) {
-	case []float32:
-		c := make([]float32, len(t))
-		copy(c, t)
-		cpy = c
-
-	case []Vec3:
-		c := make([]Vec3, len(t))
-		copy(c, t)
-		cpy = c
-
-	case []Vec4:
-		c := make([]Vec4, len(t))
-		copy(c, t)
-		cpy = c
-
-	case []Mat4:
-		c := make([]Mat4, len(t))
-		copy(c, t)
-		cpy = c
-
-	case [][]float32:
-		c := make([][]float32, len(t))
-		for i, s := range t {
-			c[i] = make([]float32, len(s))
-			copy(c[i], t[i])
-		}
-
-	case [][]Vec3:
-		c := make([][]Vec3, len(t))
-		for i, s := range t {
-			c[i] = make([]Vec3, len(s))
-			copy(c[i], t[i])
-		}
-
-	case [][]Vec4:
-		c := make([][]Vec4, len(t))
-		for i, s := range t {
-			c[i] = make([]Vec4, len(s))
-			copy(c[i], t[i])
-		}
-
-	case [][]Mat4:
-		c := make([][]Mat4, len(t))
-		for i, s := range t {
-			c[i] = make([]Mat4, len(s))
-			copy(c[i], t[i])
-		}
-
-	default:
+	if !attribTypes[reflect.TypeOf(a.Data)] {
 		return VertexAttrib{}
 	}
-	return VertexAttrib{Data: cpy}
+	v := reflect.ValueOf(a.Data)
+	cpy := reflect.MakeSlice(v.Type(), v.Len(), v.Len())
+	if v.Type().Elem().Kind() != reflect.Slice {
+		reflect.Copy(cpy, v)
+		return VertexAttrib{Data: cpy.Interface()}
+	}
+	for i := 0; i < v.Len(); i++ {
+		s := v.Index(i)
+		c := reflect.MakeSlice(s.Type(), s.Len(), s.Len())
+		reflect.Copy(c, s)
+		cpy.Index(i).Set(c)
+	}
+	return VertexAttrib{Data: cpy.Interface()}
 }
 
 // NativeMesh represents the native object of a mesh, typically only renderers
@@ -157,6 +172,10 @@ type Mesh struct {
 	// CalculateBounds() method.
 	AABB lmath.Rect3
 
+	// The kind of primitive that the vertices (or indices, for an indexed
+	// mesh) form, Triangles by default.
+	Primitive Primitive
+
 	// A slice of indices, if non-nil then this slice contains indices into
 	// each other slice (such as Vertices) and this is a indexed mesh.
 	// The indices are uint32 (instead of int) for compatability with graphics
@@ -168,7 +187,8 @@ type Mesh struct {
 	// re-upload the data slice to the graphics hardware.
 	IndicesChanged bool
 
-	// The slice of vertices for the mesh.
+	// The slice of vertices for the mesh, fed to the Vertex attribute of
+	// shaders (see AttribVertex).
 	Vertices []Vec3
 
 	// Weather or not the vertices have changed since the last time the
@@ -176,7 +196,33 @@ type Mesh struct {
 	// re-upload the data slice to the graphics hardware.
 	VerticesChanged bool
 
-	// The slice of vertex colors for the mesh.
+	// The slice of vertex normals for the mesh, fed to the Normal attribute of
+	// shaders (see AttribNormal). Normals are unit vectors in the same space
+	// as the vertices, pointing away from the front face.
+	Normals []Vec3
+
+	// Weather or not the vertex normals have changed since the last time the
+	// mesh was loaded. If set to true the renderer should take note and
+	// re-upload the data slice to the graphics hardware.
+	NormalsChanged bool
+
+	// The slice of vertex tangents for the mesh, fed to the Tangent attribute
+	// of shaders (see AttribTangent). The XYZ components are a unit vector
+	// perpendicular to the normal, pointing along the direction of increasing
+	// U texture coordinates. The W component is the sign (1 or -1) of the
+	// bitangent, which is computed in a shader as:
+	//  bitangent = Tangent.w * cross(Normal, Tangent.xyz);
+	//
+	// See the GenerateTangents method.
+	Tangents []Vec4
+
+	// Weather or not the vertex tangents have changed since the last time the
+	// mesh was loaded. If set to true the renderer should take note and
+	// re-upload the data slice to the graphics hardware.
+	TangentsChanged bool
+
+	// The slice of vertex colors for the mesh, fed to the Color attribute of
+	// shaders (see AttribColor).
 	Colors []Color
 
 	// Weather or not the vertex colors have changed since the last time
@@ -184,7 +230,8 @@ type Mesh struct {
 	// and re-upload the data slice to the graphics hardware.
 	ColorsChanged bool
 
-	// A slice of barycentric coordinates for the mesh.
+	// A slice of barycentric coordinates for the mesh, fed to the Bary
+	// attribute of shaders (see AttribBary).
 	Bary []Vec3
 
 	// Whether or not the barycentric coordinates have changed since the last
@@ -194,7 +241,8 @@ type Mesh struct {
 
 	// A slice of texture coordinate sets for the mesh, there may be
 	// multiple sets which directly relate to multiple textures on a
-	// object.
+	// object. Each set is fed to the shader attribute named after it's index
+	// in the slice (see TexCoordAttrib), e.g. TexCoords[0] to TexCoord0.
 	TexCoords []TexCoordSet
 
 	// A map of custom per-vertex attributes for the mesh. It is analogous to
@@ -243,10 +291,15 @@ func (m *Mesh) Copy() *Mesh {
 		m.KeepDataOnLoad,
 		m.Dynamic,
 		m.AABB,
+		m.Primitive,
 		make([]uint32, len(m.Indices)),
 		false, // IndicesChanged -- not copied.
 		make([]Vec3, len(m.Vertices)),
 		false, // VerticesChanged -- not copied.
+		make([]Vec3, len(m.Normals)),
+		false, // NormalsChanged -- not copied.
+		make([]Vec4, len(m.Tangents)),
+		false, // TangentsChanged -- not copied.
 		make([]Color, len(m.Colors)),
 		false, // ColorsChanged -- not copied.
 		make([]Vec3, len(m.Bary)),
@@ -257,6 +310,8 @@ func (m *Mesh) Copy() *Mesh {
 
 	copy(cpy.Indices, m.Indices)
 	copy(cpy.Vertices, m.Vertices)
+	copy(cpy.Normals, m.Normals)
+	copy(cpy.Tangents, m.Tangents)
 	copy(cpy.Colors, m.Colors)
 	copy(cpy.Bary, m.Bary)
 	for index, set := range m.TexCoords {
@@ -285,39 +340,65 @@ func (m *Mesh) Bounds() lmath.Rect3 {
 	return bounds
 }
 
-// GenerateBary generates the barycentric coordinates for this mesh.
+// GenerateBary generates the barycentric coordinates for this mesh, replacing
+// any existing ones, such that the three vertices of each triangle have
+// distinct coordinates. Only meshes whose Primitive forms triangles have
+// barycentric coordinates, for others this method does nothing.
+//
+// As the vertices of an indexed mesh may be shared by triangles that need
+// different coordinates for them, indexed meshes are first de-indexed (see the
+// Deindex method). They may be re-indexed afterwards, keeping the vertices
+// whose coordinates differ distinct (see the Reindex method).
 //
 // The mesh's write lock must be held for this method to operate safely.
 func (m *Mesh) GenerateBary() {
-	var (
-		bci = -1
-		v   Vec3
-	)
-	for _ = range m.Vertices {
-		// Add barycentric coordinates.
-		bci++
-		switch bci % 3 {
+	if m.Primitive.Vertices() != 3 {
+		return
+	}
+	m.Deindex()
+	m.Bary = make([]Vec3, len(m.Vertices))
+	for bci := range m.Bary {
+		// The first vertex of a fan is shared by every triangle, so the others
+		// alternate between the remaining two coordinates.
+		i := bci % 3
+		if m.Primitive == TriangleFan && bci > 0 {
+			i = 1 + (bci-1)%2
+		}
+		switch i {
 		case 0:
-			v = Vec3{1, 0, 0}
+			m.Bary[bci] = Vec3{1, 0, 0}
 		case 1:
-			v = Vec3{0, 1, 0}
+			m.Bary[bci] = Vec3{0, 1, 0}
 		case 2:
-			v = Vec3{0, 0, 1}
+			m.Bary[bci] = Vec3{0, 0, 1}
 		}
-		m.Bary = append(m.Bary, v)
 	}
+	m.BaryChanged = true
 }
 
 // CalculateBounds calculates a new axis aligned bounding box for this mesh.
+// Only the vertices that form whole primitives (see the Primitive field) are
+// taken into account, that is for an indexed mesh the vertices referenced by
+// it's indices, and otherwise the vertices excluding trailing ones that do not
+// form a whole primitive.
 //
 // The mesh's write lock must be held for this method to operate safely.
 func (m *Mesh) CalculateBounds() {
 	var bb lmath.Rect3
-	if len(m.Vertices) > 0 {
-		for _, v32 := range m.Vertices {
-			v := v32.Vec3()
-			bb.Min = bb.Min.Min(v)
-			bb.Max = bb.Max.Max(v)
+	add := func(v32 Vec3) {
+		v := v32.Vec3()
+		bb.Min = bb.Min.Min(v)
+		bb.Max = bb.Max.Max(v)
+	}
+	if len(m.Indices) > 0 {
+		for _, index := range m.Indices[:m.Primitive.used(len(m.Indices))] {
+			if int(index) < len(m.Vertices) {
+				add(m.Vertices[index])
+			}
+		}
+	} else {
+		for _, v := range m.Vertices[:m.Primitive.used(len(m.Vertices))] {
+			add(v)
 		}
 	}
 	m.AABB = bb
@@ -328,7 +409,7 @@ func (m *Mesh) CalculateBounds() {
 //
 // The mesh's read lock must be held for this method to operate safely.
 func (m *Mesh) HasChanged() bool {
-	if m.IndicesChanged || m.VerticesChanged || m.ColorsChanged || m.BaryChanged {
+	if m.IndicesChanged || m.VerticesChanged || m.NormalsChanged || m.TangentsChanged || m.ColorsChanged || m.BaryChanged {
 		return true
 	}
 	for _, texCoordSet := range m.TexCoords {
@@ -352,6 +433,8 @@ func (m *Mesh) ClearData() {
 	if !m.KeepDataOnLoad {
 		m.Indices = nil
 		m.Vertices = nil
+		m.Normals = nil
+		m.Tangents = nil
 		m.Colors = nil
 		m.Bary = nil
 		m.TexCoords = nil
@@ -368,10 +451,15 @@ func (m *Mesh) Reset() {
 	m.KeepDataOnLoad = false
 	m.Dynamic = false
 	m.AABB = lmath.Rect3Zero
+	m.Primitive = Triangles
 	m.Indices = m.Indices[:0]
 	m.IndicesChanged = false
 	m.Vertices = m.Vertices[:0]
 	m.VerticesChanged = false
+	m.Normals = m.Normals[:0]
+	m.NormalsChanged = false
+	m.Tangents = m.Tangents[:0]
+	m.TangentsChanged = false
 	m.Colors = m.Colors[:0]
 	m.ColorsChanged = false
 	m.Bary = m.Bary[:0]
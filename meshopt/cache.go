@@ -0,0 +1,193 @@
+// Copyright 2014 The Azul3D Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package meshopt
+
+import (
+	"math"
+
+	"azul3d.org/gfx.v1"
+)
+
+// Parameters of the vertex scores of the VertexCache function, as tuned by Tom
+// Forsyth in "Linear-Speed Vertex Cache Optimisation".
+const (
+	forsythCacheSize     = 32
+	forsythDecayPower    = 1.5
+	forsythLastTriScore  = 0.75
+	forsythValenceScale  = 2.0
+	forsythValencePower  = 0.5
+	forsythMaxValenceLUT = 64
+)
+
+// forsythValence is the valence boost of vertices with few remaining
+// triangles, indexed by their remaining triangle count.
+var forsythValence [forsythMaxValenceLUT]float64
+
+func init() {
+	for i := 1; i < len(forsythValence); i++ {
+		forsythValence[i] = forsythValenceScale * math.Pow(float64(i), -forsythValencePower)
+	}
+}
+
+// vertexScore returns the score of a vertex given it's position in the LRU
+// cache (-1 if not in it) and the number of triangles remaining to be drawn
+// that use it.
+func vertexScore(pos, remaining int) float64 {
+	if remaining == 0 {
+		// No triangle needs this vertex anymore.
+		return -1
+	}
+	var score float64
+	switch {
+	case pos < 0:
+	case pos < 3:
+		// The vertex was used by the last triangle, whichever of it's three
+		// vertices it is.
+		score = forsythLastTriScore
+	default:
+		scaler := 1 / float64(forsythCacheSize-3)
+		score = math.Pow(1-float64(pos-3)*scaler, forsythDecayPower)
+	}
+	if remaining < forsythMaxValenceLUT {
+		return score + forsythValence[remaining]
+	}
+	return score + forsythValenceScale*math.Pow(float64(remaining), -forsythValencePower)
+}
+
+// triangles returns the number of whole triangles of the indexed mesh, or zero
+// if it's not an indexed mesh of gfx.Triangles or any of it's indices is out of
+// range.
+func triangles(m *gfx.Mesh) int {
+	if m.Primitive != gfx.Triangles || len(m.Indices) == 0 {
+		return 0
+	}
+	for _, index := range m.Indices {
+		if int(index) >= len(m.Vertices) {
+			return 0
+		}
+	}
+	return m.Primitive.Count(len(m.Indices))
+}
+
+// VertexCache reorders the triangles of the indexed mesh such that the GPU
+// reuses the vertices stored in it's post-transform vertex cache as much as
+// possible, using Tom Forsyth's linear-speed algorithm. As the algorithm
+// models the cache as a LRU cache of 32 entries, the order is efficient for
+// most cache sizes and kinds (see the Analyze function).
+//
+// Only indexed meshes whose Primitive is gfx.Triangles are reordered, for
+// others this function does nothing. The vertices of each triangle keep their
+// order, and thus the triangle's winding.
+//
+// The mesh's write lock must be held for this function to operate safely.
+func VertexCache(m *gfx.Mesh) {
+	tris := triangles(m)
+	if tris == 0 {
+		return
+	}
+	indices := m.Indices
+
+	// The triangles that use each vertex, and are yet to be drawn.
+	adjacency := make([][]int, len(m.Vertices))
+	for t := 0; t < tris; t++ {
+		for _, v := range indices[t*3 : t*3+3] {
+			adjacency[v] = append(adjacency[v], t)
+		}
+	}
+
+	cachePos := make([]int, len(m.Vertices))
+	vScores := make([]float64, len(m.Vertices))
+	for v := range cachePos {
+		cachePos[v] = -1
+		vScores[v] = vertexScore(-1, len(adjacency[v]))
+	}
+	tScores := make([]float64, tris)
+	drawn := make([]bool, tris)
+	for t := range tScores {
+		for _, v := range indices[t*3 : t*3+3] {
+			tScores[t] += vScores[v]
+		}
+	}
+
+	var (
+		out      = make([]uint32, 0, len(indices))
+		cache    = make([]uint32, 0, forsythCacheSize+3)
+		newCache = make([]uint32, 0, forsythCacheSize+3)
+		next     = 0 // Next triangle to consider when no cached one is left.
+		best     = -1
+	)
+	for len(out) < tris*3 {
+		if best < 0 {
+			// No triangle uses a cached vertex, pick the best remaining one.
+			bestScore := -1.0
+			for ; next < tris && drawn[next]; next++ {
+			}
+			for t := next; t < tris; t++ {
+				if !drawn[t] && tScores[t] > bestScore {
+					best, bestScore = t, tScores[t]
+				}
+				if bestScore >= 0 && t-next > forsythCacheSize {
+					// Scanning every triangle would be quadratic, the first
+					// ones are good enough.
+					break
+				}
+			}
+		}
+
+		// Draw the triangle, and remove it from it's vertices' adjacency.
+		tri := indices[best*3 : best*3+3]
+		out = append(out, tri...)
+		drawn[best] = true
+		for _, v := range tri {
+			adj := adjacency[v]
+			for i, t := range adj {
+				if t == best {
+					adjacency[v] = append(adj[:i], adj[i+1:]...)
+					break
+				}
+			}
+		}
+
+		// Move the vertices of the triangle to the front of the cache.
+		newCache = append(newCache[:0], tri...)
+		for _, v := range cache {
+			if v != tri[0] && v != tri[1] && v != tri[2] {
+				newCache = append(newCache, v)
+			}
+		}
+		cache, newCache = newCache, cache
+
+		// Update the scores of the cached (and evicted) vertices, and of the
+		// triangles that use them.
+		for i, v := range cache {
+			if i < forsythCacheSize {
+				cachePos[v] = i
+			} else {
+				cachePos[v] = -1
+			}
+			old := vScores[v]
+			vScores[v] = vertexScore(cachePos[v], len(adjacency[v]))
+			for _, t := range adjacency[v] {
+				tScores[t] += vScores[v] - old
+			}
+		}
+		if len(cache) > forsythCacheSize {
+			cache = cache[:forsythCacheSize]
+		}
+
+		// The next triangle is the best one using a cached vertex.
+		best = -1
+		bestScore := -1.0
+		for _, v := range cache {
+			for _, t := range adjacency[v] {
+				if tScores[t] > bestScore {
+					best, bestScore = t, tScores[t]
+				}
+			}
+		}
+	}
+	copy(m.Indices, out)
+	m.IndicesChanged = true
+}
@@ -0,0 +1,175 @@
+// Copyright 2014 The Azul3D Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package meshopt optimizes meshes for faster drawing by the GPU.
+//
+// Each optimization operates in place on a single mesh, and leaves it's
+// primitives and their winding unchanged, only merging duplicate vertices and
+// reordering the triangles and vertices that they are drawn from. The entire
+// pipeline is applied by the Optimize function, whose before and after
+// statistics tell whether it paid off:
+//  m.Lock()
+//  before, after := meshopt.Optimize(m)
+//  m.Unlock()
+//  fmt.Printf("ACMR %.3f -> %.3f\n", before.ACMR, after.ACMR)
+//
+// Lower levels of detail of a mesh, with fewer triangles, are generated by the
+// Simplify and Chain functions. Unlike the optimizations, they return new
+// meshes and leave the original untouched.
+//
+// The optimizations act on the data slices of the mesh, and must thus run
+// before the mesh is loaded by a renderer that clears them.
+package meshopt
+
+import "azul3d.org/gfx.v1"
+
+// DefaultCacheSize is the number of entries of the post-transform vertex cache
+// assumed by the Optimize function, typical of most GPUs.
+const DefaultCacheSize = 16
+
+// Stats are statistics of how efficiently a mesh is drawn by the GPU, see the
+// Analyze function.
+type Stats struct {
+	// The number of vertices and triangles of the mesh.
+	Vertices, Triangles int
+
+	// The average cache miss ratio, that is the average number of vertices
+	// transformed per triangle given a FIFO post-transform vertex cache. It
+	// ranges from 3 (no vertex reuse) down to about 0.5 for large regular
+	// grids, lower is better.
+	ACMR float64
+
+	// The average transformed vertex ratio, that is the average number of
+	// times that each vertex is transformed given a FIFO post-transform vertex
+	// cache. It is 1 in the best case, lower is better.
+	ATVR float64
+}
+
+// Analyze returns the statistics of drawing the mesh with a FIFO
+// post-transform vertex cache of the given size. Only meshes whose Primitive
+// forms triangles are drawn with the vertex cache: the statistics of others
+// are all zero except for the number of vertices.
+//
+// As GPUs identify the vertices in the cache by their index, the vertices of
+// non-indexed meshes are never reused.
+//
+// The mesh's read lock must be held for this function to operate safely.
+func Analyze(m *gfx.Mesh, cacheSize int) Stats {
+	s := Stats{Vertices: len(m.Vertices)}
+	if m.Primitive.Vertices() != 3 || s.Vertices == 0 {
+		return s
+	}
+	indices := m.Indices
+	if len(indices) == 0 {
+		s.Triangles = m.Primitive.Count(s.Vertices)
+		s.ACMR = 3
+		if m.Primitive != gfx.Triangles {
+			s.ACMR = float64(s.Triangles+2) / float64(s.Triangles)
+		}
+		s.ATVR = 1
+		return s
+	}
+	s.Triangles = m.Primitive.Count(len(indices))
+	if s.Triangles == 0 {
+		return s
+	}
+	cache := newFIFO(cacheSize)
+	misses := 0
+	for _, index := range indices {
+		if !cache.add(index) {
+			misses++
+		}
+	}
+	s.ACMR = float64(misses) / float64(s.Triangles)
+	s.ATVR = float64(misses) / float64(s.Vertices)
+	return s
+}
+
+// fifo simulates a FIFO post-transform vertex cache.
+type fifo struct {
+	entries []uint32
+	next    int
+	in      map[uint32]bool
+}
+
+func newFIFO(size int) *fifo {
+	if size < 1 {
+		size = 1
+	}
+	return &fifo{
+		entries: make([]uint32, 0, size),
+		in:      make(map[uint32]bool, size),
+	}
+}
+
+// add adds the vertex to the cache if it's not in it already, and returns
+// whether it was (i.e. a cache hit).
+func (f *fifo) add(v uint32) bool {
+	if f.in[v] {
+		return true
+	}
+	if len(f.entries) < cap(f.entries) {
+		f.entries = append(f.entries, v)
+	} else {
+		delete(f.in, f.entries[f.next])
+		f.entries[f.next] = v
+		f.next = (f.next + 1) % len(f.entries)
+	}
+	f.in[v] = true
+	return false
+}
+
+// Weld merges the vertices of the mesh whose data is identical in each
+// per-vertex data slice, including the colors, texture coordinates, and
+// custom attributes. The mesh becomes indexed if it wasn't already. It is
+// short-hand for:
+//  m.Reindex()
+//
+// The mesh's write lock must be held for this function to operate safely.
+func Weld(m *gfx.Mesh) {
+	m.Reindex()
+}
+
+// VertexFetch reorders the vertices of the indexed mesh in the order that
+// they are first referenced by it's indices, such that the GPU fetches the
+// vertex data in a mostly sequential manner. Vertices that are not referenced
+// by any index are removed. For non-indexed meshes this function does
+// nothing.
+//
+// The mesh's write lock must be held for this function to operate safely.
+func VertexFetch(m *gfx.Mesh) {
+	if len(m.Indices) == 0 {
+		return
+	}
+	// Reindex numbers the unique vertices in the order that they are first
+	// referenced.
+	m.Reindex()
+}
+
+// Optimize applies each optimization of this package to the mesh, in order:
+//  Weld(m)
+//  VertexCache(m)
+//  Overdraw(m, DefaultCacheSize)
+//  VertexFetch(m)
+//
+// and returns the statistics of the mesh before and after (see the Analyze
+// function, with DefaultCacheSize). The bounding box of the mesh is then
+// recalculated (see the gfx.Mesh.CalculateBounds method), though as only the
+// vertices that form whole primitives are taken into account it is
+// unchanged.
+//
+// Only meshes whose Primitive is gfx.Triangles are reordered, others are only
+// welded.
+//
+// The mesh's write lock must be held for this function to operate safely.
+func Optimize(m *gfx.Mesh) (before, after Stats) {
+	before = Analyze(m, DefaultCacheSize)
+	Weld(m)
+	VertexCache(m)
+	Overdraw(m, DefaultCacheSize)
+	VertexFetch(m)
+	m.CalculateBounds()
+	after = Analyze(m, DefaultCacheSize)
+	return
+}
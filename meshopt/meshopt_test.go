@@ -0,0 +1,242 @@
+// Copyright 2014 The Azul3D Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package meshopt
+
+import (
+	"image"
+	"math"
+	"math/rand"
+	"testing"
+
+	"azul3d.org/gfx.v1"
+	"azul3d.org/gfx.v1/shape"
+	"azul3d.org/lmath.v1"
+)
+
+// triangleSet returns the number of times each triangle of the mesh occurs, by
+// the positions of it's vertices rotated such that the smallest index into the
+// given list of positions comes first (preserving the winding).
+func triangleSet(m *gfx.Mesh) map[[3]gfx.Vec3]int {
+	set := make(map[[3]gfx.Vec3]int)
+	less := func(a, b gfx.Vec3) bool {
+		if a.X != b.X {
+			return a.X < b.X
+		}
+		if a.Y != b.Y {
+			return a.Y < b.Y
+		}
+		return a.Z < b.Z
+	}
+	for t := 0; t < len(m.Indices)/3; t++ {
+		var tri [3]gfx.Vec3
+		for k := range tri {
+			tri[k] = m.Vertices[m.Indices[t*3+k]]
+		}
+		for less(tri[1], tri[0]) || less(tri[2], tri[0]) {
+			tri = [3]gfx.Vec3{tri[1], tri[2], tri[0]}
+		}
+		set[tri]++
+	}
+	return set
+}
+
+// shuffled returns a sphere whose triangles are in random order, and whose
+// vertices are all duplicated.
+func shuffled() *gfx.Mesh {
+	m := shape.UVSphere(1, 32, 16)
+	r := rand.New(rand.NewSource(1))
+	tris := len(m.Indices) / 3
+	for i := tris - 1; i > 0; i-- {
+		j := r.Intn(i + 1)
+		for k := 0; k < 3; k++ {
+			m.Indices[i*3+k], m.Indices[j*3+k] = m.Indices[j*3+k], m.Indices[i*3+k]
+		}
+	}
+	m.Deindex()
+	m.Reindex()
+	n := len(m.Vertices)
+	for i := range m.Indices {
+		if i%2 == 0 {
+			continue
+		}
+		// Point every other index to a copy of it's vertex.
+		v := m.Indices[i]
+		m.Vertices = append(m.Vertices, m.Vertices[v])
+		m.Normals = append(m.Normals, m.Normals[v])
+		m.TexCoords[0].Slice = append(m.TexCoords[0].Slice, m.TexCoords[0].Slice[v])
+		m.Indices[i] = uint32(len(m.Vertices) - 1)
+	}
+	if len(m.Vertices) == n {
+		panic("no duplicates")
+	}
+	return m
+}
+
+func TestOptimize(t *testing.T) {
+	m := shuffled()
+	want := triangleSet(m)
+	m.CalculateBounds()
+	bounds := m.AABB
+	unique := shape.UVSphere(1, 32, 16)
+	unique.Reindex() // Drops the unused vertices at the poles.
+
+	before, after := Optimize(m)
+	t.Logf("before %+v", before)
+	t.Logf("after %+v", after)
+
+	if after.Vertices != len(unique.Vertices) {
+		t.Errorf("got %d vertices after welding, want %d", after.Vertices, len(unique.Vertices))
+	}
+	if after.Triangles != before.Triangles {
+		t.Errorf("got %d triangles, want %d", after.Triangles, before.Triangles)
+	}
+	if !(after.ACMR < before.ACMR) || after.ACMR > 0.8 {
+		t.Errorf("ACMR %v -> %v, want a decrease to at most 0.8", before.ACMR, after.ACMR)
+	}
+	if after.ATVR > 1.5 {
+		t.Errorf("ATVR %v, want at most 1.5", after.ATVR)
+	}
+	if m.AABB != bounds {
+		t.Errorf("AABB %v, want %v", m.AABB, bounds)
+	}
+
+	got := triangleSet(m)
+	if len(got) != len(want) {
+		t.Fatalf("got %d distinct triangles, want %d", len(got), len(want))
+	}
+	for tri, n := range want {
+		if got[tri] != n {
+			t.Errorf("triangle %v occurs %d times, want %d", tri, got[tri], n)
+		}
+	}
+
+	// Vertices are fetched in order of first use.
+	var next uint32
+	for _, v := range m.Indices {
+		if v > next {
+			t.Fatalf("vertex %d used before vertex %d", v, next)
+		}
+		if v == next {
+			next++
+		}
+	}
+}
+
+func TestAnalyze(t *testing.T) {
+	m := gfx.NewMesh()
+	m.Vertices = []gfx.Vec3{{0, 0, 0}, {1, 0, 0}, {1, 1, 0}, {0, 1, 0}}
+	m.Indices = []uint32{0, 1, 2, 0, 2, 3}
+	s := Analyze(m, 16)
+	if s.Triangles != 2 || s.ACMR != 2 || s.ATVR != 1 {
+		t.Errorf("got %+v, want 2 triangles, ACMR 2 and ATVR 1", s)
+	}
+
+	// With a single cache entry, only consecutive indices are reused.
+	s = Analyze(m, 1)
+	if s.ACMR != 3 {
+		t.Errorf("got ACMR %v, want 3", s.ACMR)
+	}
+
+	m.Primitive = gfx.Lines
+	if s := Analyze(m, 16); s.Triangles != 0 || s.ACMR != 0 {
+		t.Errorf("got %+v for lines, want no triangles", s)
+	}
+}
+
+// area returns the total area of the triangles of the indexed mesh.
+func area(m *gfx.Mesh) float64 {
+	var a float64
+	for t := 0; t < len(m.Indices)/3; t++ {
+		p0 := m.Vertices[m.Indices[t*3]].Vec3()
+		p1 := m.Vertices[m.Indices[t*3+1]].Vec3()
+		p2 := m.Vertices[m.Indices[t*3+2]].Vec3()
+		a += p1.Sub(p0).Cross(p2.Sub(p0)).Length() / 2
+	}
+	return a
+}
+
+func TestSimplifyPlane(t *testing.T) {
+	m := shape.Plane(2, 2, 8, 8)
+	m.CalculateBounds()
+	lod, err := Simplify(m, 2, math.Inf(1))
+	if n := len(lod.Indices) / 3; n != 2 {
+		t.Errorf("got %d triangles, want 2", n)
+	}
+	if err > 1e-6 {
+		t.Errorf("got error %v, want zero", err)
+	}
+	if lod.AABB != m.AABB {
+		t.Errorf("got AABB %v, want %v", lod.AABB, m.AABB)
+	}
+	if a := area(lod); math.Abs(a-4) > 1e-6 {
+		t.Errorf("got area %v, want 4", a)
+	}
+	if len(lod.Normals) != len(lod.Vertices) || len(lod.TexCoords[0].Slice) != len(lod.Vertices) {
+		t.Error("per-vertex data not preserved")
+	}
+}
+
+func TestSimplifySphere(t *testing.T) {
+	m := shape.UVSphere(1, 32, 16)
+	tris := len(m.Indices) / 3
+
+	lod, err := Simplify(m, tris/4, math.Inf(1))
+	if n := len(lod.Indices) / 3; n > tris/4 {
+		t.Errorf("got %d triangles, want at most %d", n, tris/4)
+	}
+	if err <= 0 || err > 0.2 {
+		t.Errorf("got error %v, want within (0, 0.2]", err)
+	}
+	if len(lod.TexCoords[0].Slice) != len(lod.Vertices) {
+		t.Error("texture coordinates not preserved")
+	}
+
+	// Simplifying by error stops before exceeding it.
+	lod, err = Simplify(m, 0, 0.01)
+	if err > 0.01 {
+		t.Errorf("got error %v, want at most 0.01", err)
+	}
+	if n := len(lod.Indices) / 3; n >= tris {
+		t.Errorf("got %d triangles, want fewer than %d", n, tris)
+	}
+
+	levels := Chain(m, 4, 0.5)
+	if len(levels) != 4 || levels[0].Mesh != m {
+		t.Fatalf("got %d levels, want 4 starting with the mesh itself", len(levels))
+	}
+	for i := 1; i < len(levels); i++ {
+		prev, cur := levels[i-1], levels[i]
+		if len(cur.Mesh.Indices) >= len(prev.Mesh.Indices) || cur.Error < prev.Error {
+			t.Errorf("level %d: %d indices (error %v) after %d (error %v)", i, len(cur.Mesh.Indices), cur.Error, len(prev.Mesh.Indices), prev.Error)
+		}
+	}
+}
+
+func TestLODs(t *testing.T) {
+	levels := Chain(shape.UVSphere(1, 32, 16), 3, 0.5)
+	lods := LODs(levels, []float64{50, 200}, []float64{0, 0.001})
+	if len(lods) != 2 || lods[0].Meshes[0] != levels[1].Mesh || lods[1].Distance != 200 || lods[1].Coverage != 0.001 {
+		t.Fatalf("got levels of detail %+v", lods)
+	}
+
+	o := gfx.NewObject()
+	o.Meshes = []*gfx.Mesh{levels[0].Mesh}
+	o.LODs = lods
+	c := gfx.NewCamera()
+	c.SetPersp(image.Rect(0, 0, 100, 100), 90, 0.1, 1000)
+	for _, want := range []struct {
+		y    float64
+		mesh *gfx.Mesh
+	}{
+		{10, levels[0].Mesh},
+		{100, levels[1].Mesh},
+		{300, levels[2].Mesh},
+	} {
+		o.Transform.SetPos(lmath.Vec3{Y: want.y})
+		if got := o.SelectLOD(c); got[0] != want.mesh {
+			t.Errorf("at distance %v: got wrong level of detail", want.y)
+		}
+	}
+}
@@ -0,0 +1,114 @@
+// Copyright 2014 The Azul3D Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package meshopt
+
+import (
+	"sort"
+
+	"azul3d.org/gfx.v1"
+	"azul3d.org/lmath.v1"
+)
+
+// cluster is a run of consecutive triangles, to be drawn together.
+type cluster struct {
+	start, end int // Range of triangles.
+	key        float64
+}
+
+type byKey []cluster
+
+func (c byKey) Len() int           { return len(c) }
+func (c byKey) Swap(i, j int)      { c[i], c[j] = c[j], c[i] }
+func (c byKey) Less(i, j int) bool { return c[i].key > c[j].key }
+
+// Overdraw reorders the triangles of the indexed mesh such that, from most
+// viewpoints, the triangles closer to the viewer tend to be drawn first and
+// the GPU can reject more of the fragments hidden behind them by depth
+// testing.
+//
+// To preserve the efficiency of the vertex cache, the triangles are expected
+// to already be in vertex cache order (see the VertexCache function): they
+// are split into clusters wherever all three vertices of a triangle miss a
+// FIFO post-transform vertex cache of the given size, and only the clusters
+// are reordered. Following Sander et al., "Fast Triangle Reordering for Vertex
+// Locality and Reduced Overdraw", clusters facing away from the center of
+// the mesh are drawn first, as they are the most likely to occlude others.
+// Thus the mesh is best a closed one, like most solid objects.
+//
+// Only indexed meshes whose Primitive is gfx.Triangles are reordered, for
+// others this function does nothing. The vertices of each triangle keep their
+// order, and thus the triangle's winding.
+//
+// The mesh's write lock must be held for this function to operate safely.
+func Overdraw(m *gfx.Mesh, cacheSize int) {
+	tris := triangles(m)
+	if tris == 0 {
+		return
+	}
+	indices := m.Indices
+
+	// Split the triangles into clusters at hard cache boundaries.
+	var clusters []cluster
+	cache := newFIFO(cacheSize)
+	for t := 0; t < tris; t++ {
+		misses := 0
+		for _, v := range indices[t*3 : t*3+3] {
+			if !cache.add(v) {
+				misses++
+			}
+		}
+		if misses == 3 || t == 0 {
+			clusters = append(clusters, cluster{start: t})
+		}
+		clusters[len(clusters)-1].end = t + 1
+	}
+	if len(clusters) < 2 {
+		return
+	}
+
+	// The area weighted centroid and normal of each cluster, and of the mesh.
+	type moments struct {
+		centroid, normal lmath.Vec3
+		area             float64
+	}
+	sums := make([]moments, len(clusters))
+	var mesh moments
+	for i, c := range clusters {
+		s := &sums[i]
+		for t := c.start; t < c.end; t++ {
+			p0 := m.Vertices[indices[t*3]].Vec3()
+			p1 := m.Vertices[indices[t*3+1]].Vec3()
+			p2 := m.Vertices[indices[t*3+2]].Vec3()
+			n := p1.Sub(p0).Cross(p2.Sub(p0))
+			area := n.Length() / 2
+			center := p0.Add(p1).Add(p2).DivScalar(3)
+			s.centroid = s.centroid.Add(center.MulScalar(area))
+			s.normal = s.normal.Add(n)
+			s.area += area
+		}
+		mesh.centroid = mesh.centroid.Add(s.centroid)
+		mesh.area += s.area
+	}
+	if mesh.area == 0 {
+		return
+	}
+	mesh.centroid = mesh.centroid.DivScalar(mesh.area)
+	for i := range clusters {
+		s := sums[i]
+		if s.area == 0 {
+			continue
+		}
+		normal, _ := s.normal.Normalized()
+		clusters[i].key = s.centroid.DivScalar(s.area).Sub(mesh.centroid).Dot(normal)
+	}
+	sort.Stable(byKey(clusters))
+
+	out := make([]uint32, 0, tris*3)
+	for _, c := range clusters {
+		out = append(out, indices[c.start*3:c.end*3]...)
+	}
+	copy(m.Indices, out)
+	m.IndicesChanged = true
+}
@@ -0,0 +1,481 @@
+// Copyright 2014 The Azul3D Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package meshopt
+
+import (
+	"container/heap"
+	"math"
+
+	"azul3d.org/gfx.v1"
+	"azul3d.org/lmath.v1"
+)
+
+// quadric is a symmetric 4x4 matrix measuring the sum of weighted squared
+// distances of a point to a set of planes, as described by Garland and
+// Heckbert in "Surface Simplification Using Quadric Error Metrics". The weight
+// of the planes of the surface itself is accumulated separately, such that
+// the error may be expressed as a distance.
+type quadric struct {
+	a2, ab, ac, ad float64
+	b2, bc, bd     float64
+	c2, cd         float64
+	d2             float64
+	weight         float64
+}
+
+// planeQuadric returns the quadric of the plane with the given unit normal
+// passing through the point p, with the given weight.
+func planeQuadric(n, p lmath.Vec3, weight float64) quadric {
+	a, b, c := n.X, n.Y, n.Z
+	d := -n.Dot(p)
+	return quadric{
+		a * a * weight, a * b * weight, a * c * weight, a * d * weight,
+		b * b * weight, b * c * weight, b * d * weight,
+		c * c * weight, c * d * weight,
+		d * d * weight,
+		0,
+	}
+}
+
+func (q quadric) add(r quadric) quadric {
+	return quadric{
+		q.a2 + r.a2, q.ab + r.ab, q.ac + r.ac, q.ad + r.ad,
+		q.b2 + r.b2, q.bc + r.bc, q.bd + r.bd,
+		q.c2 + r.c2, q.cd + r.cd,
+		q.d2 + r.d2,
+		q.weight + r.weight,
+	}
+}
+
+// distance returns the weighted mean squared distance of the point p to the
+// planes of the quadric.
+func (q quadric) distance(p lmath.Vec3) float64 {
+	x, y, z := p.X, p.Y, p.Z
+	e := q.a2*x*x + 2*q.ab*x*y + 2*q.ac*x*z + 2*q.ad*x +
+		q.b2*y*y + 2*q.bc*y*z + 2*q.bd*y +
+		q.c2*z*z + 2*q.cd*z +
+		q.d2
+	if q.weight > 0 {
+		e /= q.weight
+	}
+	return math.Abs(e)
+}
+
+// Kinds of vertices of a mesh being simplified.
+const (
+	interior = iota // Surrounded by triangles, may collapse onto any neighbor.
+	border          // On a straight boundary, may only collapse along it.
+	locked          // On a seam, a boundary corner, or non-manifold.
+)
+
+// collapse is a candidate collapse of the vertex u onto the vertex v.
+type collapse struct {
+	u, v    uint32
+	error   float64
+	version int
+}
+
+type collapseHeap []collapse
+
+func (h collapseHeap) Len() int            { return len(h) }
+func (h collapseHeap) Less(i, j int) bool  { return h[i].error < h[j].error }
+func (h collapseHeap) Swap(i, j int)       { h[i], h[j] = h[j], h[i] }
+func (h *collapseHeap) Push(x interface{}) { *h = append(*h, x.(collapse)) }
+func (h *collapseHeap) Pop() interface{} {
+	old := *h
+	c := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return c
+}
+
+// simplifier simplifies an indexed triangle mesh by half-edge collapses.
+type simplifier struct {
+	pos      []lmath.Vec3
+	tris     [][3]uint32
+	removed  []bool    // Per triangle.
+	adj      [][]int   // Triangles of each vertex, including removed ones.
+	kind     []int     // Per vertex.
+	gone     []bool    // Per vertex.
+	quadrics []quadric // Per vertex.
+	version  []int     // Per vertex, incremented as it's neighborhood changes.
+	queue    collapseHeap
+}
+
+func newSimplifier(m *gfx.Mesh) *simplifier {
+	n := len(m.Vertices)
+	s := &simplifier{
+		pos:      make([]lmath.Vec3, n),
+		adj:      make([][]int, n),
+		kind:     make([]int, n),
+		gone:     make([]bool, n),
+		quadrics: make([]quadric, n),
+		version:  make([]int, n),
+	}
+	for i, v := range m.Vertices {
+		s.pos[i] = v.Vec3()
+	}
+	tris := m.Primitive.Count(len(m.Indices))
+	s.tris = make([][3]uint32, tris)
+	s.removed = make([]bool, tris)
+	for t := range s.tris {
+		copy(s.tris[t][:], m.Indices[t*3:t*3+3])
+		for _, v := range s.tris[t] {
+			s.adj[v] = append(s.adj[v], t)
+		}
+	}
+
+	// Vertices sharing their position with others lie along a seam of their
+	// data (e.g. texture coordinates), which would open if collapsed.
+	first := make(map[gfx.Vec3]uint32, n)
+	for i, v := range m.Vertices {
+		if f, ok := first[v]; ok {
+			s.kind[i] = locked
+			s.kind[f] = locked
+			continue
+		}
+		first[v] = uint32(i)
+	}
+
+	// Count the triangles of each edge, to find the boundary edges.
+	type edge struct{ a, b uint32 }
+	edges := make(map[edge]int)
+	for _, tri := range s.tris {
+		for k := 0; k < 3; k++ {
+			a, b := tri[k], tri[(k+1)%3]
+			if a > b {
+				a, b = b, a
+			}
+			edges[edge{a, b}]++
+		}
+	}
+
+	boundary := make(map[uint32][]lmath.Vec3)
+	for _, tri := range s.tris {
+		p0, p1, p2 := s.pos[tri[0]], s.pos[tri[1]], s.pos[tri[2]]
+		normal := p1.Sub(p0).Cross(p2.Sub(p0))
+		area := normal.Length() / 2
+		normal, ok := normal.Normalized()
+		if !ok {
+			continue
+		}
+		q := planeQuadric(normal, p0, area)
+		q.weight = area
+		for _, v := range tri {
+			s.quadrics[v] = s.quadrics[v].add(q)
+		}
+		for k := 0; k < 3; k++ {
+			a, b := tri[k], tri[(k+1)%3]
+			key := edge{a, b}
+			if a > b {
+				key = edge{b, a}
+			}
+			switch edges[key] {
+			case 2:
+				continue
+			case 1:
+				e, _ := s.pos[b].Sub(s.pos[a]).Normalized()
+				boundary[a] = append(boundary[a], e)
+				boundary[b] = append(boundary[b], e.MulScalar(-1))
+			default:
+				// Non-manifold edge.
+				s.kind[a] = locked
+				s.kind[b] = locked
+			}
+		}
+	}
+
+	// Boundary vertices may only collapse along the boundary where it is a
+	// straight line, such that the outline of the mesh is kept intact.
+	for v, dirs := range boundary {
+		if s.kind[v] == locked {
+			continue
+		}
+		if len(dirs) == 2 && dirs[0].Dot(dirs[1]) < -1+1e-9 {
+			s.kind[v] = border
+			continue
+		}
+		s.kind[v] = locked
+	}
+	return s
+}
+
+// neighbors returns the vertices sharing a remaining triangle with the vertex
+// v, and the number of remaining triangles that share each of them.
+func (s *simplifier) neighbors(v uint32) map[uint32]int {
+	n := make(map[uint32]int)
+	for _, t := range s.adj[v] {
+		if s.removed[t] {
+			continue
+		}
+		for _, w := range s.tris[t] {
+			if w != v {
+				n[w]++
+			}
+		}
+	}
+	return n
+}
+
+// valid reports whether the vertex u may collapse onto the vertex v, that is
+// whether the collapse keeps the surface manifold and flips no triangle.
+func (s *simplifier) valid(u, v uint32, shared int) bool {
+	switch s.kind[u] {
+	case locked:
+		return false
+	case border:
+		// Only along the boundary edge.
+		if shared != 1 || s.kind[v] == interior {
+			return false
+		}
+	}
+
+	// Link condition: the vertices adjacent to both u and v must be exactly
+	// those of the triangles of the edge u-v.
+	nu, nv := s.neighbors(u), s.neighbors(v)
+	common := 0
+	for w := range nu {
+		if w != v && nv[w] > 0 {
+			common++
+		}
+	}
+	if common != shared {
+		return false
+	}
+
+	// The remaining triangles of u must not flip, or become degenerate.
+	for _, t := range s.adj[u] {
+		if s.removed[t] {
+			continue
+		}
+		tri := s.tris[t]
+		if tri[0] == v || tri[1] == v || tri[2] == v {
+			continue
+		}
+		var before, after [3]lmath.Vec3
+		for k, w := range tri {
+			before[k], after[k] = s.pos[w], s.pos[w]
+			if w == u {
+				after[k] = s.pos[v]
+			}
+		}
+		n0 := before[1].Sub(before[0]).Cross(before[2].Sub(before[0]))
+		n1 := after[1].Sub(after[0]).Cross(after[2].Sub(after[0]))
+		if n1.Dot(n0) <= 0 || n1.Length() < 1e-6*n0.Length() {
+			return false
+		}
+	}
+	return true
+}
+
+// queueBest queues the lowest error collapse of the vertex u, if any.
+func (s *simplifier) queueBest(u uint32) {
+	if s.gone[u] || s.kind[u] == locked {
+		return
+	}
+	best := collapse{u: u, error: math.Inf(1), version: s.version[u]}
+	for v, shared := range s.neighbors(u) {
+		if shared > 2 {
+			continue
+		}
+		e := s.quadrics[u].add(s.quadrics[v]).distance(s.pos[v])
+		if e < best.error || (e == best.error && v < best.v) {
+			if s.valid(u, v, shared) {
+				best.v, best.error = v, e
+			}
+		}
+	}
+	if !math.IsInf(best.error, 1) {
+		heap.Push(&s.queue, best)
+	}
+}
+
+// collapse collapses the vertex u onto the vertex v, and returns the number of
+// triangles removed.
+func (s *simplifier) collapse(u, v uint32) int {
+	removed := 0
+	for _, t := range s.adj[u] {
+		if s.removed[t] {
+			continue
+		}
+		tri := &s.tris[t]
+		if tri[0] == v || tri[1] == v || tri[2] == v {
+			s.removed[t] = true
+			removed++
+			continue
+		}
+		for k, w := range tri {
+			if w == u {
+				tri[k] = v
+			}
+		}
+		s.adj[v] = append(s.adj[v], t)
+	}
+	s.gone[u] = true
+	s.adj[u] = nil
+	s.quadrics[v] = s.quadrics[v].add(s.quadrics[u])
+	return removed
+}
+
+// run simplifies the mesh until at most the given number of triangles remain,
+// or no collapse is within the given error. It returns the largest error of
+// the collapses that were made.
+func (s *simplifier) run(target int, maxError float64) float64 {
+	remaining := len(s.tris)
+	for u := range s.pos {
+		s.queueBest(uint32(u))
+	}
+	var maxDone float64
+	for remaining > target && s.queue.Len() > 0 {
+		c := heap.Pop(&s.queue).(collapse)
+		if s.gone[c.u] || c.version != s.version[c.u] {
+			// Stale, a newer candidate was queued.
+			continue
+		}
+		if s.gone[c.v] || !s.valid(c.u, c.v, s.neighbors(c.u)[c.v]) {
+			s.version[c.u]++
+			s.queueBest(c.u)
+			continue
+		}
+		if math.Sqrt(c.error) > maxError {
+			break
+		}
+		maxDone = math.Max(maxDone, math.Sqrt(c.error))
+		remaining -= s.collapse(c.u, c.v)
+
+		// The errors of collapsing v, and of collapsing it's neighbors (which
+		// now include those of u) onto it, have changed.
+		s.version[c.v]++
+		s.queueBest(c.v)
+		for w := range s.neighbors(c.v) {
+			s.version[w]++
+			s.queueBest(w)
+		}
+	}
+	return maxDone
+}
+
+// Simplify returns a simplified copy of the mesh, with at most the given
+// number of triangles, or (if that would exceed the given error) as few as
+// possible whose error is within maxError, and the actual error of the
+// simplified mesh. For instance:
+//  half, _ := meshopt.Simplify(m, len(m.Indices)/6, math.Inf(1))
+//  coarse, err := meshopt.Simplify(m, 0, 0.01)
+//
+// The mesh is simplified by repeatedly collapsing the vertex whose removal
+// least deforms the surface onto one of it's neighbors, as measured by the
+// quadric error metric of Garland and Heckbert. The error is the estimated
+// distance (in the units of the mesh's vertices) between the surface of the
+// simplified mesh and that of the original.
+//
+// Vertices are only ever removed, never moved, so the per-vertex data of the
+// remaining vertices (Normals, Colors, TexCoords, Attribs, etc) is preserved
+// as-is. Vertices on the boundary of the mesh only collapse along straight
+// runs of it, and vertices along seams in the data (i.e. that share their
+// position with another vertex) never collapse, such that the outline of the
+// mesh and it's seams are kept intact. As the triangles change, the
+// barycentric coordinates of the mesh are not preserved (see the
+// gfx.Mesh.GenerateBary method).
+//
+// Only meshes whose Primitive is gfx.Triangles are simplified, for others an
+// unmodified copy is returned. The returned mesh is indexed and has it's
+// bounding box calculated.
+//
+// The mesh's read lock must be held for this function to operate safely.
+func Simplify(m *gfx.Mesh, triangles int, maxError float64) (*gfx.Mesh, float64) {
+	cpy := m.Copy()
+	if m.Primitive != gfx.Triangles {
+		return cpy, 0
+	}
+	cpy.Bary = nil
+	cpy.Reindex()
+	if len(cpy.Vertices) == 0 {
+		return cpy, 0
+	}
+
+	s := newSimplifier(cpy)
+	err := s.run(triangles, maxError)
+	cpy.Indices = cpy.Indices[:0]
+	for t, tri := range s.tris {
+		if !s.removed[t] {
+			cpy.Indices = append(cpy.Indices, tri[:]...)
+		}
+	}
+	cpy.Reindex()
+	cpy.CalculateBounds()
+	return cpy, err
+}
+
+// Level is a single level of detail of a mesh, see the Chain function.
+type Level struct {
+	// The mesh of this level.
+	Mesh *gfx.Mesh
+
+	// The error of this level, as returned by the Simplify function.
+	Error float64
+}
+
+// Chain returns a chain of up to the given number of levels of detail of the
+// mesh, from the most detailed to the least. The first level is the mesh
+// itself with zero error, and each level after it is simplified (see the
+// Simplify function) to the given ratio of the triangles of the level before
+// it. For instance, to generate four levels with half the triangles of the
+// level before each:
+//  levels := meshopt.Chain(m, 4, 0.5)
+//
+// The chain ends early if the mesh cannot be simplified any further. Each
+// level is simplified from the mesh itself, such that errors do not
+// accumulate along the chain.
+//
+// The mesh's read lock must be held for this function to operate safely.
+func Chain(m *gfx.Mesh, levels int, ratio float64) []Level {
+	if levels < 1 {
+		return nil
+	}
+	chain := []Level{{Mesh: m}}
+	if m.Primitive != gfx.Triangles {
+		return chain
+	}
+	n := len(m.Vertices)
+	if len(m.Indices) > 0 {
+		n = len(m.Indices)
+	}
+	triangles := m.Primitive.Count(n)
+	for len(chain) < levels {
+		target := int(float64(triangles) * ratio)
+		lod, err := Simplify(m, target, math.Inf(1))
+		got := lod.Primitive.Count(len(lod.Indices))
+		if got >= triangles {
+			break
+		}
+		chain = append(chain, Level{Mesh: lod, Error: err})
+		triangles = got
+	}
+	return chain
+}
+
+// LODs returns the levels of detail of an object (see gfx.Object.LODs) for the
+// chain, whose first level is the object's own Meshes and is thus skipped.
+// The level i+1 of the chain is switched to at the distance distances[i] and
+// the screen coverage coverages[i], either of which may be nil or shorter than
+// the chain to not switch by it. For instance:
+//  levels := meshopt.Chain(m, 3, 0.5)
+//  o.Meshes = []*gfx.Mesh{levels[0].Mesh}
+//  o.LODs = meshopt.LODs(levels, []float64{50, 200}, nil)
+func LODs(chain []Level, distances, coverages []float64) []gfx.LOD {
+	if len(chain) < 2 {
+		return nil
+	}
+	lods := make([]gfx.LOD, len(chain)-1)
+	for i, l := range chain[1:] {
+		lods[i].Meshes = []*gfx.Mesh{l.Mesh}
+		if i < len(distances) {
+			lods[i].Distance = distances[i]
+		}
+		if i < len(coverages) {
+			lods[i].Coverage = coverages[i]
+		}
+	}
+	return lods
+}
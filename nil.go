@@ -7,6 +7,7 @@ package gfx
 import (
 	"image"
 	"sync"
+	"time"
 
 	"azul3d.org/clock.v1"
 )
@@ -20,11 +21,19 @@ func (n nilNativeObject) SampleCount() int {
 
 type nilNativeTexture struct {
 	format TexFormat
+
+	// The bounds of a render-to-texture texture, or an empty rectangle if
+	// the texture cannot be downloaded.
+	bounds image.Rectangle
 }
 
 func (n nilNativeTexture) Destroy() {}
 func (n nilNativeTexture) Download(r image.Rectangle, complete chan image.Image) {
-	complete <- nil
+	if n.bounds.Empty() {
+		complete <- nil
+		return
+	}
+	complete <- nilDownload(r, n.bounds)
 }
 func (n nilNativeTexture) ChosenFormat() TexFormat {
 	return n.format
@@ -38,7 +47,18 @@ type nilNativeShader struct{}
 
 func (n nilNativeShader) Destroy() {}
 
-type nilRenderer struct {
+// nilDownload returns a blank image of the rectangle r (or the entire bounds if
+// r is empty) clamped to the given bounds.
+func nilDownload(r, bounds image.Rectangle) image.Image {
+	if r.Empty() {
+		r = bounds
+	}
+	return image.NewRGBA(r.Intersect(bounds))
+}
+
+type nilCanvas struct {
+	ResizeBroadcaster
+
 	// The MSAA state.
 	msaa struct {
 		sync.RWMutex
@@ -47,58 +67,100 @@ type nilRenderer struct {
 
 	precision Precision
 
-	// The graphics clock.
-	clock *clock.Clock
+	// The canvas bounds.
+	bounds struct {
+		sync.RWMutex
+		image.Rectangle
+	}
 }
 
-func (n *nilRenderer) Clock() *clock.Clock {
-	return n.clock
+func (n *nilCanvas) Bounds() (b image.Rectangle) {
+	n.bounds.RLock()
+	b = n.bounds.Rectangle
+	n.bounds.RUnlock()
+	return
 }
 
-func (n *nilRenderer) Bounds() image.Rectangle {
-	return image.Rect(0, 0, 640, 480)
+func (n *nilCanvas) Resize(b image.Rectangle) {
+	n.bounds.Lock()
+	changed := n.bounds.Rectangle != b
+	n.bounds.Rectangle = b
+	n.bounds.Unlock()
+	if changed {
+		n.Resized(b)
+	}
 }
 
-func (n *nilRenderer) Precision() Precision {
+func (n *nilCanvas) Precision() Precision {
 	return n.precision
 }
 
-func (n *nilRenderer) GPUInfo() GPUInfo {
-	return GPUInfo{
-		MaxTextureSize:  8096,
-		AlphaToCoverage: true,
-		OcclusionQuery:  false,
-	}
-}
-func (n *nilRenderer) Download(r image.Rectangle, complete chan image.Image) {
-	complete <- nil
+func (n *nilCanvas) Download(r image.Rectangle, complete chan image.Image) {
+	complete <- nilDownload(r, n.Bounds())
 }
-func (n *nilRenderer) SetMSAA(msaa bool) {
+func (n *nilCanvas) SetMSAA(msaa bool) {
 	n.msaa.Lock()
 	n.msaa.enabled = msaa
 	n.msaa.Unlock()
 }
-func (n *nilRenderer) MSAA() (msaa bool) {
+func (n *nilCanvas) MSAA() (msaa bool) {
 	n.msaa.RLock()
 	msaa = n.msaa.enabled
 	n.msaa.RUnlock()
 	return
 }
-func (n *nilRenderer) Clear(r image.Rectangle, bg Color)           {}
-func (n *nilRenderer) ClearDepth(r image.Rectangle, depth float64) {}
-func (n *nilRenderer) ClearStencil(r image.Rectangle, stencil int) {}
-func (n *nilRenderer) Draw(r image.Rectangle, o *Object, c *Camera) {
+func (n *nilCanvas) Clear(r image.Rectangle, bg Color)           {}
+func (n *nilCanvas) ClearDepth(r image.Rectangle, depth float64) {}
+func (n *nilCanvas) ClearStencil(r image.Rectangle, stencil int) {}
+func (n *nilCanvas) Draw(r image.Rectangle, o *Object, c *Camera) {
 	o.Bounds()
 	o.Lock()
 	o.NativeObject = nilNativeObject{}
 	o.Unlock()
 }
-func (n *nilRenderer) QueryWait() {}
+func (n *nilCanvas) QueryWait() {}
+func (n *nilCanvas) Render()    {}
+
+type nilRenderer struct {
+	*nilCanvas
+
+	info    GPUInfo
+	latency time.Duration
+
+	// The graphics clock.
+	clock *clock.Clock
+}
+
+func (n *nilRenderer) Clock() *clock.Clock {
+	return n.clock
+}
+
+func (n *nilRenderer) GPUInfo() GPUInfo {
+	return n.info
+}
 func (n *nilRenderer) Render() {
 	n.clock.Tick()
 }
 
+// load invokes f after the simulated load latency. If there is any latency,
+// f is invoked in a separate goroutine.
+func (n *nilRenderer) load(f func()) {
+	if n.latency <= 0 {
+		f()
+		return
+	}
+	go func() {
+		time.Sleep(n.latency)
+		f()
+	}()
+}
+
 func (n *nilRenderer) LoadMesh(m *Mesh, done chan *Mesh) {
+	n.load(func() {
+		n.loadMesh(m, done)
+	})
+}
+func (n *nilRenderer) loadMesh(m *Mesh, done chan *Mesh) {
 	m.Lock()
 	m.Loaded = true
 	m.ClearData()
@@ -110,11 +172,16 @@ func (n *nilRenderer) LoadMesh(m *Mesh, done chan *Mesh) {
 	}
 }
 func (n *nilRenderer) LoadTexture(t *Texture, done chan *Texture) {
+	n.load(func() {
+		n.loadTexture(t, done)
+	})
+}
+func (n *nilRenderer) loadTexture(t *Texture, done chan *Texture) {
 	t.Lock()
 	t.Loaded = true
 	t.ClearData()
 	t.NativeTexture = nilNativeTexture{
-		t.Format,
+		format: t.Format,
 	}
 	t.Unlock()
 	select {
@@ -123,6 +190,11 @@ func (n *nilRenderer) LoadTexture(t *Texture, done chan *Texture) {
 	}
 }
 func (n *nilRenderer) LoadShader(s *Shader, done chan *Shader) {
+	n.load(func() {
+		n.loadShader(s, done)
+	})
+}
+func (n *nilRenderer) loadShader(s *Shader, done chan *Shader) {
 	s.Lock()
 	s.Loaded = true
 	s.ClearData()
@@ -134,21 +206,111 @@ func (n *nilRenderer) LoadShader(s *Shader, done chan *Shader) {
 	}
 }
 
+func nilHasTexFormat(formats []TexFormat, f TexFormat) bool {
+	for _, v := range formats {
+		if v == f {
+			return true
+		}
+	}
+	return false
+}
+
+func nilHasDSFormat(formats []DSFormat, f DSFormat) bool {
+	for _, v := range formats {
+		if v == f {
+			return true
+		}
+	}
+	return false
+}
+
 func (n *nilRenderer) RenderToTexture(cfg RTTConfig) Canvas {
-	return nil
+	if !cfg.Valid() {
+		panic("RenderToTexture(): invalid configuration")
+	}
+	if cfg.ColorFormat != ZeroTexFormat && !nilHasTexFormat(n.info.ColorFormats, cfg.ColorFormat) {
+		return nil
+	}
+	if cfg.DepthFormat != ZeroDSFormat && !nilHasDSFormat(n.info.DepthFormats, cfg.DepthFormat) {
+		return nil
+	}
+	if cfg.StencilFormat != ZeroDSFormat && !nilHasDSFormat(n.info.StencilFormats, cfg.StencilFormat) {
+		return nil
+	}
+
+	bounds := cfg.Bounds
+	if bounds.Empty() {
+		bounds = n.Bounds()
+	}
+	bounds = bounds.Sub(bounds.Min)
+
+	red, green, blue, alpha := cfg.ColorFormat.Bits()
+	c := &nilCanvas{
+		precision: Precision{
+			RedBits:     red,
+			GreenBits:   green,
+			BlueBits:    blue,
+			AlphaBits:   alpha,
+			DepthBits:   cfg.DepthFormat.DepthBits(),
+			StencilBits: cfg.StencilFormat.StencilBits(),
+		},
+	}
+	c.bounds.Rectangle = bounds
+	c.msaa.enabled = n.MSAA()
+
+	setup := func(t *Texture, format TexFormat) {
+		if t == nil {
+			return
+		}
+		t.Lock()
+		t.Loaded = true
+		t.ClearData()
+		t.Bounds = bounds
+		t.NativeTexture = nilNativeTexture{
+			format: format,
+			bounds: bounds,
+		}
+		t.Unlock()
+	}
+	setup(cfg.Color, cfg.ColorFormat)
+	setup(cfg.Depth, RGBA)
+	setup(cfg.Stencil, RGBA)
+	return c
 }
 
-// Nil returns a renderer that does not actually render anything.
+// Nil returns a renderer that does not actually render anything. The renderer
+// (and it's render-to-texture canvases) implement the Resizer interface, which
+// may be used to simulate resizes, and the ResizeNotifier interface. It is
+// short-hand for:
+//  NewNil(DefaultNilConfig)
 func Nil() Renderer {
-	r := new(nilRenderer)
-	r.precision = Precision{
-		RedBits:     255,
-		GreenBits:   255,
-		BlueBits:    255,
-		AlphaBits:   255,
-		DepthBits:   255,
-		StencilBits: 255,
+	return NewNil(DefaultNilConfig)
+}
+
+// NewNil returns a renderer that does not actually render anything, but which
+// reports the bounds, precision, and GPU information of the given
+// configuration. The slices of the GPU information are copied, such that the
+// configuration (e.g. one of the presets) may be modified afterwards. For
+// example, to test how an application behaves on a WebGL device whose loads
+// take a while to complete:
+//  cfg := gfx.NilWebGL1
+//  cfg.LoadLatency = 50 * time.Millisecond
+//  r := gfx.NewNil(cfg)
+func NewNil(cfg NilConfig) Renderer {
+	r := &nilRenderer{
+		nilCanvas: &nilCanvas{
+			precision: cfg.Precision,
+		},
+		info:    cfg.GPUInfo,
+		latency: cfg.LoadLatency,
 	}
+	info := &r.info
+	info.GLExtensions = append([]string(nil), info.GLExtensions...)
+	info.Samples = append([]int(nil), info.Samples...)
+	info.ColorFormats = append([]TexFormat(nil), info.ColorFormats...)
+	info.DepthFormats = append([]DSFormat(nil), info.DepthFormats...)
+	info.StencilFormats = append([]DSFormat(nil), info.StencilFormats...)
+	r.bounds.Rectangle = cfg.Bounds
 	r.msaa.enabled = true
 	r.clock = clock.New()
 	return r
@@ -5,8 +5,10 @@
 package gfx
 
 import (
+	"image"
 	"image/color"
 	"testing"
+	"time"
 )
 
 func TestNilRenderer(t *testing.T) {
@@ -41,3 +43,95 @@ func TestNilRenderer(t *testing.T) {
 		r.Render()
 	}
 }
+
+func TestNilRenderToTexture(t *testing.T) {
+	r := Nil()
+
+	// Choose a configuration from the advertised formats.
+	cfg := r.GPUInfo().RTTFormats.ChooseConfig(Precision{
+		RedBits: 8, GreenBits: 8, BlueBits: 8, AlphaBits: 8,
+		DepthBits: 24, StencilBits: 8,
+	}, false)
+	cfg.Bounds = image.Rect(10, 10, 138, 74)
+	cfg.Color = NewTexture()
+	cfg.Depth = NewTexture()
+	if !cfg.Valid() {
+		t.Fatal("invalid configuration", cfg)
+	}
+
+	canvas := r.RenderToTexture(cfg)
+	if canvas == nil {
+		t.Fatal("RenderToTexture returned nil")
+	}
+	want := image.Rect(0, 0, 128, 64)
+	if canvas.Bounds() != want {
+		t.Fatal("got canvas bounds", canvas.Bounds(), "want", want)
+	}
+	for _, tex := range []*Texture{cfg.Color, cfg.Depth} {
+		if !tex.Loaded || tex.Bounds != want {
+			t.Fatal("got texture loaded", tex.Loaded, "bounds", tex.Bounds)
+		}
+		complete := make(chan image.Image, 1)
+		tex.NativeTexture.Download(image.Rect(0, 0, 0, 0), complete)
+		if img := <-complete; img == nil || img.Bounds() != want {
+			t.Fatal("got downloaded image", img)
+		}
+	}
+
+	// Render a frame to the canvas.
+	canvas.Clear(image.Rect(0, 0, 0, 0), Color{1, 1, 1, 1})
+	canvas.Draw(canvas.Bounds(), NewObject(), NewCamera())
+	canvas.Render()
+
+	complete := make(chan image.Image, 1)
+	canvas.Download(image.Rect(16, 16, 32, 32), complete)
+	if img := <-complete; img == nil || img.Bounds() != image.Rect(16, 16, 32, 32) {
+		t.Fatal("got downloaded image", img)
+	}
+}
+
+func TestNilConfig(t *testing.T) {
+	cfg := NilGLES2Phone
+	cfg.LoadLatency = 10 * time.Millisecond
+	r := NewNil(cfg)
+	if r.Bounds() != cfg.Bounds || r.Precision() != cfg.Precision {
+		t.Fatal("got bounds", r.Bounds(), "precision", r.Precision())
+	}
+	if r.GPUInfo().NPOT {
+		t.Fatal("GLES2 phone reports NPOT support")
+	}
+
+	// Loading completes asynchronously.
+	m := NewMesh()
+	done := make(chan *Mesh, 1)
+	r.LoadMesh(m, done)
+	select {
+	case <-done:
+		t.Fatal("load completed without latency")
+	default:
+	}
+	<-done
+	m.RLock()
+	loaded := m.Loaded
+	m.RUnlock()
+	if !loaded {
+		t.Fatal("mesh not loaded")
+	}
+
+	// Unsupported render-to-texture formats are rejected.
+	canvas := r.RenderToTexture(RTTConfig{
+		Depth:       NewTexture(),
+		DepthFormat: Depth24,
+	})
+	if canvas != nil {
+		t.Fatal("Depth24 render-to-texture canvas created")
+	}
+
+	// Modifying the renderer's information leaves the preset intact.
+	r = NewNil(NilDesktopGL3)
+	r.GPUInfo().GLExtensions[0] = "modified"
+	r.GPUInfo().DepthFormats[0] = Depth32
+	if NilDesktopGL3.GPUInfo.GLExtensions[0] == "modified" || NilDesktopGL3.GPUInfo.DepthFormats[0] == Depth32 {
+		t.Fatal("preset modified through renderer")
+	}
+}
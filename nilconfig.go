@@ -0,0 +1,171 @@
+// Copyright 2014 The Azul3D Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package gfx
+
+import (
+	"image"
+	"time"
+)
+
+// NilConfig is a configuration of a nil renderer (see NewNil), which emulates
+// a specific kind of graphics hardware.
+type NilConfig struct {
+	// The bounds of the renderer's canvas.
+	Bounds image.Rectangle
+
+	// The precision of the renderer's color, depth, and stencil buffers.
+	Precision Precision
+
+	// The information returned by the renderer's GPUInfo method. Note that
+	// the RTTFormats field determines which render-to-texture configurations
+	// the renderer accepts.
+	GPUInfo GPUInfo
+
+	// The simulated latency of each LoadMesh, LoadTexture, and LoadShader
+	// operation. If non-zero, each load completes asynchronously after the
+	// given amount of time has passed.
+	LoadLatency time.Duration
+}
+
+var (
+	// The default nil renderer configuration, used by Nil.
+	DefaultNilConfig = NilConfig{
+		Bounds: image.Rect(0, 0, 640, 480),
+		Precision: Precision{
+			RedBits:     255,
+			GreenBits:   255,
+			BlueBits:    255,
+			AlphaBits:   255,
+			DepthBits:   255,
+			StencilBits: 255,
+		},
+		GPUInfo: GPUInfo{
+			MaxTextureSize:  8096,
+			AlphaToCoverage: true,
+			OcclusionQuery:  false,
+			RTTFormats: RTTFormats{
+				Samples:        []int{1, 2, 4, 8},
+				ColorFormats:   []TexFormat{RGB, RGBA},
+				DepthFormats:   []DSFormat{Depth16, Depth24, Depth32, Depth24AndStencil8},
+				StencilFormats: []DSFormat{Depth24AndStencil8},
+			},
+			GLMajor:               -1,
+			GLMinor:               -1,
+			GLSLMajor:             -1,
+			GLSLMinor:             -1,
+			GLSLMaxVaryingFloats:  -1,
+			GLSLMaxVertexInputs:   -1,
+			GLSLMaxFragmentInputs: -1,
+		},
+	}
+
+	// A nil renderer configuration emulating an OpenGL ES 2 phone with a
+	// 16-bit color buffer, no support for non-power-of-two textures or
+	// occlusion queries, and only a 16-bit depth buffer for render-to-texture.
+	NilGLES2Phone = NilConfig{
+		Bounds: image.Rect(0, 0, 720, 1280),
+		Precision: Precision{
+			RedBits:     5,
+			GreenBits:   6,
+			BlueBits:    5,
+			DepthBits:   16,
+			StencilBits: 8,
+		},
+		GPUInfo: GPUInfo{
+			MaxTextureSize:     2048,
+			AlphaToCoverage:    true,
+			OcclusionQuery:     false,
+			OcclusionQueryBits: 0,
+			Name:               "Nil GLES2 Phone",
+			Vendor:             "Azul3D",
+			NPOT:               false,
+			RTTFormats: RTTFormats{
+				Samples:      []int{1},
+				ColorFormats: []TexFormat{RGB, RGBA},
+				DepthFormats: []DSFormat{Depth16},
+			},
+			GLMajor:               2,
+			GLMinor:               0,
+			GLSLMajor:             1,
+			GLSLMinor:             0,
+			GLSLMaxVaryingFloats:  32,
+			GLSLMaxVertexInputs:   512,
+			GLSLMaxFragmentInputs: 64,
+		},
+	}
+
+	// A nil renderer configuration emulating a WebGL 1 browser, with no support
+	// for non-power-of-two textures or occlusion queries.
+	NilWebGL1 = NilConfig{
+		Bounds: image.Rect(0, 0, 1024, 768),
+		Precision: Precision{
+			RedBits:     8,
+			GreenBits:   8,
+			BlueBits:    8,
+			AlphaBits:   8,
+			DepthBits:   24,
+			StencilBits: 8,
+		},
+		GPUInfo: GPUInfo{
+			MaxTextureSize:     4096,
+			AlphaToCoverage:    true,
+			OcclusionQuery:     false,
+			OcclusionQueryBits: 0,
+			Name:               "Nil WebGL1",
+			Vendor:             "Azul3D",
+			NPOT:               false,
+			RTTFormats: RTTFormats{
+				Samples:        []int{1},
+				ColorFormats:   []TexFormat{RGB, RGBA},
+				DepthFormats:   []DSFormat{Depth16, Depth24AndStencil8},
+				StencilFormats: []DSFormat{Depth24AndStencil8},
+			},
+			GLMajor:               2,
+			GLMinor:               0,
+			GLSLMajor:             1,
+			GLSLMinor:             0,
+			GLSLMaxVaryingFloats:  60,
+			GLSLMaxVertexInputs:   1024,
+			GLSLMaxFragmentInputs: 896,
+		},
+	}
+
+	// A nil renderer configuration emulating a desktop OpenGL 3.3 graphics
+	// card, which supports every feature.
+	NilDesktopGL3 = NilConfig{
+		Bounds: image.Rect(0, 0, 1920, 1080),
+		Precision: Precision{
+			RedBits:     8,
+			GreenBits:   8,
+			BlueBits:    8,
+			AlphaBits:   8,
+			DepthBits:   24,
+			StencilBits: 8,
+		},
+		GPUInfo: GPUInfo{
+			MaxTextureSize:     16384,
+			AlphaToCoverage:    true,
+			OcclusionQuery:     true,
+			OcclusionQueryBits: 32,
+			Name:               "Nil Desktop GL3",
+			Vendor:             "Azul3D",
+			NPOT:               true,
+			RTTFormats: RTTFormats{
+				Samples:        []int{1, 2, 4, 8, 16},
+				ColorFormats:   []TexFormat{RGB, RGBA},
+				DepthFormats:   []DSFormat{Depth16, Depth24, Depth32, Depth24AndStencil8},
+				StencilFormats: []DSFormat{Depth24AndStencil8},
+			},
+			GLMajor:               3,
+			GLMinor:               3,
+			GLExtensions:          []string{"GL_EXT_texture_compression_s3tc"},
+			GLSLMajor:             3,
+			GLSLMinor:             30,
+			GLSLMaxVaryingFloats:  64,
+			GLSLMaxVertexInputs:   4096,
+			GLSLMaxFragmentInputs: 4096,
+		},
+	}
+)
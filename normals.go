@@ -0,0 +1,250 @@
+// Copyright 2014 The Azul3D Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package gfx
+
+import (
+	"math"
+
+	"azul3d.org/lmath.v1"
+)
+
+// cornerAngle returns the angle at a of the triangle a, b, c.
+func cornerAngle(a, b, c lmath.Vec3) float64 {
+	x, y := b.Sub(a), c.Sub(a)
+	l := x.Length() * y.Length()
+	if l == 0 {
+		return 0
+	}
+	return math.Acos(math.Max(-1, math.Min(1, x.Dot(y)/l)))
+}
+
+// corner is the k-th corner of the i-th triangle of a mesh.
+type corner struct {
+	i, k int
+}
+
+// GenerateNormals generates the normals of this mesh from the positions of
+// it's vertices, replacing any existing normals.
+//
+// The normal at each corner of a triangle is the average of the normals of
+// the triangles sharing the position of that corner whose normals are within
+// the given smoothing angle (in degrees) of the triangle's own, weighted by
+// their angle at that corner. For instance:
+//  m.GenerateNormals(0)   // Flat normals.
+//  m.GenerateNormals(30)  // Smooth normals, except at edges sharper than 30°.
+//  m.GenerateNormals(180) // Smooth normals.
+//
+// Both indexed and non-indexed meshes are supported: corners are matched by
+// the position of their vertices, and the vertices of an indexed mesh are
+// duplicated (see the per-vertex data slices) where a single vertex has
+// different normals at each corner. As strips and fans share vertices between
+// triangles, each of their vertices is given the average of the normals at
+// it's corners instead.
+//
+// Only meshes whose Primitive forms triangles have normals, for others this
+// method does nothing.
+//
+// The mesh's write lock must be held for this method to operate safely.
+func (m *Mesh) GenerateNormals(smoothAngle float64) {
+	p := m.Primitive
+	if p.Vertices() != 3 {
+		return
+	}
+	n, vertex := m.elements()
+	tris := p.Count(n)
+
+	// Face normals, corner angles, and the corners at each position.
+	faces := make([]lmath.Vec3, tris)
+	angles := make([][3]float64, tris)
+	valid := make([]bool, tris)
+	at := make(map[Vec3][]corner)
+	for i := 0; i < tris; i++ {
+		var pos [3]lmath.Vec3
+		valid[i] = true
+		for k := range pos {
+			v, ok := vertex(p.Element(i, k))
+			if !ok {
+				valid[i] = false
+				break
+			}
+			pos[k] = m.Vertices[v].Vec3()
+		}
+		if !valid[i] {
+			continue
+		}
+		faces[i], _ = pos[1].Sub(pos[0]).Cross(pos[2].Sub(pos[0])).Normalized()
+		for k := range pos {
+			angles[i][k] = cornerAngle(pos[k], pos[(k+1)%3], pos[(k+2)%3])
+			v, _ := vertex(p.Element(i, k))
+			at[m.Vertices[v]] = append(at[m.Vertices[v]], corner{i, k})
+		}
+	}
+
+	// Sum the normal at each corner into it's element.
+	minCos := math.Cos(lmath.Radians(smoothAngle)) - 1e-9
+	sums := make([]lmath.Vec3, n)
+	for i := 0; i < tris; i++ {
+		if !valid[i] {
+			continue
+		}
+		for k := 0; k < 3; k++ {
+			e := p.Element(i, k)
+			v, _ := vertex(e)
+			var sum lmath.Vec3
+			for _, c := range at[m.Vertices[v]] {
+				if faces[c.i].Dot(faces[i]) >= minCos {
+					sum = sum.Add(faces[c.i].MulScalar(angles[c.i][c.k]))
+				}
+			}
+			if sum, ok := sum.Normalized(); ok {
+				sums[e] = sums[e].Add(sum)
+			}
+		}
+	}
+	normals := make([]Vec3, n)
+	for e, sum := range sums {
+		sum, _ = sum.Normalized()
+		normals[e] = ConvertVec3(sum)
+	}
+
+	if len(m.Indices) == 0 {
+		m.Normals = normals
+		m.NormalsChanged = true
+		return
+	}
+	m.split(func(e int) interface{} {
+		return normals[e]
+	})
+	m.Normals = make([]Vec3, len(m.Vertices))
+	for e, v := range m.Indices {
+		if int(v) < len(m.Normals) {
+			m.Normals[v] = normals[e]
+		}
+	}
+	m.NormalsChanged = true
+}
+
+// GenerateTangents generates the tangents of this mesh (see the Tangents field)
+// from it's normals and the texture coordinate set at the given index,
+// replacing any existing tangents. If the mesh has no normals, smooth normals
+// are generated first (see GenerateNormals).
+//
+// The tangents are compatible with those of the MikkTSpace algorithm, used by
+// most content creation tools for baking normal maps: the tangent at each
+// corner of a triangle is projected onto the plane of the vertex normal, and
+// averaged with those of the corners sharing the same position, normal,
+// texture coordinate, and bitangent sign, weighted by their angle. The
+// vertices of an indexed mesh are duplicated where a single vertex has
+// different tangents at each corner (e.g. along mirrored texture seams).
+//
+// Only meshes whose Primitive forms triangles and that have the given texture
+// coordinate set have tangents, for others this method does nothing.
+//
+// The mesh's write lock must be held for this method to operate safely.
+func (m *Mesh) GenerateTangents(set int) {
+	p := m.Primitive
+	if p.Vertices() != 3 || set < 0 || set >= len(m.TexCoords) || len(m.TexCoords[set].Slice) != len(m.Vertices) {
+		return
+	}
+	if len(m.Normals) != len(m.Vertices) {
+		m.GenerateNormals(180)
+	}
+	uvs := m.TexCoords[set].Slice
+	n, vertex := m.elements()
+	tris := p.Count(n)
+
+	// The key of a corner, corners of equal keys share a tangent.
+	type key struct {
+		pos, normal Vec3
+		uv          TexCoord
+		sign        float32
+	}
+	sums := make(map[key]lmath.Vec3)
+	keys := make([]*key, n)
+	for i := 0; i < tris; i++ {
+		var (
+			v   [3]uint32
+			pos [3]lmath.Vec3
+			ok  bool
+		)
+		for k := range v {
+			if v[k], ok = vertex(p.Element(i, k)); !ok {
+				break
+			}
+			pos[k] = m.Vertices[v[k]].Vec3()
+		}
+		if !ok {
+			continue
+		}
+		e1, e2 := pos[1].Sub(pos[0]), pos[2].Sub(pos[0])
+		du1, dv1 := float64(uvs[v[1]].U-uvs[v[0]].U), float64(uvs[v[1]].V-uvs[v[0]].V)
+		du2, dv2 := float64(uvs[v[2]].U-uvs[v[0]].U), float64(uvs[v[2]].V-uvs[v[0]].V)
+		r := du1*dv2 - du2*dv1
+		if r == 0 {
+			// Degenerate texture coordinates.
+			continue
+		}
+		t := e1.MulScalar(dv2).Sub(e2.MulScalar(dv1)).MulScalar(1 / r)
+		b := e2.MulScalar(du1).Sub(e1.MulScalar(du2)).MulScalar(1 / r)
+		for k := range v {
+			normal := m.Normals[v[k]].Vec3()
+			tk, ok := t.Sub(normal.MulScalar(normal.Dot(t))).Normalized()
+			if !ok {
+				continue
+			}
+			kk := key{m.Vertices[v[k]], m.Normals[v[k]], uvs[v[k]], 1}
+			if normal.Cross(tk).Dot(b) < 0 {
+				kk.sign = -1
+			}
+			angle := cornerAngle(pos[k], pos[(k+1)%3], pos[(k+2)%3])
+			sums[kk] = sums[kk].Add(tk.MulScalar(angle))
+			if e := p.Element(i, k); keys[e] == nil {
+				keys[e] = &kk
+			}
+		}
+	}
+
+	tangents := make([]Vec4, n)
+	for e := range tangents {
+		v, ok := vertex(e)
+		if !ok {
+			continue
+		}
+		normal := m.Normals[v].Vec3()
+		var (
+			t    lmath.Vec3
+			sign float32 = 1
+		)
+		if kk := keys[e]; kk != nil {
+			t, ok = sums[*kk].Sub(normal.MulScalar(normal.Dot(sums[*kk]))).Normalized()
+			sign = kk.sign
+		}
+		if !ok || keys[e] == nil {
+			// No texture coordinates to follow, use any perpendicular axis.
+			axis := lmath.Vec3{X: 1, Y: 0, Z: 0}
+			if math.Abs(normal.X) > 0.9 {
+				axis = lmath.Vec3{X: 0, Y: 1, Z: 0}
+			}
+			t, _ = axis.Sub(normal.MulScalar(normal.Dot(axis))).Normalized()
+		}
+		tangents[e] = Vec4{float32(t.X), float32(t.Y), float32(t.Z), sign}
+	}
+
+	if len(m.Indices) == 0 {
+		m.Tangents = tangents
+		m.TangentsChanged = true
+		return
+	}
+	m.split(func(e int) interface{} {
+		return tangents[e]
+	})
+	m.Tangents = make([]Vec4, len(m.Vertices))
+	for e, v := range m.Indices {
+		if int(v) < len(m.Tangents) {
+			m.Tangents[v] = tangents[e]
+		}
+	}
+	m.TangentsChanged = true
+}
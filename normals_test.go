@@ -0,0 +1,120 @@
+// Copyright 2014 The Azul3D Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package gfx
+
+import (
+	"math"
+	"testing"
+)
+
+func near(a, b Vec3) bool {
+	const eps = 1e-5
+	return math.Abs(float64(a.X-b.X)) < eps && math.Abs(float64(a.Y-b.Y)) < eps && math.Abs(float64(a.Z-b.Z)) < eps
+}
+
+// roof returns an indexed mesh of two quads meeting at a 90° ridge along the
+// Y axis, sharing the vertices of the ridge.
+func roof() *Mesh {
+	m := NewMesh()
+	m.Vertices = []Vec3{
+		{-1, 0, 0}, {-1, 1, 0}, // Left edge.
+		{0, 0, 1}, {0, 1, 1}, // Ridge.
+		{1, 0, 0}, {1, 1, 0}, // Right edge.
+	}
+	m.Indices = []uint32{
+		0, 2, 1, 1, 2, 3,
+		2, 4, 3, 3, 4, 5,
+	}
+	return m
+}
+
+func TestGenerateNormals(t *testing.T) {
+	s := float32(math.Sqrt(0.5))
+	left, right, up := Vec3{-s, 0, s}, Vec3{s, 0, s}, Vec3{0, 0, 1}
+
+	// Smooth normals keep the shared vertices, which point straight up along
+	// the ridge.
+	m := roof()
+	m.GenerateNormals(180)
+	if len(m.Vertices) != 6 || len(m.Normals) != 6 {
+		t.Fatalf("smooth: got %d vertices and %d normals, want 6", len(m.Vertices), len(m.Normals))
+	}
+	for v, want := range []Vec3{left, left, up, up, right, right} {
+		if !near(m.Normals[v], want) {
+			t.Errorf("smooth: Normals[%d] = %v, want %v", v, m.Normals[v], want)
+		}
+	}
+
+	// Flat normals split the ridge vertices, one copy per side.
+	m = roof()
+	m.GenerateNormals(30)
+	if len(m.Vertices) != 8 || len(m.Normals) != 8 || !m.IndicesChanged {
+		t.Fatalf("flat: got %d vertices and %d normals, want 8", len(m.Vertices), len(m.Normals))
+	}
+	for e, v := range m.Indices {
+		want := left
+		if e >= 6 {
+			want = right
+		}
+		if !near(m.Normals[v], want) {
+			t.Errorf("flat: normal of Indices[%d] = %v, want %v", e, m.Normals[v], want)
+		}
+	}
+
+	// Non-indexed meshes are matched by position.
+	m = roof()
+	for _, v := range m.Indices {
+		m.Vertices = append(m.Vertices, m.Vertices[v])
+	}
+	m.Vertices = m.Vertices[6:]
+	m.Indices = nil
+	m.GenerateNormals(180)
+	if len(m.Normals) != 12 {
+		t.Fatalf("non-indexed: got %d normals, want 12", len(m.Normals))
+	}
+	if !near(m.Normals[1], up) || !near(m.Normals[0], left) {
+		t.Errorf("non-indexed: got normals %v and %v, want %v and %v", m.Normals[0], m.Normals[1], left, up)
+	}
+}
+
+func TestGenerateTangents(t *testing.T) {
+	// A quad facing +Z whose left half mirrors the texture of it's right half,
+	// sharing the vertices along the seam.
+	m := NewMesh()
+	m.Vertices = []Vec3{
+		{-1, 0, 0}, {-1, 1, 0},
+		{0, 0, 0}, {0, 1, 0},
+		{1, 0, 0}, {1, 1, 0},
+	}
+	m.Indices = []uint32{
+		0, 2, 1, 1, 2, 3,
+		2, 4, 3, 3, 4, 5,
+	}
+	m.TexCoords = []TexCoordSet{{Slice: []TexCoord{
+		{1, 1}, {1, 0},
+		{0, 1}, {0, 0},
+		{1, 1}, {1, 0},
+	}}}
+	m.GenerateTangents(0)
+
+	if len(m.Normals) != len(m.Vertices) {
+		t.Fatalf("got %d normals for %d vertices", len(m.Normals), len(m.Vertices))
+	}
+	if len(m.Vertices) != 8 || len(m.Tangents) != 8 {
+		t.Fatalf("got %d vertices and %d tangents, want 8 (split along the seam)", len(m.Vertices), len(m.Tangents))
+	}
+	for e, v := range m.Indices {
+		// +U runs along -X on the left half and +X on the right one, while +V
+		// runs along -Y on both, so the bitangent signs differ.
+		want := Vec4{-1, 0, 0, 1}
+		if e >= 6 {
+			want = Vec4{1, 0, 0, -1}
+		}
+		got := m.Tangents[v]
+		if !near(Vec3{got.X, got.Y, got.Z}, Vec3{want.X, want.Y, want.Z}) || got.W != want.W {
+			t.Errorf("tangent of Indices[%d] = %v, want %v", e, got, want)
+		}
+	}
+}
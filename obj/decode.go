@@ -0,0 +1,362 @@
+// Copyright 2014 The Azul3D Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package obj
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"azul3d.org/gfx.v1"
+	"azul3d.org/lmath.v1"
+)
+
+// corner is a corner of a face, as indices into the positions, texture
+// coordinates, and normals of the file (-1 if absent).
+type corner struct {
+	v, vt, vn int
+}
+
+// group collects the faces of a Group for each of it's materials, until it's
+// meshes are built.
+type group struct {
+	*Group
+	faces [][][]corner // Faces of each material of the group.
+}
+
+// decoder decodes a single OBJ file, and the files that it references.
+type decoder struct {
+	open Opener
+
+	// Vertex data of the file, converted into the conventions of the gfx
+	// package.
+	positions []lmath.Vec3
+	colors    []gfx.Color
+	hasColors bool
+	texCoords []gfx.TexCoord
+	normals   []lmath.Vec3
+
+	model    *Model
+	groups   []*group
+	group    *group
+	material *Material
+	textures map[string]*gfx.Texture
+}
+
+func newDecoder(open Opener) *decoder {
+	return &decoder{
+		open:     open,
+		model:    &Model{Materials: make(map[string]*Material)},
+		textures: make(map[string]*gfx.Texture),
+	}
+}
+
+// statements calls fn with the keyword and arguments of each statement of the
+// given OBJ or MTL file data, skipping comments and blank lines and joining
+// lines continued by a backslash. Errors returned by fn are reported at the
+// line of the statement.
+func statements(r io.Reader, file string, fn func(keyword string, args []string) error) error {
+	var (
+		br    = bufio.NewReader(r)
+		line  int
+		start int
+		stmt  string
+	)
+	for {
+		s, err := br.ReadString('\n')
+		if err != nil && err != io.EOF {
+			return err
+		}
+		if s == "" && err == io.EOF && stmt == "" {
+			return nil
+		}
+		line++
+		if stmt == "" {
+			start = line
+		}
+		s = strings.TrimRight(s, "\r\n")
+		if i := strings.IndexByte(s, '#'); i >= 0 {
+			s = s[:i]
+		}
+		if strings.HasSuffix(s, `\`) {
+			stmt += s[:len(s)-1] + " "
+			if err == nil {
+				continue
+			}
+		} else {
+			stmt += s
+		}
+		fields := strings.Fields(stmt)
+		stmt = ""
+		if len(fields) > 0 {
+			if ferr := fn(fields[0], fields[1:]); ferr != nil {
+				if e, ok := ferr.(*Error); ok {
+					return e
+				}
+				return &Error{File: file, Line: start, Msg: ferr.Error()}
+			}
+		}
+		if err == io.EOF {
+			return nil
+		}
+	}
+}
+
+// floats parses the given arguments as floating-point numbers.
+func floats(args []string) ([]float64, error) {
+	f := make([]float64, len(args))
+	for i, a := range args {
+		var err error
+		if f[i], err = strconv.ParseFloat(a, 64); err != nil {
+			return nil, fmt.Errorf("invalid number %q", a)
+		}
+	}
+	return f, nil
+}
+
+// zUp converts the Y up position or direction into the Z up coordinate system.
+func zUp(x, y, z float64) lmath.Vec3 {
+	return lmath.Vec3{X: x, Y: -z, Z: y}
+}
+
+// index resolves the one-based (or if negative, relative to the end) index
+// into the n elements declared so far.
+func index(s string, n int) (int, error) {
+	i, err := strconv.Atoi(s)
+	switch {
+	case err != nil:
+		return 0, fmt.Errorf("invalid index %q", s)
+	case i > 0 && i <= n:
+		return i - 1, nil
+	case i < 0 && -i <= n:
+		return n + i, nil
+	}
+	return 0, fmt.Errorf("index %d is out of range of %d elements", i, n)
+}
+
+// parseCorner parses a face corner of the form v, v/vt, v//vn or v/vt/vn.
+func (d *decoder) parseCorner(s string) (c corner, err error) {
+	parts := strings.Split(s, "/")
+	if len(parts) > 3 {
+		return c, fmt.Errorf("invalid face corner %q", s)
+	}
+	c = corner{-1, -1, -1}
+	if c.v, err = index(parts[0], len(d.positions)); err != nil {
+		return
+	}
+	if len(parts) > 1 && parts[1] != "" {
+		if c.vt, err = index(parts[1], len(d.texCoords)); err != nil {
+			return
+		}
+	}
+	if len(parts) > 2 && parts[2] != "" {
+		if c.vn, err = index(parts[2], len(d.normals)); err != nil {
+			return
+		}
+	}
+	return
+}
+
+// setGroup makes the named group the current one, declaring it if needed.
+func (d *decoder) setGroup(name string) {
+	for _, g := range d.groups {
+		if g.Name == name {
+			d.group = g
+			return
+		}
+	}
+	d.group = &group{Group: &Group{Name: name}}
+	d.groups = append(d.groups, d.group)
+}
+
+// addFace adds the face to the current group and material.
+func (d *decoder) addFace(f []corner) {
+	if d.group == nil {
+		d.setGroup("")
+	}
+	g := d.group
+	for i, m := range g.Materials {
+		if m == d.material {
+			g.faces[i] = append(g.faces[i], f)
+			return
+		}
+	}
+	g.Materials = append(g.Materials, d.material)
+	g.faces = append(g.faces, [][]corner{f})
+}
+
+// statement decodes a single statement of the OBJ file.
+func (d *decoder) statement(keyword string, args []string) error {
+	switch keyword {
+	case "v":
+		f, err := floats(args)
+		if err != nil {
+			return err
+		}
+		if len(f) < 3 {
+			return errors.New("vertex position with less than three coordinates")
+		}
+		d.positions = append(d.positions, zUp(f[0], f[1], f[2]))
+		color := gfx.Color{R: 1, G: 1, B: 1, A: 1}
+		if len(f) == 6 {
+			// Vertex colors, a common extension.
+			color = gfx.Color{R: float32(f[3]), G: float32(f[4]), B: float32(f[5]), A: 1}
+			d.hasColors = true
+		}
+		d.colors = append(d.colors, color)
+
+	case "vt":
+		f, err := floats(args)
+		if err != nil {
+			return err
+		}
+		if len(f) < 1 {
+			return errors.New("texture coordinate without any component")
+		}
+		f = append(f, 0)
+		d.texCoords = append(d.texCoords, gfx.TexCoord{U: float32(f[0]), V: float32(1 - f[1])})
+
+	case "vn":
+		f, err := floats(args)
+		if err != nil {
+			return err
+		}
+		if len(f) < 3 {
+			return errors.New("vertex normal with less than three components")
+		}
+		d.normals = append(d.normals, zUp(f[0], f[1], f[2]))
+
+	case "f":
+		if len(args) < 3 {
+			return errors.New("face with less than three corners")
+		}
+		f := make([]corner, len(args))
+		for i, a := range args {
+			var err error
+			if f[i], err = d.parseCorner(a); err != nil {
+				return err
+			}
+		}
+		d.addFace(f)
+
+	case "g", "o":
+		d.setGroup(strings.Join(args, " "))
+
+	case "usemtl":
+		name := strings.Join(args, " ")
+		m, ok := d.model.Materials[name]
+		if !ok {
+			m = newMaterial(name)
+			d.model.Materials[name] = m
+		}
+		d.material = m
+
+	case "mtllib":
+		if d.open == nil {
+			return nil
+		}
+		for _, name := range args {
+			if err := d.loadLibrary(name); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// decode decodes the OBJ file data, and builds the meshes of the model.
+func (d *decoder) decode(r io.Reader, file string) (*Model, error) {
+	if err := statements(r, file, d.statement); err != nil {
+		return nil, err
+	}
+	for _, g := range d.groups {
+		for _, faces := range g.faces {
+			g.Meshes = append(g.Meshes, d.mesh(faces))
+		}
+		if len(g.Meshes) > 0 {
+			d.model.Groups = append(d.model.Groups, g.Group)
+		}
+	}
+	return d.model, nil
+}
+
+// mesh builds an indexed triangle mesh from the given faces. Each unique
+// combination of position, texture coordinate, and normal indices of the face
+// corners becomes a single vertex.
+func (d *decoder) mesh(faces [][]corner) *gfx.Mesh {
+	var hasTexCoords, hasNormals bool
+	for _, f := range faces {
+		for _, c := range f {
+			hasTexCoords = hasTexCoords || c.vt >= 0
+			hasNormals = hasNormals || c.vn >= 0
+		}
+	}
+
+	var (
+		m         = gfx.NewMesh()
+		texCoords []gfx.TexCoord
+		vertices  = make(map[corner]uint32)
+		extra     []lmath.Vec3 // Face normals of corners without one.
+		pos       []lmath.Vec3
+		ids       []uint32
+	)
+	for _, f := range faces {
+		pos = pos[:0]
+		for _, c := range f {
+			pos = append(pos, d.positions[c.v])
+		}
+		faceNormal := -1
+		ids = ids[:0]
+		for _, c := range f {
+			if hasNormals && c.vn < 0 {
+				// Mixed faces with and without normals, use the normal of
+				// the face itself for the latter.
+				if faceNormal < 0 {
+					n, _ := newell(pos).Normalized()
+					extra = append(extra, n)
+					faceNormal = len(d.normals) + len(extra) - 1
+				}
+				c.vn = faceNormal
+			}
+			id, ok := vertices[c]
+			if !ok {
+				id = uint32(len(m.Vertices))
+				vertices[c] = id
+				m.Vertices = append(m.Vertices, gfx.ConvertVec3(d.positions[c.v]))
+				if d.hasColors {
+					m.Colors = append(m.Colors, d.colors[c.v])
+				}
+				if hasTexCoords {
+					var uv gfx.TexCoord
+					if c.vt >= 0 {
+						uv = d.texCoords[c.vt]
+					}
+					texCoords = append(texCoords, uv)
+				}
+				if hasNormals {
+					var n lmath.Vec3
+					if c.vn < len(d.normals) {
+						n = d.normals[c.vn]
+					} else {
+						n = extra[c.vn-len(d.normals)]
+					}
+					m.Normals = append(m.Normals, gfx.ConvertVec3(n))
+				}
+			}
+			ids = append(ids, id)
+		}
+		for _, tri := range triangulate(pos) {
+			m.Indices = append(m.Indices, ids[tri[0]], ids[tri[1]], ids[tri[2]])
+		}
+	}
+	if hasTexCoords {
+		m.TexCoords = []gfx.TexCoordSet{{Slice: texCoords}}
+	}
+	m.CalculateBounds()
+	return m
+}
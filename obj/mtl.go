@@ -0,0 +1,201 @@
+// Copyright 2014 The Azul3D Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package obj
+
+import (
+	"errors"
+	"fmt"
+	"image"
+	"strconv"
+	"strings"
+
+	"azul3d.org/gfx.v1"
+)
+
+// loadLibrary loads the named MTL file, adding it's materials to the model.
+func (d *decoder) loadLibrary(name string) error {
+	f, err := d.open(name)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	var m *Material
+	return statements(f, name, func(keyword string, args []string) error {
+		keyword = strings.ToLower(keyword)
+		if keyword == "newmtl" {
+			name := strings.Join(args, " ")
+			m = newMaterial(name)
+			d.model.Materials[name] = m
+			return nil
+		}
+		if m == nil {
+			// Statement outside of any material.
+			return nil
+		}
+		switch keyword {
+		case "ka":
+			return parseColor(&m.Ambient, args)
+		case "kd":
+			return parseColor(&m.Diffuse, args)
+		case "ks":
+			return parseColor(&m.Specular, args)
+		case "ke":
+			return parseColor(&m.Emissive, args)
+		case "ns":
+			return parseFloat(&m.Shininess, args)
+		case "d":
+			if len(args) > 0 && args[0] == "-halo" {
+				args = args[1:]
+			}
+			return parseFloat(&m.Opacity, args)
+		case "tr":
+			if err := parseFloat(&m.Opacity, args); err != nil {
+				return err
+			}
+			m.Opacity = 1 - m.Opacity
+		case "map_kd":
+			return d.parseMap(&m.DiffuseMap, args)
+		case "map_ka":
+			return d.parseMap(&m.AmbientMap, args)
+		case "map_ks":
+			return d.parseMap(&m.SpecularMap, args)
+		case "map_ke":
+			return d.parseMap(&m.EmissiveMap, args)
+		case "map_d":
+			return d.parseMap(&m.AlphaMap, args)
+		case "map_bump", "bump", "norm":
+			return d.parseMap(&m.NormalMap, args)
+		}
+		return nil
+	})
+}
+
+// parseFloat parses the single number argument of a statement.
+func parseFloat(f *float64, args []string) error {
+	v, err := floats(args)
+	if err != nil {
+		return err
+	}
+	if len(v) < 1 {
+		return errors.New("missing number")
+	}
+	*f = v[0]
+	return nil
+}
+
+// parseColor parses the RGB color arguments of a statement, a single
+// component stands for all three. Spectral and CIEXYZ colors are not supported
+// and are ignored.
+func parseColor(c *gfx.Color, args []string) error {
+	if len(args) > 0 && (args[0] == "spectral" || args[0] == "xyz") {
+		return nil
+	}
+	v, err := floats(args)
+	if err != nil {
+		return err
+	}
+	switch len(v) {
+	case 1:
+		*c = gfx.Color{R: float32(v[0]), G: float32(v[0]), B: float32(v[0]), A: 1}
+	case 3:
+		*c = gfx.Color{R: float32(v[0]), G: float32(v[1]), B: float32(v[2]), A: 1}
+	default:
+		return fmt.Errorf("color with %d components", len(v))
+	}
+	return nil
+}
+
+// mapOptions are the number of arguments of each texture map option. Options
+// with a variable number of arguments take up to the given number of numbers.
+var mapOptions = map[string]int{
+	"-blendu":  1,
+	"-blendv":  1,
+	"-boost":   1,
+	"-bm":      1,
+	"-cc":      1,
+	"-clamp":   1,
+	"-imfchan": 1,
+	"-texres":  1,
+	"-type":    1,
+	"-mm":      2,
+	"-o":       -3,
+	"-s":       -3,
+	"-t":       -3,
+}
+
+// parseMap parses the arguments of a texture map statement, that is options
+// followed by the file name, and loads the texture.
+func (d *decoder) parseMap(t **gfx.Texture, args []string) error {
+	clamp := false
+	for len(args) > 0 && strings.HasPrefix(args[0], "-") {
+		opt := args[0]
+		n, ok := mapOptions[opt]
+		if !ok {
+			return fmt.Errorf("unknown texture map option %q", opt)
+		}
+		args = args[1:]
+		if n < 0 {
+			// Up to -n numbers.
+			for n = 0; n < 3 && n < len(args)-1; n++ {
+				if _, err := strconv.ParseFloat(args[n], 64); err != nil {
+					break
+				}
+			}
+		}
+		if len(args) < n {
+			return fmt.Errorf("missing arguments of texture map option %q", opt)
+		}
+		if opt == "-clamp" {
+			clamp = args[0] == "on"
+		}
+		args = args[n:]
+	}
+	if len(args) == 0 {
+		return errors.New("texture map without a file name")
+	}
+	if d.open == nil {
+		return nil
+	}
+	tex, err := d.texture(strings.Join(args, " "), clamp)
+	if err != nil {
+		return err
+	}
+	*t = tex
+	return nil
+}
+
+// texture returns the texture of the named image file, loading it if it was
+// not already.
+func (d *decoder) texture(name string, clamp bool) (*gfx.Texture, error) {
+	key := name
+	if clamp {
+		key += " (clamped)"
+	}
+	if t, ok := d.textures[key]; ok {
+		return t, nil
+	}
+	f, err := d.open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	img, _, err := image.Decode(f)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", name, err)
+	}
+
+	t := gfx.NewTexture()
+	t.Source = img
+	t.Bounds = img.Bounds()
+	t.MinFilter = gfx.LinearMipmapLinear
+	t.MagFilter = gfx.Linear
+	if clamp {
+		t.WrapU = gfx.Clamp
+		t.WrapV = gfx.Clamp
+	}
+	d.textures[key] = t
+	return t, nil
+}
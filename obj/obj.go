@@ -0,0 +1,239 @@
+// Copyright 2014 The Azul3D Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package obj loads Wavefront OBJ models, and their MTL material libraries,
+// into meshes, textures, and objects of the gfx package.
+//
+// Each group of faces of an OBJ file (declared by the g or o statements)
+// becomes a Group of meshes, one for each material that it's faces use. The
+// faces are triangulated, and as OBJ files index the positions, texture
+// coordinates and normals of each face corner separately the meshes are
+// re-indexed such that each unique combination of them becomes a single
+// vertex. For example, to draw a model with the textured shader:
+//  model, err := obj.Load("house.obj")
+//  if err != nil {
+//      log.Fatal(err)
+//  }
+//  for _, o := range model.Objects(shaders.Textured(shaders.GLSL120)) {
+//      r.Draw(r.Bounds(), o, camera)
+//  }
+//
+// Texture images are decoded with the image package, so the packages of the
+// image formats in use must be imported, e.g.:
+//  import _ "image/png"
+//
+// OBJ files use the right handed Y up coordinate system, and a bottom-left
+// texture coordinate origin. The data is converted into the conventions of the
+// gfx package: positions and normals are rotated into the right handed Z up
+// coordinate system, that is (x, y, z) becomes (x, -z, y), and the V texture
+// coordinate is flipped for the top-left origin, that is v becomes 1 - v.
+//
+// Free-form geometry, lines, and points are not supported and are ignored.
+package obj
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"azul3d.org/gfx.v1"
+)
+
+// Error describes an error in an OBJ or MTL file.
+type Error struct {
+	// The name of the file, as given to Load or as referenced by the OBJ
+	// file (or "" for the OBJ file given to Decode).
+	File string
+
+	// The line number of the error, starting at one.
+	Line int
+
+	// The message describing the error.
+	Msg string
+}
+
+// Error implements the error interface.
+func (e *Error) Error() string {
+	return fmt.Sprintf("obj: %s:%d: %s", e.File, e.Line, e.Msg)
+}
+
+// Opener opens the files that an OBJ file references, that is material
+// libraries and texture images, given their name as written in the file.
+type Opener func(name string) (io.ReadCloser, error)
+
+// DirOpener returns an Opener that opens files relative to the given
+// directory. Both forward and backward slashes are accepted as path
+// separators, as OBJ files exported on Windows commonly use the latter.
+func DirOpener(dir string) Opener {
+	return func(name string) (io.ReadCloser, error) {
+		name = filepath.FromSlash(strings.Replace(name, `\`, "/", -1))
+		if !filepath.IsAbs(name) {
+			name = filepath.Join(dir, name)
+		}
+		return os.Open(name)
+	}
+}
+
+// Material is a material of an MTL file.
+type Material struct {
+	// The name of the material.
+	Name string
+
+	// The ambient, diffuse, specular, and emissive colors of the material
+	// (Ka, Kd, Ks and Ke statements), all opaque.
+	Ambient, Diffuse, Specular, Emissive gfx.Color
+
+	// The specular exponent of the material (Ns statement).
+	Shininess float64
+
+	// The opacity of the material (d statement, or one minus the Tr
+	// statement), from zero (transparent) to one (opaque).
+	Opacity float64
+
+	// The texture maps of the material (map_Kd, map_Ka, map_Ks, map_Ke,
+	// map_d, and map_bump or norm statements), or nil if absent.
+	DiffuseMap, AmbientMap, SpecularMap, EmissiveMap, AlphaMap, NormalMap *gfx.Texture
+}
+
+// newMaterial returns a new material with the default values of the MTL
+// format.
+func newMaterial(name string) *Material {
+	return &Material{
+		Name:     name,
+		Ambient:  gfx.Color{R: 0.2, G: 0.2, B: 0.2, A: 1},
+		Diffuse:  gfx.Color{R: 0.8, G: 0.8, B: 0.8, A: 1},
+		Specular: gfx.Color{R: 1, G: 1, B: 1, A: 1},
+		Emissive: gfx.Color{R: 0, G: 0, B: 0, A: 1},
+		Opacity:  1,
+	}
+}
+
+// Group is a named group of faces of an OBJ file.
+type Group struct {
+	// The name of the group, or "" for the faces that precede any group.
+	Name string
+
+	// The meshes of the group, one for each material used by it's faces in
+	// the order that they are first used.
+	Meshes []*gfx.Mesh
+
+	// The material of each mesh, or nil for the faces that precede any
+	// usemtl statement.
+	Materials []*Material
+}
+
+// Model is a model loaded from an OBJ file.
+type Model struct {
+	// The groups of the model, in the order that they are declared. Groups
+	// without any faces are omitted.
+	Groups []*Group
+
+	// The materials of the model's material libraries, by name. Materials
+	// that are used but not declared in any library are added with the
+	// default values of the MTL format.
+	Materials map[string]*Material
+}
+
+// Names of the shader inputs and samplers that the Objects method assigns the
+// data of each material to.
+const (
+	// Colors of the material, as gfx.Vec4 values.
+	InputAmbient  = "Ambient"
+	InputDiffuse  = "Diffuse"
+	InputSpecular = "Specular"
+	InputEmissive = "Emissive"
+
+	// The specular exponent and opacity of the material, as float32 values.
+	InputShininess = "Shininess"
+	InputOpacity   = "Opacity"
+
+	// Texture maps of the material other than the diffuse one, which is
+	// bound to the first texture of the object (see gfx.TextureSampler).
+	SamplerAmbientMap  = "AmbientMap"
+	SamplerSpecularMap = "SpecularMap"
+	SamplerEmissiveMap = "EmissiveMap"
+	SamplerAlphaMap    = "AlphaMap"
+	SamplerNormalMap   = "NormalMap"
+)
+
+// Objects returns a new object for each mesh of the model, drawn with the
+// given shader. The data of each mesh's material is assigned to the object as
+// follows, except for texture maps that are nil:
+//  o.Textures = []*gfx.Texture{m.DiffuseMap}
+//  o.Samplers[SamplerNormalMap] = m.NormalMap     // Likewise for other maps.
+//  o.InputOverrides[InputDiffuse] = gfx.Vec4{...} // Likewise for colors.
+//  o.InputOverrides[InputShininess] = float32(m.Shininess)
+//  o.InputOverrides[InputOpacity] = float32(m.Opacity)
+//
+// Objects of translucent materials (whose opacity is below one, or that have
+// an alpha map) use the gfx.AlphaBlend alpha mode.
+func (m *Model) Objects(shader *gfx.Shader) []*gfx.Object {
+	var objects []*gfx.Object
+	for _, g := range m.Groups {
+		for i, mesh := range g.Meshes {
+			o := gfx.NewObject()
+			o.Shader = shader
+			o.Meshes = []*gfx.Mesh{mesh}
+			if mat := g.Materials[i]; mat != nil {
+				mat.apply(o)
+			}
+			objects = append(objects, o)
+		}
+	}
+	return objects
+}
+
+// apply assigns the data of the material to the object, see Model.Objects.
+func (m *Material) apply(o *gfx.Object) {
+	if m.DiffuseMap != nil {
+		o.Textures = []*gfx.Texture{m.DiffuseMap}
+	}
+	maps := map[string]*gfx.Texture{
+		SamplerAmbientMap:  m.AmbientMap,
+		SamplerSpecularMap: m.SpecularMap,
+		SamplerEmissiveMap: m.EmissiveMap,
+		SamplerAlphaMap:    m.AlphaMap,
+		SamplerNormalMap:   m.NormalMap,
+	}
+	for name, t := range maps {
+		if t != nil {
+			o.Samplers[name] = t
+		}
+	}
+	colors := map[string]gfx.Color{
+		InputAmbient:  m.Ambient,
+		InputDiffuse:  m.Diffuse,
+		InputSpecular: m.Specular,
+		InputEmissive: m.Emissive,
+	}
+	for name, c := range colors {
+		o.InputOverrides[name] = gfx.Vec4{X: c.R, Y: c.G, Z: c.B, W: c.A}
+	}
+	o.InputOverrides[InputShininess] = float32(m.Shininess)
+	o.InputOverrides[InputOpacity] = float32(m.Opacity)
+	if m.Opacity < 1 || m.AlphaMap != nil {
+		o.AlphaMode = gfx.AlphaBlend
+	}
+}
+
+// Load loads the model from the named OBJ file, and it's material libraries
+// and textures relative to the directory of the file (see DirOpener).
+func Load(name string) (*Model, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	d := newDecoder(DirOpener(filepath.Dir(name)))
+	return d.decode(f, name)
+}
+
+// Decode decodes the model from the given OBJ file data. Material libraries
+// and textures are opened using the given opener, if it is nil they are not
+// loaded (and used materials have the default values of the MTL format).
+func Decode(r io.Reader, open Opener) (*Model, error) {
+	return newDecoder(open).decode(r, "")
+}
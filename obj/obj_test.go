@@ -0,0 +1,203 @@
+// Copyright 2014 The Azul3D Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package obj
+
+import (
+	"bytes"
+	"image"
+	"image/png"
+	"io"
+	"io/ioutil"
+	"math"
+	"os"
+	"strings"
+	"testing"
+
+	"azul3d.org/gfx.v1"
+)
+
+const testOBJ = `# A quad, an L-shaped hexagon, and a triangle.
+mtllib scene.mtl
+v 0 0 0
+v 1 0 0
+v 1 1 0
+v 0 1 0 \
+    # Continued line.
+vt 0 0
+vt 1 0
+vt 1 1
+vt 0 1
+vn 0 0 1
+vn 0 1 0
+
+o quad
+usemtl red
+f 1/1/1 2/2/1 3/3/1 4/4/1
+
+g L shape
+usemtl textured
+v 0 0 0
+v 2 0 0
+v 2 1 0
+v 1 1 0
+v 1 2 0
+v 0 2 0
+f -6 -5 -4 -3 -2 -1
+
+g quad
+usemtl textured
+f 1//2 2//2 3//2
+`
+
+const testMTL = `newmtl red
+Kd 1 0 0
+d 0.5
+
+newmtl textured
+Kd 1
+map_Kd -clamp on -o 0.5 0.5 checker.png
+`
+
+// testFiles returns an opener of the test files, and a texture image of 4x2
+// pixels.
+func testFiles(t *testing.T) Opener {
+	var img bytes.Buffer
+	if err := png.Encode(&img, image.NewRGBA(image.Rect(0, 0, 4, 2))); err != nil {
+		t.Fatal(err)
+	}
+	files := map[string][]byte{
+		"scene.mtl":   []byte(testMTL),
+		"checker.png": img.Bytes(),
+	}
+	return func(name string) (io.ReadCloser, error) {
+		data, ok := files[name]
+		if !ok {
+			return nil, os.ErrNotExist
+		}
+		return ioutil.NopCloser(bytes.NewReader(data)), nil
+	}
+}
+
+// triangleArea returns the signed area of the triangle of the mesh with the
+// given index, along the normal n.
+func triangleArea(m *gfx.Mesh, i int, n gfx.Vec3) float64 {
+	a := m.Vertices[m.Indices[i*3]].Vec3()
+	b := m.Vertices[m.Indices[i*3+1]].Vec3()
+	c := m.Vertices[m.Indices[i*3+2]].Vec3()
+	return b.Sub(a).Cross(c.Sub(a)).Dot(n.Vec3()) / 2
+}
+
+func TestDecode(t *testing.T) {
+	model, err := Decode(strings.NewReader(testOBJ), testFiles(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(model.Groups) != 2 || model.Groups[0].Name != "quad" || model.Groups[1].Name != "L shape" {
+		t.Fatalf("got %d groups, want quad and L shape", len(model.Groups))
+	}
+	red, textured := model.Materials["red"], model.Materials["textured"]
+	if red == nil || textured == nil {
+		t.Fatal("materials not loaded")
+	}
+
+	// The quad group uses both materials.
+	quad := model.Groups[0]
+	if len(quad.Meshes) != 2 || quad.Materials[0] != red || quad.Materials[1] != textured {
+		t.Fatalf("got %d meshes in the quad group, want 2", len(quad.Meshes))
+	}
+	m := quad.Meshes[0]
+	if len(m.Vertices) != 4 || len(m.Indices) != 6 {
+		t.Fatalf("got %d vertices and %d indices, want 4 and 6", len(m.Vertices), len(m.Indices))
+	}
+	// Y up to Z up, and V flipped.
+	if m.Vertices[3] != (gfx.Vec3{0, 0, 1}) {
+		t.Errorf("got vertex %v, want {0 0 1}", m.Vertices[3])
+	}
+	if m.Normals[3] != (gfx.Vec3{0, -1, 0}) {
+		t.Errorf("got normal %v, want {0 -1 0}", m.Normals[3])
+	}
+	if uv := m.TexCoords[0].Slice[3]; uv != (gfx.TexCoord{0, 0}) {
+		t.Errorf("got texture coordinate %v, want {0 0}", uv)
+	}
+	for i := 0; i < 2; i++ {
+		if a := triangleArea(m, i, gfx.Vec3{0, -1, 0}); math.Abs(a-0.5) > 1e-6 {
+			t.Errorf("quad triangle %d has area %v along it's normal, want 0.5", i, a)
+		}
+	}
+
+	// Positions shared with different normals are distinct vertices.
+	m = quad.Meshes[1]
+	if len(m.Vertices) != 3 || m.Normals[0] != (gfx.Vec3{0, 0, 1}) || len(m.TexCoords) != 0 {
+		t.Errorf("got %d vertices with normal %v", len(m.Vertices), m.Normals[0])
+	}
+
+	// The concave hexagon is triangulated without overlap.
+	m = model.Groups[1].Meshes[0]
+	if len(m.Indices) != 12 || len(m.Normals) != 0 {
+		t.Fatalf("got %d indices and %d normals, want 12 and 0", len(m.Indices), len(m.Normals))
+	}
+	var area float64
+	for i := 0; i < 4; i++ {
+		a := triangleArea(m, i, gfx.Vec3{0, -1, 0})
+		if a <= 0 {
+			t.Errorf("hexagon triangle %d has area %v, want a positive one", i, a)
+		}
+		area += a
+	}
+	if math.Abs(area-3) > 1e-6 {
+		t.Errorf("got hexagon area %v, want 3", area)
+	}
+	if m.AABB.Max != (gfx.Vec3{2, 0, 2}.Vec3()) {
+		t.Errorf("got AABB %v", m.AABB)
+	}
+}
+
+func TestDecodeMaterials(t *testing.T) {
+	model, err := Decode(strings.NewReader(testOBJ), testFiles(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	red, textured := model.Materials["red"], model.Materials["textured"]
+	if red.Diffuse != (gfx.Color{1, 0, 0, 1}) || red.Opacity != 0.5 {
+		t.Errorf("got red diffuse %v and opacity %v", red.Diffuse, red.Opacity)
+	}
+	if textured.Diffuse != (gfx.Color{1, 1, 1, 1}) {
+		t.Errorf("got textured diffuse %v", textured.Diffuse)
+	}
+	tex := textured.DiffuseMap
+	if tex == nil || tex.Bounds.Dx() != 4 || tex.Bounds.Dy() != 2 || tex.WrapU != gfx.Clamp {
+		t.Fatalf("got texture %v", tex)
+	}
+
+	objects := model.Objects(nil)
+	if len(objects) != 3 {
+		t.Fatalf("got %d objects, want 3", len(objects))
+	}
+	if o := objects[0]; o.AlphaMode != gfx.AlphaBlend || o.InputOverrides[InputDiffuse] != (gfx.Vec4{1, 0, 0, 1}) {
+		t.Errorf("got alpha mode %v and diffuse %v", o.AlphaMode, o.InputOverrides[InputDiffuse])
+	}
+	if o := objects[1]; len(o.Textures) != 1 || o.Textures[0] != tex || o.AlphaMode != gfx.NoAlpha {
+		t.Error("textured material not applied")
+	}
+}
+
+func TestDecodeErrors(t *testing.T) {
+	tests := []struct {
+		obj  string
+		line int
+	}{
+		{"v 0 0 0\nf 1 2 3\n", 2},
+		{"v 0 0\n", 1},
+		{"v 0 0 0\n\nf 1 1\n", 3},
+		{"mtllib missing.mtl\n", 1},
+	}
+	for _, tst := range tests {
+		_, err := Decode(strings.NewReader(tst.obj), testFiles(t))
+		e, ok := err.(*Error)
+		if !ok || e.Line != tst.line {
+			t.Errorf("%q: got error %v, want one at line %d", tst.obj, err, tst.line)
+		}
+	}
+}
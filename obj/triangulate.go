@@ -0,0 +1,108 @@
+// Copyright 2014 The Azul3D Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package obj
+
+import (
+	"math"
+
+	"azul3d.org/lmath.v1"
+)
+
+// newell returns the (unnormalized) normal of the polygon, computed with
+// Newell's method such that it is robust to non-planar and concave polygons.
+func newell(poly []lmath.Vec3) lmath.Vec3 {
+	var n lmath.Vec3
+	for i, a := range poly {
+		b := poly[(i+1)%len(poly)]
+		n.X += (a.Y - b.Y) * (a.Z + b.Z)
+		n.Y += (a.Z - b.Z) * (a.X + b.X)
+		n.Z += (a.X - b.X) * (a.Y + b.Y)
+	}
+	return n
+}
+
+// cross2 returns the Z component of the cross product of the 2D vectors a-o
+// and b-o.
+func cross2(o, a, b lmath.Vec2) float64 {
+	return (a.X-o.X)*(b.Y-o.Y) - (a.Y-o.Y)*(b.X-o.X)
+}
+
+// triangulate splits the simple polygon into triangles, returned as indices
+// into it's vertices and wound like the polygon itself. Concave polygons are
+// split by ear clipping, and polygons that are too degenerate to be clipped
+// are split as a fan.
+func triangulate(poly []lmath.Vec3) [][3]int {
+	n := len(poly)
+	if n == 3 {
+		return [][3]int{{0, 1, 2}}
+	}
+	tris := make([][3]int, 0, n-2)
+
+	// Project the polygon onto the plane of the axes most perpendicular to
+	// it's normal, such that it is wound counter-clockwise.
+	normal := newell(poly)
+	ax, ay, az := math.Abs(normal.X), math.Abs(normal.Y), math.Abs(normal.Z)
+	flat := make([]lmath.Vec2, n)
+	for i, p := range poly {
+		switch {
+		case az >= ax && az >= ay:
+			flat[i] = lmath.Vec2{X: p.X, Y: p.Y}
+			if normal.Z < 0 {
+				flat[i] = lmath.Vec2{X: p.Y, Y: p.X}
+			}
+		case ax >= ay:
+			flat[i] = lmath.Vec2{X: p.Y, Y: p.Z}
+			if normal.X < 0 {
+				flat[i] = lmath.Vec2{X: p.Z, Y: p.Y}
+			}
+		default:
+			flat[i] = lmath.Vec2{X: p.Z, Y: p.X}
+			if normal.Y < 0 {
+				flat[i] = lmath.Vec2{X: p.X, Y: p.Z}
+			}
+		}
+	}
+
+	remaining := make([]int, n)
+	for i := range remaining {
+		remaining[i] = i
+	}
+	for len(remaining) > 3 {
+		ear := -1
+		for i := range remaining {
+			a := remaining[(i+len(remaining)-1)%len(remaining)]
+			b := remaining[i]
+			c := remaining[(i+1)%len(remaining)]
+			if cross2(flat[a], flat[b], flat[c]) <= 0 {
+				// Reflex (or degenerate) corner.
+				continue
+			}
+			inside := false
+			for _, p := range remaining {
+				if p == a || p == b || p == c || flat[p] == flat[a] || flat[p] == flat[b] || flat[p] == flat[c] {
+					continue
+				}
+				if cross2(flat[a], flat[b], flat[p]) >= 0 && cross2(flat[b], flat[c], flat[p]) >= 0 && cross2(flat[c], flat[a], flat[p]) >= 0 {
+					inside = true
+					break
+				}
+			}
+			if !inside {
+				ear = i
+				break
+			}
+		}
+		if ear < 0 {
+			break
+		}
+		l := len(remaining)
+		tris = append(tris, [3]int{remaining[(ear+l-1)%l], remaining[ear], remaining[(ear+1)%l]})
+		remaining = append(remaining[:ear], remaining[ear+1:]...)
+	}
+	for i := 1; i+1 < len(remaining); i++ {
+		tris = append(tris, [3]int{remaining[0], remaining[i], remaining[i+1]})
+	}
+	return tris
+}
@@ -5,6 +5,8 @@
 package gfx
 
 import (
+	"strconv"
+	"strings"
 	"sync"
 
 	"azul3d.org/lmath.v1"
@@ -67,6 +69,16 @@ type Object struct {
 	// The shader program to be used during rendering the object.
 	*Shader
 
+	// A map of names and values to use as inputs for the shader program while
+	// rendering this object. They override the inputs of the same name of the
+	// shader (see Shader.Inputs), allowing objects to share a single shader
+	// program while each having their own input values, for instance:
+	//  o.InputOverrides["Tint"] = gfx.Vec4{1, 0, 0, 1}
+	//
+	// The values must be of the same data types as those of Shader.Inputs. A
+	// nil map overrides no inputs.
+	InputOverrides map[string]interface{}
+
 	// A slice of meshes which make up the object. The order in which the
 	// meshes appear in this slice also affects the order in which they are
 	// sent to the graphics card.
@@ -77,11 +89,42 @@ type Object struct {
 	// support).
 	Meshes []*Mesh
 
+	// A slice of less detailed versions of the object's meshes, listed from
+	// the most detailed to the least, which are drawn in place of Meshes once
+	// their switch distance or screen coverage is reached. For instance:
+	//  o.LODs = []gfx.LOD{
+	//      {Meshes: medium, Distance: 50},
+	//      {Meshes: low, Distance: 200},
+	//  }
+	//
+	// The bounding box of the object is still that of Meshes, which should
+	// thus be the most detailed version. See the SelectLOD method.
+	LODs []LOD
+
+	// The hysteresis applied when switching between levels of detail, as a
+	// fraction of their thresholds (e.g. 0.1 for 10%). See the LODLevel
+	// method.
+	LODHysteresis float64
+
+	// The level of detail (an index into LODs, or -1 for Meshes) last
+	// selected for each camera that the object was drawn through. See the
+	// SelectLOD method.
+	ActiveLODs map[*Camera]int
+
 	// A slice of textures which are used to texture the meshes of this object.
 	// The order in which the textures appear in this slice is also the order
 	// in which they are sent to the graphics card.
+	//
+	// Each texture is bound to the shader's sampler named after it's index in
+	// the slice (see TextureSampler), e.g. Textures[1] to Texture1.
 	Textures []*Texture
 
+	// A map of sampler names to the textures bound to them, which is used
+	// alongside (and overrides) the Textures slice. It allows shaders to
+	// declare samplers by purpose instead of position, for instance:
+	//  o.Samplers["NormalMap"] = normalMap
+	Samplers map[string]*Texture
+
 	// CachedBounds represents the pre-calculated cached bounding box of this
 	// object. Note that the bounds are only calculated once Object.Bounds() is
 	// invoked.
@@ -161,33 +204,132 @@ func (o *Object) Compare(other *Object) bool {
 	}
 
 	// Compare textures.
+	if len(o.Textures) != len(other.Textures) {
+		return false
+	}
 	for i, tex := range o.Textures {
 		if other.Textures[i] != tex {
 			return false
 		}
 	}
+	if len(o.Samplers) != len(other.Samplers) {
+		return false
+	}
+	for name, tex := range o.Samplers {
+		if other.Samplers[name] != tex {
+			return false
+		}
+	}
 
 	// Compare state then.
 	return o.State.Compare(other.State)
 }
 
+// TextureSampler returns the name of the sampler that the texture at the given
+// index of an object's Textures slice is bound to, e.g.:
+//  TextureSampler(0) == "Texture0"
+func TextureSampler(index int) string {
+	return "Texture" + strconv.Itoa(index)
+}
+
+// Texture returns the texture bound to the named sampler: the texture in
+// o.Samplers if present, or else the texture in o.Textures whose index the
+// sampler is named after (see TextureSampler), or nil if there is none.
+//
+// The object's read lock must be held for this method to operate safely.
+func (o *Object) Texture(sampler string) *Texture {
+	if t, ok := o.Samplers[sampler]; ok {
+		return t
+	}
+	if !strings.HasPrefix(sampler, "Texture") {
+		return nil
+	}
+	i, err := strconv.Atoi(sampler[len("Texture"):])
+	if err != nil || i < 0 || i >= len(o.Textures) || TextureSampler(i) != sampler {
+		return nil
+	}
+	return o.Textures[i]
+}
+
+// Input returns the value of the named shader input to use while rendering
+// this object: the value in o.InputOverrides if present, or else the value in
+// the shader's Inputs.
+//
+// The object's read lock, and it's shader's read lock (if any), must be held
+// for this method to operate safely.
+func (o *Object) Input(name string) (v interface{}, ok bool) {
+	if v, ok = o.InputOverrides[name]; ok {
+		return
+	}
+	if o.Shader != nil {
+		v, ok = o.Shader.Inputs[name]
+	}
+	return
+}
+
+// MergeInputs stores each shader input to use while rendering this object
+// (see the Input method) into the given map, which is returned. If the map is
+// nil a new one is allocated.
+//
+// The object's read lock, and it's shader's read lock (if any), must be held
+// for this method to operate safely.
+func (o *Object) MergeInputs(m map[string]interface{}) map[string]interface{} {
+	if m == nil {
+		n := len(o.InputOverrides)
+		if o.Shader != nil {
+			n += len(o.Shader.Inputs)
+		}
+		m = make(map[string]interface{}, n)
+	}
+	if o.Shader != nil {
+		for name, v := range o.Shader.Inputs {
+			m[name] = v
+		}
+	}
+	for name, v := range o.InputOverrides {
+		m[name] = v
+	}
+	return m
+}
+
 // Copy returns a new copy of this Object. Explicitily not copied is the native
 // object. The transform is copied via it's Copy() method. The shader is only
-// copied by pointer.
+// copied by pointer. The inputs and samplers maps are copied, but not the
+// values within them. The levels of detail are copied, but not the levels
+// active for each camera.
 //
 // The object's read lock must be held for this method to operate safely.
 func (o *Object) Copy() *Object {
-	cpyCachedBounds := *o.CachedBounds
+	var cpyCachedBounds *lmath.Rect3
+	if o.CachedBounds != nil {
+		b := *o.CachedBounds
+		cpyCachedBounds = &b
+	}
 	cpy := &Object{
-		OcclusionTest: o.OcclusionTest,
-		State:         o.State,
-		Transform:     o.Transform.Copy(),
-		Shader:        o.Shader,
-		Meshes:        make([]*Mesh, len(o.Meshes)),
-		Textures:      make([]*Texture, len(o.Textures)),
-		CachedBounds:  &cpyCachedBounds,
+		OcclusionTest:  o.OcclusionTest,
+		State:          o.State,
+		Transform:      o.Transform.Copy(),
+		Shader:         o.Shader,
+		InputOverrides: make(map[string]interface{}, len(o.InputOverrides)),
+		Meshes:         make([]*Mesh, len(o.Meshes)),
+		LODs:           make([]LOD, len(o.LODs)),
+		LODHysteresis:  o.LODHysteresis,
+		ActiveLODs:     make(map[*Camera]int),
+		Textures:       make([]*Texture, len(o.Textures)),
+		Samplers:       make(map[string]*Texture, len(o.Samplers)),
+		CachedBounds:   cpyCachedBounds,
+	}
+	for name, v := range o.InputOverrides {
+		cpy.InputOverrides[name] = v
+	}
+	for name, t := range o.Samplers {
+		cpy.Samplers[name] = t
 	}
 	copy(cpy.Meshes, o.Meshes)
+	for i, lod := range o.LODs {
+		lod.Meshes = append([]*Mesh(nil), lod.Meshes...)
+		cpy.LODs[i] = lod
+	}
 	copy(cpy.Textures, o.Textures)
 	return cpy
 }
@@ -201,6 +343,9 @@ func (o *Object) Reset() {
 	o.State = DefaultState
 	o.Transform = NewTransform()
 	o.Shader = nil
+	for k := range o.InputOverrides {
+		delete(o.InputOverrides, k)
+	}
 	o.CachedBounds = nil
 
 	// Nil out each mesh pointer.
@@ -209,11 +354,25 @@ func (o *Object) Reset() {
 	}
 	o.Meshes = o.Meshes[:0]
 
+	// Nil out each level of detail.
+	for i := 0; i < len(o.LODs); i++ {
+		o.LODs[i] = LOD{}
+	}
+	o.LODs = o.LODs[:0]
+	o.LODHysteresis = 0
+	for k := range o.ActiveLODs {
+		delete(o.ActiveLODs, k)
+	}
+
 	// Nil out each texture pointer.
 	for i := 0; i < len(o.Textures); i++ {
 		o.Textures[i] = nil
 	}
 	o.Textures = o.Textures[:0]
+
+	for k := range o.Samplers {
+		delete(o.Samplers, k)
+	}
 }
 
 // Destroy destroys this object for use by other callees to NewObject. You must
@@ -232,8 +391,11 @@ func (o *Object) Destroy() {
 var objPool = sync.Pool{
 	New: func() interface{} {
 		return &Object{
-			State:     DefaultState,
-			Transform: NewTransform(),
+			State:          DefaultState,
+			Transform:      NewTransform(),
+			InputOverrides: make(map[string]interface{}),
+			Samplers:       make(map[string]*Texture),
+			ActiveLODs:     make(map[*Camera]int),
 		}
 	},
 }
@@ -0,0 +1,94 @@
+// Copyright 2014 The Azul3D Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package gfx
+
+import "testing"
+
+func TestObjectInputs(t *testing.T) {
+	s := NewShader("shared")
+	s.Inputs["Tint"] = Vec4{1, 1, 1, 1}
+	s.Inputs["Scale"] = float32(1)
+
+	a := NewObject()
+	a.Shader = s
+	b := NewObject()
+	b.Shader = s
+	b.InputOverrides["Tint"] = Vec4{1, 0, 0, 1}
+
+	if v, _ := a.Input("Tint"); v != (Vec4{1, 1, 1, 1}) {
+		t.Fatal("got shader default", v)
+	}
+	if v, _ := b.Input("Tint"); v != (Vec4{1, 0, 0, 1}) {
+		t.Fatal("got object override", v)
+	}
+	if _, ok := b.Input("Missing"); ok {
+		t.Fatal("got missing input")
+	}
+
+	m := b.MergeInputs(nil)
+	if len(m) != 2 || m["Tint"] != (Vec4{1, 0, 0, 1}) || m["Scale"] != float32(1) {
+		t.Fatal("got merged inputs", m)
+	}
+
+	// A nil map overrides no inputs.
+	c := &Object{Shader: s}
+	if v, _ := c.Input("Tint"); v != (Vec4{1, 1, 1, 1}) {
+		t.Fatal("got shader default with nil overrides", v)
+	}
+	if m := c.MergeInputs(nil); len(m) != 2 {
+		t.Fatal("got merged inputs with nil overrides", m)
+	}
+
+	cpy := b.Copy()
+	b.InputOverrides["Tint"] = Vec4{}
+	if cpy.InputOverrides["Tint"] != (Vec4{1, 0, 0, 1}) {
+		t.Fatal("inputs map not copied")
+	}
+
+	b.Reset()
+	if len(b.InputOverrides) != 0 {
+		t.Fatal("inputs not reset")
+	}
+}
+
+func TestObjectSamplers(t *testing.T) {
+	albedo, normals := NewTexture(), NewTexture()
+
+	o := NewObject()
+	o.Textures = []*Texture{albedo}
+	o.Samplers["NormalMap"] = normals
+	if o.Texture(TextureSampler(0)) != albedo {
+		t.Fatal("positional texture not bound to", TextureSampler(0))
+	}
+	if o.Texture("NormalMap") != normals {
+		t.Fatal("named texture not bound")
+	}
+	if o.Texture("Texture1") != nil || o.Texture("Texture00") != nil || o.Texture("Other") != nil {
+		t.Fatal("got texture for unbound sampler")
+	}
+
+	// Named bindings take precedence over positional ones.
+	o.Samplers["Texture0"] = normals
+	if o.Texture("Texture0") != normals {
+		t.Fatal("named binding does not take precedence")
+	}
+
+	cpy := o.Copy()
+	if !o.Compare(cpy) || !cpy.Compare(o) {
+		t.Fatal("copy does not compare equal")
+	}
+	cpy.Samplers["NormalMap"] = albedo
+	if o.Samplers["NormalMap"] != normals {
+		t.Fatal("samplers map not copied")
+	}
+	if o.Compare(cpy) {
+		t.Fatal("objects with different samplers compare equal")
+	}
+
+	o.Reset()
+	if len(o.Samplers) != 0 {
+		t.Fatal("samplers not reset")
+	}
+}
@@ -0,0 +1,129 @@
+// Copyright 2014 The Azul3D Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package gfx
+
+import "fmt"
+
+// Primitive represents the kind of geometric primitive that the vertices of a
+// mesh form, and how they are assembled into them. Triangles is the default
+// (zero value).
+type Primitive uint8
+
+// String returns a string representation of this Primitive.
+// e.g. TriangleStrip -> "TriangleStrip"
+func (p Primitive) String() string {
+	switch p {
+	case Triangles:
+		return "Triangles"
+	case TriangleStrip:
+		return "TriangleStrip"
+	case TriangleFan:
+		return "TriangleFan"
+	case Points:
+		return "Points"
+	case Lines:
+		return "Lines"
+	case LineStrip:
+		return "LineStrip"
+	}
+	return fmt.Sprintf("Primitive(%d)", p)
+}
+
+const (
+	// Each three consecutive vertices form a separate triangle.
+	Triangles Primitive = iota
+
+	// The first three vertices form a triangle, and each vertex after them
+	// forms a triangle with the two vertices preceding it.
+	TriangleStrip
+
+	// The first three vertices form a triangle, and each vertex after them
+	// forms a triangle with the vertex preceding it and the first vertex.
+	TriangleFan
+
+	// Each vertex forms a separate point, drawn State.PointSize pixels wide.
+	Points
+
+	// Each two consecutive vertices form a separate line segment, drawn
+	// State.LineWidth pixels wide.
+	Lines
+
+	// Each vertex after the first forms a line segment with the vertex
+	// preceding it, drawn State.LineWidth pixels wide.
+	LineStrip
+)
+
+// Vertices returns the number of vertices of a single primitive of this kind:
+// one for points, two for lines, and three for triangles.
+func (p Primitive) Vertices() int {
+	switch p {
+	case Points:
+		return 1
+	case Lines, LineStrip:
+		return 2
+	}
+	return 3
+}
+
+// Count returns the number of primitives that the given number of vertices (or
+// indices, for indexed meshes) form. Trailing vertices that do not form a whole
+// primitive are not counted.
+func (p Primitive) Count(n int) int {
+	switch p {
+	case TriangleStrip, TriangleFan:
+		n -= 2
+	case Points:
+	case Lines:
+		n /= 2
+	case LineStrip:
+		n--
+	default:
+		n /= 3
+	}
+	if n < 0 {
+		return 0
+	}
+	return n
+}
+
+// Element returns the index of the k-th vertex of the i-th primitive in the
+// list of vertices (or indices, for indexed meshes) that form the primitives.
+// The vertices of each triangle are returned in the same winding order, that
+// is every other triangle of a strip has it's first two vertices swapped.
+//
+// For example, the second triangle of a triangle fan is made up of the
+// elements:
+//  TriangleFan.Element(1, 0) == 0
+//  TriangleFan.Element(1, 1) == 2
+//  TriangleFan.Element(1, 2) == 3
+func (p Primitive) Element(i, k int) int {
+	switch p {
+	case TriangleStrip:
+		if i%2 == 1 && k < 2 {
+			return i + 1 - k
+		}
+		return i + k
+	case TriangleFan:
+		if k == 0 {
+			return 0
+		}
+		return i + k
+	case Points:
+		return i
+	case LineStrip:
+		return i + k
+	}
+	return i*p.Vertices() + k
+}
+
+// used returns the number of the given vertices (or indices, for indexed
+// meshes) that form whole primitives.
+func (p Primitive) used(n int) int {
+	c := p.Count(n)
+	if c == 0 {
+		return 0
+	}
+	return p.Element(c-1, p.Vertices()-1) + 1
+}
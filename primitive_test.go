@@ -0,0 +1,70 @@
+// Copyright 2014 The Azul3D Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package gfx
+
+import "testing"
+
+func TestPrimitiveElements(t *testing.T) {
+	tests := []struct {
+		p     Primitive
+		n     int
+		elems [][]int
+	}{
+		{Triangles, 7, [][]int{{0, 1, 2}, {3, 4, 5}}},
+		{TriangleStrip, 5, [][]int{{0, 1, 2}, {2, 1, 3}, {2, 3, 4}}},
+		{TriangleFan, 5, [][]int{{0, 1, 2}, {0, 2, 3}, {0, 3, 4}}},
+		{Points, 2, [][]int{{0}, {1}}},
+		{Lines, 5, [][]int{{0, 1}, {2, 3}}},
+		{LineStrip, 3, [][]int{{0, 1}, {1, 2}}},
+		{LineStrip, 1, nil},
+	}
+	for _, tst := range tests {
+		if c := tst.p.Count(tst.n); c != len(tst.elems) {
+			t.Errorf("%v.Count(%d) = %d, want %d", tst.p, tst.n, c, len(tst.elems))
+			continue
+		}
+		for i, want := range tst.elems {
+			for k, e := range want {
+				if got := tst.p.Element(i, k); got != e {
+					t.Errorf("%v.Element(%d, %d) = %d, want %d", tst.p, i, k, got, e)
+				}
+			}
+		}
+	}
+}
+
+func TestMeshPrimitive(t *testing.T) {
+	m := NewMesh()
+	m.Primitive = TriangleFan
+	m.Vertices = []Vec3{{0, 0, 0}, {1, 0, 0}, {1, 1, 0}, {0, 1, 0}, {5, 5, 5}}
+	m.GenerateBary()
+
+	// The vertices of each triangle have distinct barycentric coordinates.
+	for i := 0; i < m.Primitive.Count(len(m.Vertices)); i++ {
+		var sum Vec3
+		for k := 0; k < 3; k++ {
+			b := m.Bary[m.Primitive.Element(i, k)]
+			sum = Vec3{sum.X + b.X, sum.Y + b.Y, sum.Z + b.Z}
+		}
+		if sum != (Vec3{1, 1, 1}) {
+			t.Fatalf("triangle %d has barycentric coordinates summing to %v", i, sum)
+		}
+	}
+
+	// The last vertex of a line list does not form a whole line.
+	m.Primitive = Lines
+	m.CalculateBounds()
+	if m.AABB.Max.Z != 0 || m.AABB.Max.X != 1 {
+		t.Fatal("got bounds", m.AABB)
+	}
+
+	points := NewMesh()
+	points.Primitive = Points
+	points.Vertices = m.Vertices
+	points.GenerateBary()
+	if len(points.Bary) != 0 {
+		t.Fatal("generated barycentric coordinates for points")
+	}
+}
@@ -0,0 +1,480 @@
+// Copyright 2014 The Azul3D Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package record
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"image"
+	"image/png"
+	"io"
+
+	"azul3d.org/gfx.v1"
+	"azul3d.org/lmath.v1"
+)
+
+const (
+	// CaptureFormat is the format identifier stored in every capture.
+	CaptureFormat = "azul3d.org/gfx.v1/record"
+
+	// CaptureVersion is the version of the capture format written by this
+	// package. Captures of a newer version cannot be decoded.
+	CaptureVersion = 1
+)
+
+// MeshData is the captured data of a single mesh.
+type MeshData struct {
+	Dynamic   bool
+	AABB      lmath.Rect3
+	Primitive gfx.Primitive `json:",omitempty"`
+	Indices   []uint32
+	Vertices  []gfx.Vec3
+	Normals   []gfx.Vec3 `json:",omitempty"`
+	Tangents  []gfx.Vec4 `json:",omitempty"`
+	Colors    []gfx.Color
+	Bary      []gfx.Vec3
+	TexCoords [][]gfx.TexCoord
+	Attribs   map[string]Value
+}
+
+// TextureData is the captured data of a single texture.
+type TextureData struct {
+	// The PNG encoded image of the texture, or nil if neither it's source
+	// image nor a download of it were available.
+	PNG []byte
+
+	Bounds               image.Rectangle
+	Format               gfx.TexFormat
+	WrapU, WrapV         gfx.TexWrap
+	BorderColor          gfx.Color
+	MinFilter, MagFilter gfx.TexFilter
+}
+
+// ShaderData is the captured data of a single shader.
+type ShaderData struct {
+	Name               string
+	GLSLVert, GLSLFrag string
+
+	// The inputs of the shader at the time of capture.
+	Inputs map[string]Value
+}
+
+// CameraData is the captured data of the camera used by a draw operation.
+type CameraData struct {
+	Projection gfx.Mat4
+	Transform  lmath.Mat4
+}
+
+// DrawData is the captured data of a single draw operation. Meshes, textures,
+// and shaders are referenced by their index in the capture.
+type DrawData struct {
+	State     gfx.State
+	Transform lmath.Mat4
+
+	// Index of the shader, or -1 if the object had no shader.
+	Shader int
+
+	// The shader inputs at the time of the draw operation.
+	Inputs map[string]Value
+
+	// Indices of the meshes and textures (-1 for a nil texture).
+	Meshes, Textures []int
+
+	// Indices of the textures bound to named samplers (-1 for a nil
+	// texture).
+	Samplers map[string]int `json:",omitempty"`
+
+	// The camera, or nil if there was none.
+	Camera *CameraData
+}
+
+// CapturedOp is a single captured canvas operation, see the Op type.
+type CapturedOp struct {
+	Kind    OpKind
+	Rect    image.Rectangle
+	Color   gfx.Color
+	Depth   float64
+	Stencil int
+	Draw    *DrawData
+}
+
+// Capture is a portable and self-contained capture of recorded canvas
+// operations. It holds everything required to replay the operations onto any
+// renderer: mesh data, textures (as PNG images), shader sources and inputs,
+// render state, and transformations.
+//
+// Since renderers may clear the data of meshes, textures, and shaders once
+// they are loaded (see their KeepDataOnLoad fields), the data should be kept
+// or the capture should be created before loading. Textures whose source image
+// has been cleared are downloaded from the renderer instead, if possible.
+type Capture struct {
+	// The format identifier and version of the capture, see CaptureFormat and
+	// CaptureVersion.
+	Format  string
+	Version int
+
+	// The bounds of the canvas that the operations were recorded on.
+	Bounds image.Rectangle
+
+	Meshes   []*MeshData
+	Textures []*TextureData
+	Shaders  []*ShaderData
+	Ops      []CapturedOp
+}
+
+// capturer builds a capture, de-duplicating the resources by identity.
+type capturer struct {
+	*Capture
+	meshes   map[*gfx.Mesh]int
+	textures map[*gfx.Texture]int
+	shaders  map[*gfx.Shader]int
+}
+
+func (c *capturer) mesh(m *gfx.Mesh) (int, error) {
+	if i, ok := c.meshes[m]; ok {
+		return i, nil
+	}
+	m.RLock()
+	d := &MeshData{
+		Dynamic:   m.Dynamic,
+		AABB:      m.AABB,
+		Primitive: m.Primitive,
+		Indices:   append([]uint32(nil), m.Indices...),
+		Vertices:  append([]gfx.Vec3(nil), m.Vertices...),
+		Normals:   append([]gfx.Vec3(nil), m.Normals...),
+		Tangents:  append([]gfx.Vec4(nil), m.Tangents...),
+		Colors:    append([]gfx.Color(nil), m.Colors...),
+		Bary:      append([]gfx.Vec3(nil), m.Bary...),
+	}
+	for _, set := range m.TexCoords {
+		d.TexCoords = append(d.TexCoords, append([]gfx.TexCoord(nil), set.Slice...))
+	}
+	attribs := make(map[string]interface{}, len(m.Attribs))
+	for name, a := range m.Attribs {
+		attribs[name] = a.Data
+	}
+	var err error
+	d.Attribs, err = newValues(attribs)
+	m.RUnlock()
+	if err != nil {
+		return 0, err
+	}
+	c.meshes[m] = len(c.Meshes)
+	c.Meshes = append(c.Meshes, d)
+	return c.meshes[m], nil
+}
+
+func (c *capturer) texture(t *gfx.Texture) (int, error) {
+	if t == nil {
+		return -1, nil
+	}
+	if i, ok := c.textures[t]; ok {
+		return i, nil
+	}
+	t.RLock()
+	d := &TextureData{
+		Bounds:      t.Bounds,
+		Format:      t.Format,
+		WrapU:       t.WrapU,
+		WrapV:       t.WrapV,
+		BorderColor: t.BorderColor,
+		MinFilter:   t.MinFilter,
+		MagFilter:   t.MagFilter,
+	}
+	img := t.Source
+	native := t.NativeTexture
+	t.RUnlock()
+
+	if img == nil && native != nil {
+		complete := make(chan image.Image, 1)
+		native.Download(d.Bounds, complete)
+		img = <-complete
+	}
+	if img != nil {
+		var buf bytes.Buffer
+		if err := png.Encode(&buf, img); err != nil {
+			return 0, err
+		}
+		d.PNG = buf.Bytes()
+	}
+	c.textures[t] = len(c.Textures)
+	c.Textures = append(c.Textures, d)
+	return c.textures[t], nil
+}
+
+func (c *capturer) shader(s *gfx.Shader) (int, error) {
+	if s == nil {
+		return -1, nil
+	}
+	if i, ok := c.shaders[s]; ok {
+		return i, nil
+	}
+	s.RLock()
+	d := &ShaderData{
+		Name:     s.Name,
+		GLSLVert: string(s.GLSLVert),
+		GLSLFrag: string(s.GLSLFrag),
+	}
+	var err error
+	d.Inputs, err = newValues(s.Inputs)
+	s.RUnlock()
+	if err != nil {
+		return 0, err
+	}
+	c.shaders[s] = len(c.Shaders)
+	c.Shaders = append(c.Shaders, d)
+	return c.shaders[s], nil
+}
+
+func (c *capturer) draw(op *DrawOp) (*DrawData, error) {
+	d := &DrawData{
+		State:     op.State,
+		Transform: op.Transform,
+	}
+	var err error
+	if d.Shader, err = c.shader(op.Shader); err != nil {
+		return nil, err
+	}
+	if d.Inputs, err = newValues(op.Inputs); err != nil {
+		return nil, err
+	}
+	for _, m := range op.Meshes {
+		i, err := c.mesh(m)
+		if err != nil {
+			return nil, err
+		}
+		d.Meshes = append(d.Meshes, i)
+	}
+	for _, t := range op.Textures {
+		i, err := c.texture(t)
+		if err != nil {
+			return nil, err
+		}
+		d.Textures = append(d.Textures, i)
+	}
+	for name, t := range op.Samplers {
+		i, err := c.texture(t)
+		if err != nil {
+			return nil, err
+		}
+		if d.Samplers == nil {
+			d.Samplers = make(map[string]int, len(op.Samplers))
+		}
+		d.Samplers[name] = i
+	}
+	if op.Camera != nil {
+		d.Camera = &CameraData{
+			Projection: op.Projection,
+			Transform:  op.CameraTransform,
+		}
+	}
+	return d, nil
+}
+
+// NewCapture creates a capture of the operations recorded by the given canvas.
+// The current data of the meshes, textures, and shaders referenced by the
+// recorded draw operations is captured.
+func NewCapture(c *Canvas) (*Capture, error) {
+	cp := &capturer{
+		Capture: &Capture{
+			Format:  CaptureFormat,
+			Version: CaptureVersion,
+			Bounds:  c.Bounds(),
+		},
+		meshes:   make(map[*gfx.Mesh]int),
+		textures: make(map[*gfx.Texture]int),
+		shaders:  make(map[*gfx.Shader]int),
+	}
+	for _, op := range c.Ops() {
+		cop := CapturedOp{
+			Kind:    op.Kind,
+			Rect:    op.Rect,
+			Color:   op.Color,
+			Depth:   op.Depth,
+			Stencil: op.Stencil,
+		}
+		if op.Draw != nil {
+			d, err := cp.draw(op.Draw)
+			if err != nil {
+				return nil, err
+			}
+			cop.Draw = d
+		}
+		cp.Ops = append(cp.Ops, cop)
+	}
+	return cp.Capture, nil
+}
+
+// Encode writes the capture to w as JSON.
+func (c *Capture) Encode(w io.Writer) error {
+	return json.NewEncoder(w).Encode(c)
+}
+
+// Decode reads a capture, as previously written by Encode, from r.
+func Decode(r io.Reader) (*Capture, error) {
+	c := new(Capture)
+	if err := json.NewDecoder(r).Decode(c); err != nil {
+		return nil, err
+	}
+	if c.Format != CaptureFormat {
+		return nil, errors.New("record: not a capture")
+	}
+	if c.Version > CaptureVersion {
+		return nil, fmt.Errorf("record: unsupported capture version %d", c.Version)
+	}
+	return c, nil
+}
+
+// Replay replays the captured operations onto the given renderer. The captured
+// meshes, textures, and shaders are created and loaded using the renderer
+// before any operation is replayed. Draw operations are replayed using new
+// objects (and cameras), whose transforms are set from the captured matrices.
+//
+// The created meshes, textures, and shaders keep their data once loaded, such
+// that the replayed operations can themselves be captured again.
+func (c *Capture) Replay(r gfx.Renderer) error {
+	meshes := make([]*gfx.Mesh, len(c.Meshes))
+	meshDone := make(chan *gfx.Mesh, 1)
+	for i, d := range c.Meshes {
+		m := gfx.NewMesh()
+		m.KeepDataOnLoad = true
+		m.Dynamic = d.Dynamic
+		m.AABB = d.AABB
+		m.Primitive = d.Primitive
+		m.Indices = d.Indices
+		m.Vertices = d.Vertices
+		m.Normals = d.Normals
+		m.Tangents = d.Tangents
+		m.Colors = d.Colors
+		m.Bary = d.Bary
+		for _, s := range d.TexCoords {
+			m.TexCoords = append(m.TexCoords, gfx.TexCoordSet{Slice: s})
+		}
+		attribs, err := decodeValues(d.Attribs)
+		if err != nil {
+			return err
+		}
+		for name, a := range attribs {
+			m.Attribs[name] = gfx.VertexAttrib{Data: a}
+		}
+		r.LoadMesh(m, meshDone)
+		<-meshDone
+		meshes[i] = m
+	}
+
+	textures := make([]*gfx.Texture, len(c.Textures))
+	texDone := make(chan *gfx.Texture, 1)
+	for i, d := range c.Textures {
+		t := gfx.NewTexture()
+		t.KeepDataOnLoad = true
+		t.Bounds = d.Bounds
+		t.Format = d.Format
+		t.WrapU, t.WrapV = d.WrapU, d.WrapV
+		t.BorderColor = d.BorderColor
+		t.MinFilter, t.MagFilter = d.MinFilter, d.MagFilter
+		if d.PNG != nil {
+			img, err := png.Decode(bytes.NewReader(d.PNG))
+			if err != nil {
+				return err
+			}
+			t.Source = img
+		}
+		r.LoadTexture(t, texDone)
+		<-texDone
+		textures[i] = t
+	}
+
+	shaders := make([]*gfx.Shader, len(c.Shaders))
+	shaderDone := make(chan *gfx.Shader, 1)
+	for i, d := range c.Shaders {
+		s := gfx.NewShader(d.Name)
+		s.KeepDataOnLoad = true
+		s.GLSLVert = []byte(d.GLSLVert)
+		s.GLSLFrag = []byte(d.GLSLFrag)
+		inputs, err := decodeValues(d.Inputs)
+		if err != nil {
+			return err
+		}
+		s.Inputs = inputs
+		r.LoadShader(s, shaderDone)
+		<-shaderDone
+		shaders[i] = s
+	}
+
+	for _, op := range c.Ops {
+		switch op.Kind {
+		case Clear:
+			r.Clear(op.Rect, op.Color)
+		case ClearDepth:
+			r.ClearDepth(op.Rect, op.Depth)
+		case ClearStencil:
+			r.ClearStencil(op.Rect, op.Stencil)
+		case Render:
+			r.Render()
+		case Draw:
+			if op.Draw == nil {
+				return errors.New("record: draw operation without data")
+			}
+			if err := c.replayDraw(r, op.Rect, op.Draw, meshes, textures, shaders); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (c *Capture) replayDraw(r gfx.Renderer, rect image.Rectangle, d *DrawData, meshes []*gfx.Mesh, textures []*gfx.Texture, shaders []*gfx.Shader) error {
+	o := gfx.NewObject()
+	o.State = d.State
+	o.Transform.SetMat4(d.Transform)
+	for _, i := range d.Meshes {
+		if i < 0 || i >= len(meshes) {
+			return fmt.Errorf("record: mesh index %d out of range", i)
+		}
+		o.Meshes = append(o.Meshes, meshes[i])
+	}
+	for _, i := range d.Textures {
+		if i >= len(textures) {
+			return fmt.Errorf("record: texture index %d out of range", i)
+		}
+		var t *gfx.Texture
+		if i >= 0 {
+			t = textures[i]
+		}
+		o.Textures = append(o.Textures, t)
+	}
+	for name, i := range d.Samplers {
+		if i >= len(textures) {
+			return fmt.Errorf("record: texture index %d out of range", i)
+		}
+		var t *gfx.Texture
+		if i >= 0 {
+			t = textures[i]
+		}
+		o.Samplers[name] = t
+	}
+	if d.Shader >= len(shaders) {
+		return fmt.Errorf("record: shader index %d out of range", d.Shader)
+	}
+	if d.Shader >= 0 {
+		o.Shader = shaders[d.Shader]
+		inputs, err := decodeValues(d.Inputs)
+		if err != nil {
+			return err
+		}
+		o.InputOverrides = inputs
+	}
+
+	var cam *gfx.Camera
+	if d.Camera != nil {
+		cam = gfx.NewCamera()
+		cam.Projection = d.Camera.Projection
+		cam.Object.Transform.SetMat4(d.Camera.Transform)
+	}
+	r.Draw(rect, o, cam)
+	return nil
+}
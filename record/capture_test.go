@@ -0,0 +1,130 @@
+// Copyright 2014 The Azul3D Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package record
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"testing"
+
+	"azul3d.org/gfx.v1"
+	"azul3d.org/lmath.v1"
+)
+
+func TestCaptureRoundTrip(t *testing.T) {
+	r := New(gfx.Nil())
+
+	shader := gfx.NewShader("MyShader")
+	shader.GLSLVert = []byte("void main() {}")
+	shader.Inputs["Scale"] = float32(2)
+	shader.Inputs["Tints"] = []gfx.Vec4{{1, 0, 0, 1}, {0, 1, 0, 1}}
+
+	mesh := gfx.NewMesh()
+	mesh.Vertices = []gfx.Vec3{{0, 0, 0}, {1, 0, 0}, {0, 1, 0}}
+	mesh.TexCoords = []gfx.TexCoordSet{{Slice: []gfx.TexCoord{{0, 0}, {1, 0}, {0, 1}}}}
+	mesh.Attribs["Weight"] = gfx.VertexAttrib{Data: []float32{1, 2, 3}}
+
+	src := image.NewRGBA(image.Rect(0, 0, 2, 2))
+	src.Set(1, 1, color.RGBA{255, 0, 0, 255})
+	tex := gfx.NewTexture()
+	tex.Source = src
+	tex.Bounds = src.Bounds()
+	tex.WrapU = gfx.Clamp
+
+	o := gfx.NewObject()
+	o.Shader = shader
+	o.Meshes = []*gfx.Mesh{mesh}
+	o.Textures = []*gfx.Texture{tex}
+	o.Transform.SetPos(lmath.Vec3{1, 2, 3})
+	o.Transform.SetScale(lmath.Vec3{2, 2, 2})
+	o.DepthCmp = gfx.Greater
+
+	rect := image.Rect(0, 0, 10, 10)
+	r.Clear(rect, gfx.Color{R: 1, A: 1})
+	r.Draw(rect, o, nil)
+	r.Draw(rect, o, nil)
+	r.Render()
+
+	capture, err := NewCapture(r.Canvas)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(capture.Meshes) != 1 || len(capture.Textures) != 1 || len(capture.Shaders) != 1 {
+		t.Fatal("resources were not de-duplicated")
+	}
+
+	var buf bytes.Buffer
+	if err := capture.Encode(&buf); err != nil {
+		t.Fatal(err)
+	}
+	decoded, err := Decode(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	replay := New(gfx.Nil())
+	if err := decoded.Replay(replay); err != nil {
+		t.Fatal(err)
+	}
+	ops := replay.Ops()
+	if len(ops) != 4 || ops[0].Kind != Clear || ops[1].Kind != Draw || ops[3].Kind != Render {
+		t.Fatalf("unexpected replayed ops %v", ops)
+	}
+	d := ops[1].Draw
+	if d.ShaderName != "MyShader" || d.State != o.State {
+		t.Error("shader name or state not replayed")
+	}
+	if d.Inputs["Scale"] != float32(2) || d.Inputs["Tints"].([]gfx.Vec4)[1].Y != 1 {
+		t.Error("shader inputs not replayed", d.Inputs)
+	}
+	if !d.Transform.AlmostEquals(o.Transform.Mat4(), 1e-9) {
+		t.Error("transform not replayed", d.Transform)
+	}
+
+	m := d.Meshes[0]
+	if m.Attribs["Weight"].Data.([]float32)[2] != 3 || m.TexCoords[0].Slice[1].U != 1 {
+		t.Error("mesh data not replayed")
+	}
+	rt := d.Textures[0]
+	if rt.WrapU != gfx.Clamp || rt.Bounds != src.Bounds() {
+		t.Error("texture parameters not replayed")
+	}
+}
+
+func TestCaptureParentTransform(t *testing.T) {
+	r := New(gfx.Nil())
+
+	// The world matrix of a rotated child of a non-uniformly scaled parent
+	// is sheared.
+	parent := gfx.NewTransform()
+	parent.SetScale(lmath.Vec3{1, 3, 1})
+	o := gfx.NewObject()
+	o.Meshes = []*gfx.Mesh{gfx.NewMesh()}
+	o.Transform.SetParent(parent)
+	o.Transform.SetRot(lmath.Vec3{0, 0, 45})
+	r.Draw(r.Bounds(), o, nil)
+	r.Render()
+
+	capture, err := NewCapture(r.Canvas)
+	if err != nil {
+		t.Fatal(err)
+	}
+	replay := New(gfx.Nil())
+	if err := capture.Replay(replay); err != nil {
+		t.Fatal(err)
+	}
+	d := replay.Ops()[0].Draw
+	if want := o.Transform.Mat4(); !d.Transform.AlmostEquals(want, 1e-9) {
+		t.Fatalf("got transform %v, want %v", d.Transform, want)
+	}
+}
+
+func TestDecodeVersion(t *testing.T) {
+	buf := bytes.NewBufferString(`{"Format": "azul3d.org/gfx.v1/record", "Version": 999}`)
+	if _, err := Decode(buf); err == nil {
+		t.Fatal("expected an error for an unsupported version")
+	}
+}
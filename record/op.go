@@ -0,0 +1,186 @@
+// Copyright 2014 The Azul3D Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package record
+
+import (
+	"fmt"
+	"image"
+
+	"azul3d.org/gfx.v1"
+	"azul3d.org/lmath.v1"
+)
+
+// OpKind describes the kind of a single recorded canvas operation.
+type OpKind uint8
+
+// String returns a string representation of this operation kind.
+// e.g. Clear -> "Clear"
+func (k OpKind) String() string {
+	switch k {
+	case Clear:
+		return "Clear"
+	case ClearDepth:
+		return "ClearDepth"
+	case ClearStencil:
+		return "ClearStencil"
+	case Draw:
+		return "Draw"
+	case Render:
+		return "Render"
+	}
+	return fmt.Sprintf("OpKind(%d)", k)
+}
+
+const (
+	// Clear is a Canvas.Clear operation.
+	Clear OpKind = iota
+
+	// ClearDepth is a Canvas.ClearDepth operation.
+	ClearDepth
+
+	// ClearStencil is a Canvas.ClearStencil operation.
+	ClearStencil
+
+	// Draw is a Canvas.Draw operation.
+	Draw
+
+	// Render is a Canvas.Render operation, it marks the end of a frame.
+	Render
+)
+
+// Op represents a single recorded canvas operation. Only the fields relevant
+// to the operation's kind are set.
+type Op struct {
+	// The kind of operation.
+	Kind OpKind
+
+	// The rectangle passed to the operation, as given by the caller (i.e. not
+	// clamped to the canvas bounds).
+	Rect image.Rectangle
+
+	// The background color of a Clear operation.
+	Color gfx.Color
+
+	// The depth value of a ClearDepth operation.
+	Depth float64
+
+	// The stencil value of a ClearStencil operation.
+	Stencil int
+
+	// The snapshot of a Draw operation.
+	Draw *DrawOp
+}
+
+// DrawOp is a snapshot of a single object as it was when it was drawn.
+type DrawOp struct {
+	// The object that was drawn. Only it's identity is meaningful, as it may
+	// have been modified since the draw operation was recorded.
+	Object *gfx.Object
+
+	// The render state of the object.
+	State gfx.State
+
+	// The local-to-world transformation matrix of the object.
+	Transform lmath.Mat4
+
+	// The shader of the object, and it's name and a copy of the inputs used
+	// to draw the object (i.e. the shader's inputs merged with the object's,
+	// see Object.MergeInputs). The shader is nil if the object had none.
+	Shader     *gfx.Shader
+	ShaderName string
+	Inputs     map[string]interface{}
+
+	// The meshes drawn (those of the level of detail selected for the camera,
+	// see gfx.Object.SelectLOD), textures, and named texture bindings of the
+	// object. Only their identities are recorded, not their data.
+	Meshes   []*gfx.Mesh
+	Textures []*gfx.Texture
+	Samplers map[string]*gfx.Texture
+
+	// The camera the object was drawn with, or nil if there was none. If
+	// non-nil, it's projection and local-to-world transformation matrices are
+	// recorded as well.
+	Camera          *gfx.Camera
+	Projection      gfx.Mat4
+	CameraTransform lmath.Mat4
+}
+
+// copyInput returns a copy of the shader input value, copying the underlying
+// slice of slice types such that later modifications are not observed.
+func copyInput(v interface{}) interface{} {
+	switch t := v.(type) {
+	case []float32:
+		return append([]float32(nil), t...)
+	case []int32:
+		return append([]int32(nil), t...)
+	case []gfx.Vec2:
+		return append([]gfx.Vec2(nil), t...)
+	case []gfx.Vec3:
+		return append([]gfx.Vec3(nil), t...)
+	case []gfx.Vec4:
+		return append([]gfx.Vec4(nil), t...)
+	case []gfx.IVec2:
+		return append([]gfx.IVec2(nil), t...)
+	case []gfx.IVec3:
+		return append([]gfx.IVec3(nil), t...)
+	case []gfx.IVec4:
+		return append([]gfx.IVec4(nil), t...)
+	case []gfx.Mat3:
+		return append([]gfx.Mat3(nil), t...)
+	case []gfx.Mat4:
+		return append([]gfx.Mat4(nil), t...)
+	}
+	return v
+}
+
+// snapshot returns a snapshot of the given object and camera. It properly
+// locks the object, it's shader, and the camera.
+func snapshot(o *gfx.Object, c *gfx.Camera) *DrawOp {
+	d := &DrawOp{Object: o}
+
+	// As the selection of the level of detail is stable, the canvas being
+	// wrapped selects the same level again.
+	if c != nil {
+		c.RLock()
+		d.Meshes = append([]*gfx.Mesh(nil), o.SelectLOD(c)...)
+		c.RUnlock()
+	}
+
+	o.RLock()
+	if c == nil {
+		d.Meshes = append([]*gfx.Mesh(nil), o.Meshes...)
+	}
+	d.State = o.State
+	if o.Transform != nil {
+		d.Transform = o.Transform.Mat4()
+	} else {
+		d.Transform = lmath.Mat4Identity
+	}
+	d.Shader = o.Shader
+	d.Textures = append([]*gfx.Texture(nil), o.Textures...)
+	d.Samplers = make(map[string]*gfx.Texture, len(o.Samplers))
+	for name, t := range o.Samplers {
+		d.Samplers[name] = t
+	}
+	if d.Shader != nil {
+		d.Shader.RLock()
+		d.ShaderName = d.Shader.Name
+		d.Inputs = o.MergeInputs(nil)
+		d.Shader.RUnlock()
+		for name, v := range d.Inputs {
+			d.Inputs[name] = copyInput(v)
+		}
+	}
+	o.RUnlock()
+
+	if c != nil {
+		d.Camera = c
+		c.RLock()
+		d.Projection = c.Projection
+		d.CameraTransform = c.Object.Transform.Mat4()
+		c.RUnlock()
+	}
+	return d
+}
@@ -0,0 +1,165 @@
+// Copyright 2014 The Azul3D Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package record implements a gfx.Canvas and gfx.Renderer wrapper that records
+// every operation submitted to it.
+//
+// The recorded operations describe what would have been drawn without
+// depending on any pixels, which makes them well suited for tests:
+//  r := record.New(gfx.Nil())
+//  ... draw the scene using r ...
+//  for _, op := range r.Ops() {
+//      if op.Kind == record.Draw {
+//          fmt.Println(op.Draw.ShaderName, op.Draw.State.AlphaMode)
+//      }
+//  }
+//
+// Recorded operations can also be saved to disk as a portable capture (see the
+// Capture type) and later replayed onto any renderer, which is useful for
+// attaching reproductions to bug reports or comparing frames between versions.
+package record
+
+import (
+	"image"
+	"sync"
+
+	"azul3d.org/clock.v1"
+	"azul3d.org/gfx.v1"
+)
+
+// Canvas wraps a gfx.Canvas and records every Clear, ClearDepth, ClearStencil,
+// Draw, and Render operation in the order they were submitted. Each operation
+// is passed on to the wrapped canvas after being recorded.
+type Canvas struct {
+	gfx.Canvas
+
+	access sync.Mutex
+	ops    []Op
+}
+
+func (c *Canvas) record(op Op) {
+	c.access.Lock()
+	c.ops = append(c.ops, op)
+	c.access.Unlock()
+}
+
+// Ops returns a copy of the list of operations recorded since the canvas was
+// created or Reset was last called.
+func (c *Canvas) Ops() []Op {
+	c.access.Lock()
+	ops := make([]Op, len(c.ops))
+	copy(ops, c.ops)
+	c.access.Unlock()
+	return ops
+}
+
+// Reset clears the list of recorded operations.
+func (c *Canvas) Reset() {
+	c.access.Lock()
+	c.ops = c.ops[:0]
+	c.access.Unlock()
+}
+
+// Implements gfx.Canvas interface.
+func (c *Canvas) Clear(r image.Rectangle, bg gfx.Color) {
+	c.record(Op{Kind: Clear, Rect: r, Color: bg})
+	c.Canvas.Clear(r, bg)
+}
+
+// Implements gfx.Canvas interface.
+func (c *Canvas) ClearDepth(r image.Rectangle, depth float64) {
+	c.record(Op{Kind: ClearDepth, Rect: r, Depth: depth})
+	c.Canvas.ClearDepth(r, depth)
+}
+
+// Implements gfx.Canvas interface.
+func (c *Canvas) ClearStencil(r image.Rectangle, stencil int) {
+	c.record(Op{Kind: ClearStencil, Rect: r, Stencil: stencil})
+	c.Canvas.ClearStencil(r, stencil)
+}
+
+// Implements gfx.Canvas interface.
+func (c *Canvas) Draw(r image.Rectangle, o *gfx.Object, cam *gfx.Camera) {
+	c.record(Op{Kind: Draw, Rect: r, Draw: snapshot(o, cam)})
+	c.Canvas.Draw(r, o, cam)
+}
+
+// NotifyResize implements the gfx.ResizeNotifier interface by forwarding to
+// the wrapped canvas. If the wrapped canvas does not implement it, no
+// notifications are ever sent.
+func (c *Canvas) NotifyResize(ch chan image.Rectangle) {
+	if n, ok := c.Canvas.(gfx.ResizeNotifier); ok {
+		n.NotifyResize(ch)
+	}
+}
+
+// StopNotifyResize implements the gfx.ResizeNotifier interface by forwarding to
+// the wrapped canvas.
+func (c *Canvas) StopNotifyResize(ch chan image.Rectangle) {
+	if n, ok := c.Canvas.(gfx.ResizeNotifier); ok {
+		n.StopNotifyResize(ch)
+	}
+}
+
+// Implements gfx.Canvas interface.
+func (c *Canvas) Render() {
+	c.record(Op{Kind: Render})
+	c.Canvas.Render()
+}
+
+// NewCanvas returns a new recording canvas that wraps the given one.
+func NewCanvas(c gfx.Canvas) *Canvas {
+	return &Canvas{Canvas: c}
+}
+
+// Renderer wraps a gfx.Renderer and records the operations submitted to it's
+// canvas (see the Canvas type).
+type Renderer struct {
+	*Canvas
+	r gfx.Renderer
+}
+
+// Implements gfx.Renderer interface.
+func (r *Renderer) Clock() *clock.Clock {
+	return r.r.Clock()
+}
+
+// Implements gfx.Renderer interface.
+func (r *Renderer) GPUInfo() gfx.GPUInfo {
+	return r.r.GPUInfo()
+}
+
+// Implements gfx.Renderer interface.
+func (r *Renderer) LoadMesh(m *gfx.Mesh, done chan *gfx.Mesh) {
+	r.r.LoadMesh(m, done)
+}
+
+// Implements gfx.Renderer interface.
+func (r *Renderer) LoadTexture(t *gfx.Texture, done chan *gfx.Texture) {
+	r.r.LoadTexture(t, done)
+}
+
+// Implements gfx.Renderer interface.
+func (r *Renderer) LoadShader(s *gfx.Shader, done chan *gfx.Shader) {
+	r.r.LoadShader(s, done)
+}
+
+// RenderToTexture implements the gfx.Renderer interface. The returned canvas,
+// if not nil, is a *Canvas that records it's own operations separately from
+// this renderer's.
+func (r *Renderer) RenderToTexture(cfg gfx.RTTConfig) gfx.Canvas {
+	c := r.r.RenderToTexture(cfg)
+	if c == nil {
+		return nil
+	}
+	return NewCanvas(c)
+}
+
+// New returns a new recording renderer that wraps the given one.
+func New(r gfx.Renderer) *Renderer {
+	return &Renderer{
+		Canvas: NewCanvas(r),
+		r:      r,
+	}
+}
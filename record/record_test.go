@@ -0,0 +1,110 @@
+// Copyright 2014 The Azul3D Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package record
+
+import (
+	"image"
+	"testing"
+
+	"azul3d.org/gfx.v1"
+	"azul3d.org/lmath.v1"
+)
+
+func TestRecord(t *testing.T) {
+	r := New(gfx.Nil())
+
+	shader := gfx.NewShader("MyShader")
+	shader.Inputs["Tint"] = []gfx.Vec4{{1, 0, 0, 1}}
+	shader.Inputs["Scale"] = float32(1)
+
+	o := gfx.NewObject()
+	o.Shader = shader
+	o.InputOverrides["Scale"] = float32(2)
+	o.Meshes = []*gfx.Mesh{gfx.NewMesh()}
+	low := gfx.NewMesh()
+	o.LODs = []gfx.LOD{{Meshes: []*gfx.Mesh{low}, Distance: 1}}
+	o.Transform.SetPos(lmath.Vec3{1, 2, 3})
+	o.AlphaMode = gfx.AlphaBlend
+
+	cam := gfx.NewCamera()
+	cam.SetOrtho(r.Bounds(), 0.1, 100)
+
+	rect := image.Rect(0, 0, 10, 10)
+	r.Clear(rect, gfx.Color{R: 1, A: 1})
+	r.ClearDepth(rect, 1.0)
+	r.ClearStencil(rect, 3)
+	r.Draw(rect, o, cam)
+	r.Render()
+
+	// Later modifications must not be observed by the recording.
+	shader.Inputs["Tint"].([]gfx.Vec4)[0].X = 0
+	o.Transform.SetPos(lmath.Vec3{})
+
+	ops := r.Ops()
+	kinds := []OpKind{Clear, ClearDepth, ClearStencil, Draw, Render}
+	if len(ops) != len(kinds) {
+		t.Fatalf("got %d ops, want %d", len(ops), len(kinds))
+	}
+	for i, k := range kinds {
+		if ops[i].Kind != k {
+			t.Errorf("op %d: got %v, want %v", i, ops[i].Kind, k)
+		}
+	}
+	if ops[2].Stencil != 3 {
+		t.Errorf("got stencil %d, want 3", ops[2].Stencil)
+	}
+
+	d := ops[3].Draw
+	if d.Object != o || d.Camera != cam || ops[3].Rect != rect {
+		t.Error("draw identities/rectangle not recorded")
+	}
+	if len(d.Meshes) != 1 || d.Meshes[0] != low {
+		t.Error("drawn level of detail not recorded")
+	}
+	if d.ShaderName != "MyShader" || d.State.AlphaMode != gfx.AlphaBlend {
+		t.Error("draw shader name or state not recorded")
+	}
+	if d.Inputs["Tint"].([]gfx.Vec4)[0].X != 1 {
+		t.Error("shader inputs were not copied")
+	}
+	if d.Inputs["Scale"] != float32(2) {
+		t.Error("object inputs were not merged", d.Inputs)
+	}
+	if !d.Transform.Translation().Equals(lmath.Vec3{1, 2, 3}) {
+		t.Error("transform not recorded", d.Transform)
+	}
+	if d.Projection != cam.Projection {
+		t.Error("camera projection not recorded")
+	}
+
+	r.Reset()
+	if len(r.Ops()) != 0 {
+		t.Error("Reset did not clear the recorded ops")
+	}
+}
+
+func TestNotifyResize(t *testing.T) {
+	wrapped := gfx.Nil()
+	r := New(wrapped)
+	ch := make(chan image.Rectangle, 1)
+	r.NotifyResize(ch)
+	wrapped.(gfx.Resizer).Resize(image.Rect(0, 0, 320, 240))
+	select {
+	case b := <-ch:
+		if b != image.Rect(0, 0, 320, 240) {
+			t.Fatal("got bounds", b)
+		}
+	default:
+		t.Fatal("resize notification not forwarded")
+	}
+
+	r.StopNotifyResize(ch)
+	wrapped.(gfx.Resizer).Resize(image.Rect(0, 0, 640, 480))
+	select {
+	case b := <-ch:
+		t.Fatal("notification after StopNotifyResize", b)
+	default:
+	}
+}
@@ -0,0 +1,86 @@
+// Copyright 2014 The Azul3D Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package record
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+
+	"azul3d.org/gfx.v1"
+)
+
+// Value is a dynamically typed value (i.e. a shader input or vertex attribute)
+// in a form that can be encoded and decoded without loss of it's type.
+type Value struct {
+	// The Go type name of the value, e.g. "[]gfx.Vec3".
+	Type string
+
+	// The JSON encoded value.
+	Data json.RawMessage
+}
+
+// valueTypes maps the name of each type that may be used as a shader input or
+// vertex attribute (see gfx.InputTypes and gfx.AttribTypes) to it's Go type.
+var valueTypes = func() map[string]reflect.Type {
+	m := make(map[string]reflect.Type)
+	for _, t := range append(gfx.InputTypes(), gfx.AttribTypes()...) {
+		m[t.String()] = t
+	}
+	return m
+}()
+
+// typeName returns the name of the value's type, or an empty string if the
+// type is not one that may be used as a shader input or vertex attribute.
+func typeName(v interface{}) string {
+	if !gfx.ValidInput(v) && !gfx.ValidAttrib(v) {
+		return ""
+	}
+	return reflect.TypeOf(v).String()
+}
+
+// newValues encodes each value of the map whose type is supported. Values of
+// unsupported types are ignored, just as renderers ignore them.
+func newValues(m map[string]interface{}) (map[string]Value, error) {
+	values := make(map[string]Value, len(m))
+	for name, v := range m {
+		t := typeName(v)
+		if t == "" {
+			continue
+		}
+		data, err := json.Marshal(v)
+		if err != nil {
+			return nil, fmt.Errorf("record: encoding %q: %v", name, err)
+		}
+		values[name] = Value{Type: t, Data: data}
+	}
+	return values, nil
+}
+
+// Interface decodes and returns the value.
+func (v Value) Interface() (interface{}, error) {
+	t, ok := valueTypes[v.Type]
+	if !ok {
+		return nil, fmt.Errorf("record: unknown value type %q", v.Type)
+	}
+	dst := reflect.New(t)
+	if err := json.Unmarshal(v.Data, dst.Interface()); err != nil {
+		return nil, err
+	}
+	return dst.Elem().Interface(), nil
+}
+
+// decodeValues decodes each value of the map.
+func decodeValues(values map[string]Value) (map[string]interface{}, error) {
+	m := make(map[string]interface{}, len(values))
+	for name, v := range values {
+		i, err := v.Interface()
+		if err != nil {
+			return nil, err
+		}
+		m[name] = i
+	}
+	return m, nil
+}
@@ -91,6 +91,16 @@ type Canvas interface {
 	// given camera object (taking into account the camera object's
 	// transformation and projection matrices).
 	//
+	// The vertices (or indices) of each mesh are assembled into the kind of
+	// primitive given by it's Primitive field. Points and lines are drawn
+	// using the PointSize and LineWidth of the object's state, and are never
+	// culled (see State.FaceCulling).
+	//
+	// The shader program is fed the built-in inputs (see BuiltinInputs and
+	// NewBuiltins), overridden by the shader's inputs, overridden by the
+	// object's inputs of the same name (see Object.InputOverrides and the
+	// Object.Input method).
+	//
 	// If the GPU supports occlusion queries (see GPUInfo.OcclusionQuery) and
 	// o.OcclusionTest is set to true then at some point in the future (or when
 	// QueryWait() is called) the native object will record the number of
@@ -98,6 +108,10 @@ type Canvas interface {
 	// SampleCount() is called it will return the number of samples last drawn
 	// by the object.
 	//
+	// If the object has levels of detail (see Object.LODs), the meshes of the
+	// level selected for the camera are drawn in place of o.Meshes (see the
+	// Object.SelectLOD method).
+	//
 	// The canvas must invoke o.Bounds() some time before clearing data slices
 	// of loaded meshes, such that the object has a chance to determine it's
 	// bounding box.
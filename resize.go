@@ -0,0 +1,161 @@
+// Copyright 2014 The Azul3D Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package gfx
+
+import (
+	"image"
+	"sync"
+)
+
+// ResizeNotifier is implemented by canvases that can notify the client when
+// their bounds change (e.g. when a user resizes the window). Canvases whose
+// bounds never change need not implement it, so clients should check for it
+// with a type assertion:
+//  if n, ok := canvas.(gfx.ResizeNotifier); ok {
+//      n.NotifyResize(ch)
+//  }
+type ResizeNotifier interface {
+	// NotifyResize requests that the canvas send it's new bounds over the
+	// given channel each time they change.
+	//
+	// The canvas will not block sending over the channel, so a buffered
+	// channel should be used. If the channel is full, the oldest pending
+	// bounds are discarded such that the most recent bounds are always
+	// delivered.
+	NotifyResize(ch chan image.Rectangle)
+
+	// StopNotifyResize stops sending bounds over the given channel, which was
+	// previously passed to NotifyResize.
+	StopNotifyResize(ch chan image.Rectangle)
+}
+
+// ResizeBroadcaster implements the ResizeNotifier interface. It may be
+// embedded into canvas implementations, which then simply invoke it's Resized
+// method whenever their bounds change.
+//
+// The zero value is ready for use. All methods are safe to call from multiple
+// goroutines.
+type ResizeBroadcaster struct {
+	access sync.Mutex
+	chans  []chan image.Rectangle
+}
+
+// NotifyResize implements the ResizeNotifier interface.
+func (n *ResizeBroadcaster) NotifyResize(ch chan image.Rectangle) {
+	n.access.Lock()
+	n.chans = append(n.chans, ch)
+	n.access.Unlock()
+}
+
+// StopNotifyResize implements the ResizeNotifier interface.
+func (n *ResizeBroadcaster) StopNotifyResize(ch chan image.Rectangle) {
+	n.access.Lock()
+	for i, c := range n.chans {
+		if c == ch {
+			n.chans = append(n.chans[:i], n.chans[i+1:]...)
+			break
+		}
+	}
+	n.access.Unlock()
+}
+
+// Resized sends the new bounds over each channel passed to NotifyResize. It
+// never blocks: if a channel is full, the oldest pending bounds in it are
+// discarded so that the most recent bounds are always delivered.
+func (n *ResizeBroadcaster) Resized(bounds image.Rectangle) {
+	n.access.Lock()
+	for _, ch := range n.chans {
+		select {
+		case ch <- bounds:
+			continue
+		default:
+		}
+
+		// Discard the oldest pending bounds and try again.
+		select {
+		case <-ch:
+		default:
+		}
+		select {
+		case ch <- bounds:
+		default:
+		}
+	}
+	n.access.Unlock()
+}
+
+// Resizer is implemented by canvases whose bounds can be changed directly by
+// the client, such as the renderer returned by Nil (which can be used to
+// simulate window resizes):
+//  r := gfx.Nil()
+//  r.(gfx.Resizer).Resize(image.Rect(0, 0, 800, 600))
+type Resizer interface {
+	// Resize changes the bounds of the canvas. If they differ from the old
+	// bounds, a notification is sent to each channel passed to the canvas's
+	// NotifyResize method (see ResizeNotifier).
+	Resize(bounds image.Rectangle)
+}
+
+// onResize invokes f with the current bounds of the canvas, and then in a
+// separate goroutine with the new bounds each time the canvas is resized,
+// until the returned stop function is called. If the canvas does not
+// implement ResizeNotifier f is only invoked once.
+func onResize(c Canvas, f func(bounds image.Rectangle)) (stop func()) {
+	f(c.Bounds())
+	n, ok := c.(ResizeNotifier)
+	if !ok {
+		return func() {}
+	}
+	ch := make(chan image.Rectangle, 1)
+	quit := make(chan struct{})
+	n.NotifyResize(ch)
+	go func() {
+		for {
+			select {
+			case bounds := <-ch:
+				f(bounds)
+			case <-quit:
+				return
+			}
+		}
+	}()
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			n.StopNotifyResize(ch)
+			close(quit)
+		})
+	}
+}
+
+// PerspOnResize sets the camera's projection to a perspective one (see the
+// Camera.SetPersp method) using the current bounds of the canvas, and (if the
+// canvas implements ResizeNotifier) updates it each time the canvas is
+// resized, until the returned stop function is called. For example:
+//  stop := gfx.PerspOnResize(renderer, camera, 75, 0.1, 1000)
+//  defer stop()
+//
+// The camera is properly write-locked each time it is updated.
+func PerspOnResize(c Canvas, cam *Camera, fov, near, far float64) (stop func()) {
+	return onResize(c, func(bounds image.Rectangle) {
+		cam.Lock()
+		cam.SetPersp(bounds, fov, near, far)
+		cam.Unlock()
+	})
+}
+
+// OrthoOnResize sets the camera's projection to an orthographic one (see the
+// Camera.SetOrtho method) using the current bounds of the canvas, and (if the
+// canvas implements ResizeNotifier) updates it each time the canvas is
+// resized, until the returned stop function is called.
+//
+// The camera is properly write-locked each time it is updated.
+func OrthoOnResize(c Canvas, cam *Camera, near, far float64) (stop func()) {
+	return onResize(c, func(bounds image.Rectangle) {
+		cam.Lock()
+		cam.SetOrtho(bounds, near, far)
+		cam.Unlock()
+	})
+}
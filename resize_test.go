@@ -0,0 +1,67 @@
+// Copyright 2014 The Azul3D Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package gfx
+
+import (
+	"image"
+	"testing"
+	"time"
+)
+
+func TestNilResize(t *testing.T) {
+	r := Nil()
+	ch := make(chan image.Rectangle, 1)
+	r.(ResizeNotifier).NotifyResize(ch)
+
+	// Resizing to the same bounds sends no notification.
+	r.(Resizer).Resize(r.Bounds())
+	select {
+	case b := <-ch:
+		t.Fatal("unexpected notification", b)
+	default:
+	}
+
+	// Only the most recent bounds are pending.
+	r.(Resizer).Resize(image.Rect(0, 0, 800, 600))
+	r.(Resizer).Resize(image.Rect(0, 0, 1024, 768))
+	if b := <-ch; b != image.Rect(0, 0, 1024, 768) {
+		t.Fatal("got bounds", b)
+	}
+	if r.Bounds() != image.Rect(0, 0, 1024, 768) {
+		t.Fatal("got bounds", r.Bounds())
+	}
+
+	r.(ResizeNotifier).StopNotifyResize(ch)
+	r.(Resizer).Resize(image.Rect(0, 0, 640, 480))
+	select {
+	case b := <-ch:
+		t.Fatal("notification after StopNotifyResize", b)
+	default:
+	}
+}
+
+func TestPerspOnResize(t *testing.T) {
+	r := Nil()
+	cam := NewCamera()
+	stop := PerspOnResize(r, cam, 75, 0.1, 1000)
+	defer stop()
+
+	projection := func() Mat4 {
+		cam.RLock()
+		defer cam.RUnlock()
+		return cam.Projection
+	}
+	wide := NewCamera()
+	wide.SetPersp(image.Rect(0, 0, 1600, 600), 75, 0.1, 1000)
+
+	before := projection()
+	r.(Resizer).Resize(image.Rect(0, 0, 1600, 600))
+	for i := 0; projection() != wide.Projection; i++ {
+		if i == 100 {
+			t.Fatal("projection not updated, got", projection(), "before", before)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
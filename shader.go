@@ -46,12 +46,29 @@ type Shader struct {
 	//  bool
 	//  float32
 	//  []float32
+	//  int32
+	//  []int32
+	//  gfx.Vec2
+	//  []gfx.Vec2
 	//  gfx.Vec3
 	//  []gfx.Vec3
 	//  gfx.Vec4
 	//  []gfx.Vec4
+	//  gfx.IVec2
+	//  []gfx.IVec2
+	//  gfx.IVec3
+	//  []gfx.IVec3
+	//  gfx.IVec4
+	//  []gfx.IVec4
+	//  gfx.Mat3
+	//  []gfx.Mat3
 	//  gfx.Mat4
 	//  []gfx.Mat4
+	//
+	// Sampler uniforms are fed by the object's textures, but an int32 input
+	// may be used to select the texture unit of a sampler explicitly.
+	//
+	// The fields of a Go struct may be stored as inputs using an InputBinding.
 	Inputs map[string]interface{}
 
 	// The error log from compiling the shader program, if any. Only set once
@@ -0,0 +1,259 @@
+// Copyright 2014 The Azul3D Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package shaders provides ready-made shaders for common materials.
+//
+// Each function returns a new shader, written against the GLSL version that
+// is given to it:
+//  o.Shader = shaders.Textured(shaders.GLSL120)
+//  o.Textures = []*gfx.Texture{albedo}
+//
+// All of the shaders follow the naming convention of the gfx package: vertex
+// data is read from the built-in vertex attributes (see gfx.AttribVertex and
+// gfx.TexCoordAttrib), vertices are transformed by the built-in MVP input
+// (see gfx.InputMVP), and textures are read from the samplers named after
+// their index in the object's Textures slice (see gfx.TextureSampler). Inputs
+// specific to a shader are stored in it's Inputs map with default values, and
+// may be overridden per object (see gfx.Object.InputOverrides).
+//
+// Colors are expected to be in premultiplied alpha form, like those of the
+// gfx package.
+package shaders
+
+import (
+	"fmt"
+
+	"azul3d.org/gfx.v1"
+)
+
+// Version is a GLSL version that the shaders may be written against.
+type Version uint8
+
+const (
+	// GLSL 1.20, as supported by OpenGL 2.1 and above.
+	GLSL120 Version = iota
+
+	// GLSL ES 1.00, as supported by OpenGL ES 2.0 and WebGL 1.
+	GLSLES100
+)
+
+// String returns a string representation of this version, e.g. "GLSL 1.20".
+func (v Version) String() string {
+	switch v {
+	case GLSL120:
+		return "GLSL 1.20"
+	case GLSLES100:
+		return "GLSL ES 1.00"
+	}
+	return fmt.Sprintf("Version(%d)", uint8(v))
+}
+
+// The names of the shader inputs specific to the shaders of this package.
+const (
+	// The float32 alpha value below which fragments are discarded by the
+	// Cutout shader, defaulting to 0.5.
+	InputAlphaCutoff = "AlphaCutoff"
+
+	// The gfx.Color (as a gfx.Vec4) of the edges drawn by the Wireframe
+	// shader, defaulting to opaque white.
+	InputWireColor = "WireColor"
+
+	// The float32 width in pixels of the edges drawn by the Wireframe shader,
+	// defaulting to 1.
+	InputWireWidth = "WireWidth"
+
+	// The gfx.Color (as a gfx.Vec4) of the interior of the triangles drawn by
+	// the Wireframe shader, defaulting to fully transparent.
+	InputFillColor = "FillColor"
+)
+
+// header returns the directives that begin a source of the given version and
+// stage, followed by the given extensions (ignored for GLSL 1.20, where they
+// are part of the core language).
+func header(v Version, frag bool, extensions ...string) string {
+	switch v {
+	case GLSL120:
+		return "#version 120\n"
+	case GLSLES100:
+		h := "#version 100\n"
+		for _, ext := range extensions {
+			h += "#extension " + ext + " : enable\n"
+		}
+		if frag {
+			h += "precision mediump float;\n"
+		}
+		return h
+	}
+	panic(fmt.Sprintf("shaders: invalid version %v", v))
+}
+
+// newShader returns a new shader with the given name and sources, written
+// against the given version.
+func newShader(name string, v Version, vert, frag string, extensions ...string) *gfx.Shader {
+	s := gfx.NewShader(fmt.Sprintf("%s (%v)", name, v))
+	s.GLSLVert = []byte(header(v, false) + vert)
+	s.GLSLFrag = []byte(header(v, true, extensions...) + frag)
+	return s
+}
+
+const vertexColorVert = `
+attribute vec3 Vertex;
+attribute vec4 Color;
+
+uniform mat4 MVP;
+
+varying vec4 color;
+
+void main() {
+	color = Color;
+	gl_Position = vec4(Vertex, 1.0) * MVP;
+}
+`
+
+const vertexColorFrag = `
+varying vec4 color;
+
+void main() {
+	gl_FragColor = color;
+}
+`
+
+// VertexColor returns a new unlit shader that colors each mesh by it's vertex
+// colors (see gfx.Mesh.Colors).
+func VertexColor(v Version) *gfx.Shader {
+	return newShader("VertexColor", v, vertexColorVert, vertexColorFrag)
+}
+
+const texturedVert = `
+attribute vec3 Vertex;
+attribute vec2 TexCoord0;
+
+uniform mat4 MVP;
+
+varying vec2 texCoord;
+
+void main() {
+	texCoord = TexCoord0;
+	gl_Position = vec4(Vertex, 1.0) * MVP;
+}
+`
+
+const texturedFrag = `
+uniform sampler2D Texture0;
+
+varying vec2 texCoord;
+
+void main() {
+	gl_FragColor = texture2D(Texture0, texCoord);
+}
+`
+
+// Textured returns a new unlit shader that textures each mesh with the first
+// texture of the object, using the mesh's first texture coordinate set.
+func Textured(v Version) *gfx.Shader {
+	return newShader("Textured", v, texturedVert, texturedFrag)
+}
+
+const texturedColorVert = `
+attribute vec3 Vertex;
+attribute vec4 Color;
+attribute vec2 TexCoord0;
+
+uniform mat4 MVP;
+
+varying vec4 color;
+varying vec2 texCoord;
+
+void main() {
+	color = Color;
+	texCoord = TexCoord0;
+	gl_Position = vec4(Vertex, 1.0) * MVP;
+}
+`
+
+const texturedColorFrag = `
+uniform sampler2D Texture0;
+
+varying vec4 color;
+varying vec2 texCoord;
+
+void main() {
+	gl_FragColor = texture2D(Texture0, texCoord) * color;
+}
+`
+
+// TexturedColor returns a new unlit shader that textures each mesh like the
+// Textured shader, multiplied by the mesh's vertex colors.
+func TexturedColor(v Version) *gfx.Shader {
+	return newShader("TexturedColor", v, texturedColorVert, texturedColorFrag)
+}
+
+const cutoutFrag = `
+uniform sampler2D Texture0;
+uniform float AlphaCutoff;
+
+varying vec2 texCoord;
+
+void main() {
+	vec4 c = texture2D(Texture0, texCoord);
+	if (c.a < AlphaCutoff) {
+		discard;
+	}
+	gl_FragColor = c;
+}
+`
+
+// Cutout returns a new unlit shader that textures each mesh like the Textured
+// shader, but discards the fragments whose alpha is below the AlphaCutoff
+// input. As the fragments that remain need not be blended, it is typically
+// used with the gfx.NoAlpha alpha mode for foliage, fences, etc.
+func Cutout(v Version) *gfx.Shader {
+	s := newShader("Cutout", v, texturedVert, cutoutFrag)
+	s.Inputs[InputAlphaCutoff] = float32(0.5)
+	return s
+}
+
+const wireframeVert = `
+attribute vec3 Vertex;
+attribute vec3 Bary;
+
+uniform mat4 MVP;
+
+varying vec3 bary;
+
+void main() {
+	bary = Bary;
+	gl_Position = vec4(Vertex, 1.0) * MVP;
+}
+`
+
+const wireframeFrag = `
+uniform vec4 WireColor;
+uniform vec4 FillColor;
+uniform float WireWidth;
+
+varying vec3 bary;
+
+void main() {
+	// Distance to the nearest edge in pixels, using the screen-space rate of
+	// change of the barycentric coordinates.
+	vec3 d = bary / max(fwidth(bary), vec3(1e-6));
+	float edge = min(min(d.x, d.y), d.z);
+	gl_FragColor = mix(WireColor, FillColor, clamp(edge - WireWidth * 0.5 + 0.5, 0.0, 1.0));
+}
+`
+
+// Wireframe returns a new unlit shader that draws the edges of each triangle
+// in the WireColor input, WireWidth pixels wide, and fills the triangle with
+// the FillColor input. Each mesh must have barycentric coordinates (see
+// gfx.Mesh.GenerateBary).
+//
+// The GLSL ES 1.00 flavor requires the GL_OES_standard_derivatives extension.
+func Wireframe(v Version) *gfx.Shader {
+	s := newShader("Wireframe", v, wireframeVert, wireframeFrag, "GL_OES_standard_derivatives")
+	s.Inputs[InputWireColor] = gfx.Vec4{X: 1, Y: 1, Z: 1, W: 1}
+	s.Inputs[InputFillColor] = gfx.Vec4{}
+	s.Inputs[InputWireWidth] = float32(1)
+	return s
+}
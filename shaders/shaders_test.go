@@ -0,0 +1,64 @@
+// Copyright 2014 The Azul3D Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package shaders
+
+import (
+	"bytes"
+	"testing"
+
+	"azul3d.org/gfx.v1"
+	"azul3d.org/gfx.v1/glsl"
+)
+
+var constructors = map[string]func(Version) *gfx.Shader{
+	"VertexColor":   VertexColor,
+	"Textured":      Textured,
+	"TexturedColor": TexturedColor,
+	"Cutout":        Cutout,
+	"Wireframe":     Wireframe,
+}
+
+// attribs are the names of the built-in vertex attributes.
+var attribs = []string{
+	gfx.AttribVertex,
+	gfx.AttribColor,
+	gfx.AttribBary,
+	gfx.TexCoordAttrib(0),
+}
+
+func TestShaders(t *testing.T) {
+	for name, fn := range constructors {
+		for _, v := range []Version{GLSL120, GLSLES100} {
+			s := fn(v)
+			prog, err := glsl.Reflect(s)
+			if err != nil {
+				t.Fatalf("%s (%v): %v", name, v, err)
+			}
+
+			// Every uniform is either built-in or has a default input.
+			for _, err := range prog.CheckInputs(s.Inputs, gfx.BuiltinInputs...) {
+				t.Errorf("%s (%v): %v", name, v, err)
+			}
+			for _, err := range prog.CheckAttribs(nil, attribs...) {
+				t.Errorf("%s (%v): %v", name, v, err)
+			}
+			for _, sampler := range prog.Samplers {
+				if sampler.Name != gfx.TextureSampler(0) {
+					t.Errorf("%s (%v): unexpected sampler %v", name, v, sampler)
+				}
+			}
+
+			es := v == GLSLES100
+			for _, src := range [][]byte{s.GLSLVert, s.GLSLFrag} {
+				if bytes.HasPrefix(src, []byte("#version 100\n")) != es {
+					t.Errorf("%s (%v): wrong #version directive", name, v)
+				}
+			}
+			if bytes.Contains(s.GLSLFrag, []byte("precision mediump float;")) != es {
+				t.Errorf("%s (%v): wrong precision statement", name, v)
+			}
+		}
+	}
+}
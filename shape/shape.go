@@ -0,0 +1,413 @@
+// Copyright 2014 The Azul3D Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package shape generates meshes of common geometric shapes.
+//
+// Each function returns a new indexed triangle mesh with vertex normals and a
+// single texture coordinate set. For example, to draw a textured sphere:
+//  o := gfx.NewObject()
+//  o.Shader = shaders.Textured(shaders.GLSL120)
+//  o.Meshes = []*gfx.Mesh{shape.UVSphere(1, 32, 16)}
+//  o.Textures = []*gfx.Texture{earth}
+//
+// The meshes follow the conventions of the gfx package: they are in the right
+// handed Z up coordinate system (the poles of spheres and the axes of
+// cylinders are along the Z axis), their front faces are wound
+// counter-clockwise and their texture coordinates have a top-left origin
+// (that is, V increases downwards along the surface, e.g. from the north pole
+// of a sphere to the south pole).
+//
+// All shapes are centered at the origin. Subdivision counts below the minimum
+// that forms the shape are raised to that minimum.
+package shape
+
+import (
+	"math"
+
+	"azul3d.org/gfx.v1"
+	"azul3d.org/lmath.v1"
+)
+
+// builder builds a single indexed mesh.
+type builder struct {
+	m   *gfx.Mesh
+	uvs []gfx.TexCoord
+}
+
+func newBuilder() *builder {
+	return &builder{m: gfx.NewMesh()}
+}
+
+// vertex adds a vertex and returns it's index.
+func (b *builder) vertex(p, n lmath.Vec3, uv gfx.TexCoord) uint32 {
+	if nn, ok := n.Normalized(); ok {
+		n = nn
+	}
+	b.m.Vertices = append(b.m.Vertices, gfx.ConvertVec3(p))
+	b.m.Normals = append(b.m.Normals, gfx.ConvertVec3(n))
+	b.uvs = append(b.uvs, uv)
+	return uint32(len(b.m.Vertices) - 1)
+}
+
+// triangle adds the counter-clockwise triangle a, b, c unless it is
+// degenerate (i.e. two of it's vertices are at the same position).
+func (b *builder) triangle(i0, i1, i2 uint32) {
+	v := b.m.Vertices
+	if v[i0] == v[i1] || v[i1] == v[i2] || v[i2] == v[i0] {
+		return
+	}
+	b.m.Indices = append(b.m.Indices, i0, i1, i2)
+}
+
+// grid adds a grid of (cols+1) * (rows+1) vertices, whose positions, normals
+// and texture coordinates are returned by the given function, and two
+// triangles for each cell of the grid.
+//
+// Columns are ordered left to right and rows top to bottom, as seen from the
+// front of the surface, such that the normals point towards the viewer.
+func (b *builder) grid(cols, rows int, fn func(col, row int) (p, n lmath.Vec3, uv gfx.TexCoord)) {
+	first := uint32(len(b.m.Vertices))
+	for row := 0; row <= rows; row++ {
+		for col := 0; col <= cols; col++ {
+			b.vertex(fn(col, row))
+		}
+	}
+	stride := uint32(cols + 1)
+	for row := uint32(0); row < uint32(rows); row++ {
+		for col := uint32(0); col < uint32(cols); col++ {
+			tl := first + row*stride + col
+			bl := tl + stride
+			b.triangle(tl, bl, bl+1)
+			b.triangle(tl, bl+1, tl+1)
+		}
+	}
+}
+
+// disc adds a disc of the given radius centered at c, facing +Z (or -Z if
+// down is true).
+func (b *builder) disc(c lmath.Vec3, radius float64, segments, rings int, down bool) {
+	sign := 1.0
+	if down {
+		sign = -1
+	}
+	b.grid(segments, rings, func(col, row int) (lmath.Vec3, lmath.Vec3, gfx.TexCoord) {
+		phi := 2 * math.Pi * float64(col) / float64(segments)
+		t := float64(row) / float64(rings)
+		if down {
+			// Rows go from the edge to the center, such that the normal
+			// points downwards.
+			t = 1 - t
+		}
+		x, y := t*math.Cos(phi), t*math.Sin(phi)
+		p := lmath.Vec3{X: c.X + radius*x, Y: c.Y + radius*y, Z: c.Z}
+		return p, lmath.Vec3{X: 0, Y: 0, Z: sign}, uv(0.5+0.5*sign*x, 0.5-0.5*y)
+	})
+}
+
+// mesh returns the built mesh.
+func (b *builder) mesh() *gfx.Mesh {
+	b.m.TexCoords = []gfx.TexCoordSet{{Slice: b.uvs}}
+	b.m.CalculateBounds()
+	return b.m
+}
+
+func uv(u, v float64) gfx.TexCoord {
+	return gfx.TexCoord{U: float32(u), V: float32(v)}
+}
+
+func atLeast(n, min int) int {
+	if n < min {
+		return min
+	}
+	return n
+}
+
+// Plane returns a plane in the XY plane facing +Z, of the given width (along
+// the X axis) and depth (along the Y axis), subdivided into the given number
+// of segments along each axis.
+func Plane(width, depth float64, xSegments, ySegments int) *gfx.Mesh {
+	xSegments = atLeast(xSegments, 1)
+	ySegments = atLeast(ySegments, 1)
+	b := newBuilder()
+	b.grid(xSegments, ySegments, func(col, row int) (lmath.Vec3, lmath.Vec3, gfx.TexCoord) {
+		u := float64(col) / float64(xSegments)
+		v := float64(row) / float64(ySegments)
+		p := lmath.Vec3{X: (u - 0.5) * width, Y: (0.5 - v) * depth, Z: 0}
+		return p, lmath.Vec3{X: 0, Y: 0, Z: 1}, uv(u, v)
+	})
+	return b.mesh()
+}
+
+// Box returns a box of the given size along each axis, each face subdivided
+// into the given number of segments along each of it's edges. Each face is
+// textured with the entire texture, upright when seen from the outside with
+// +Z pointing upwards (the top and bottom faces have +Y pointing upwards).
+func Box(size gfx.Vec3, segments int) *gfx.Mesh {
+	segments = atLeast(segments, 1)
+	half := lmath.Vec3{X: float64(size.X) / 2, Y: float64(size.Y) / 2, Z: float64(size.Z) / 2}
+	faces := []struct {
+		// The axes pointing right and down on the face, as seen from the
+		// outside.
+		right, down lmath.Vec3
+	}{
+		{lmath.Vec3{X: 0, Y: 1, Z: 0}, lmath.Vec3{X: 0, Y: 0, Z: -1}},  // +X
+		{lmath.Vec3{X: 0, Y: -1, Z: 0}, lmath.Vec3{X: 0, Y: 0, Z: -1}}, // -X
+		{lmath.Vec3{X: -1, Y: 0, Z: 0}, lmath.Vec3{X: 0, Y: 0, Z: -1}}, // +Y
+		{lmath.Vec3{X: 1, Y: 0, Z: 0}, lmath.Vec3{X: 0, Y: 0, Z: -1}},  // -Y
+		{lmath.Vec3{X: 1, Y: 0, Z: 0}, lmath.Vec3{X: 0, Y: -1, Z: 0}},  // +Z
+		{lmath.Vec3{X: 1, Y: 0, Z: 0}, lmath.Vec3{X: 0, Y: 1, Z: 0}},   // -Z
+	}
+	b := newBuilder()
+	for _, f := range faces {
+		n := f.down.Cross(f.right)
+		b.grid(segments, segments, func(col, row int) (lmath.Vec3, lmath.Vec3, gfx.TexCoord) {
+			u := float64(col) / float64(segments)
+			v := float64(row) / float64(segments)
+			p := n.Add(f.right.MulScalar(2*u - 1)).Add(f.down.MulScalar(2*v - 1)).Mul(half)
+			return p, n, uv(u, v)
+		})
+	}
+	return b.mesh()
+}
+
+// UVSphere returns a sphere of the given radius made up of the given number of
+// segments (around the Z axis) and rings (from pole to pole). The texture is
+// mapped using an equirectangular projection, with U increasing
+// counter-clockwise around the Z axis starting at +X.
+func UVSphere(radius float64, segments, rings int) *gfx.Mesh {
+	segments = atLeast(segments, 3)
+	rings = atLeast(rings, 2)
+	b := newBuilder()
+	b.grid(segments, rings, func(col, row int) (lmath.Vec3, lmath.Vec3, gfx.TexCoord) {
+		u := float64(col) / float64(segments)
+		v := float64(row) / float64(rings)
+		n := spherical(2*math.Pi*u, math.Pi*v)
+		return n.MulScalar(radius), n, uv(u, v)
+	})
+	return b.mesh()
+}
+
+// spherical returns the unit vector at the given azimuth (counter-clockwise
+// around the Z axis, starting at +X) and polar angle (from +Z).
+func spherical(azimuth, polar float64) lmath.Vec3 {
+	s := math.Sin(polar)
+	if math.Abs(s) < 1e-12 {
+		// Exactly at a pole, such that triangles meeting there are found to
+		// be degenerate.
+		s = 0
+	}
+	return lmath.Vec3{X: s * math.Cos(azimuth), Y: s * math.Sin(azimuth), Z: math.Cos(polar)}
+}
+
+// Cylinder returns a capped cylinder of the given radius and height along the
+// Z axis, made up of the given number of segments (around the Z axis) and
+// rings (along the Z axis). The side is textured like a UVSphere, and each cap
+// with a disc inscribed in the texture.
+func Cylinder(radius, height float64, segments, rings int) *gfx.Mesh {
+	segments = atLeast(segments, 3)
+	rings = atLeast(rings, 1)
+	b := newBuilder()
+	b.grid(segments, rings, func(col, row int) (lmath.Vec3, lmath.Vec3, gfx.TexCoord) {
+		u := float64(col) / float64(segments)
+		v := float64(row) / float64(rings)
+		n := spherical(2*math.Pi*u, math.Pi/2)
+		p := lmath.Vec3{X: n.X * radius, Y: n.Y * radius, Z: (0.5 - v) * height}
+		return p, n, uv(u, v)
+	})
+	b.disc(lmath.Vec3{X: 0, Y: 0, Z: height / 2}, radius, segments, 1, false)
+	b.disc(lmath.Vec3{X: 0, Y: 0, Z: -height / 2}, radius, segments, 1, true)
+	return b.mesh()
+}
+
+// Cone returns a cone of the given base radius and height along the Z axis,
+// with it's apex pointing towards +Z, made up of the given number of segments
+// (around the Z axis) and rings (from the apex to the base). The side is
+// textured like a UVSphere, and the base with a disc inscribed in the texture.
+func Cone(radius, height float64, segments, rings int) *gfx.Mesh {
+	segments = atLeast(segments, 3)
+	rings = atLeast(rings, 1)
+	b := newBuilder()
+	b.grid(segments, rings, func(col, row int) (lmath.Vec3, lmath.Vec3, gfx.TexCoord) {
+		u := float64(col) / float64(segments)
+		v := float64(row) / float64(rings)
+		dir := spherical(2*math.Pi*u, math.Pi/2)
+		p := lmath.Vec3{X: dir.X * radius * v, Y: dir.Y * radius * v, Z: (0.5 - v) * height}
+		n := lmath.Vec3{X: dir.X * height, Y: dir.Y * height, Z: radius}
+		return p, n, uv(u, v)
+	})
+	b.disc(lmath.Vec3{X: 0, Y: 0, Z: -height / 2}, radius, segments, 1, true)
+	return b.mesh()
+}
+
+// Torus returns a torus lying in the XY plane, whose tube of the given minor
+// radius is swept around the Z axis at the given major radius. It is made up of
+// the given number of segments (around the Z axis) and sides (around the
+// tube). The texture is wrapped around the Z axis along U, and around the tube
+// along V starting at the top of the tube.
+func Torus(major, minor float64, segments, sides int) *gfx.Mesh {
+	segments = atLeast(segments, 3)
+	sides = atLeast(sides, 3)
+	b := newBuilder()
+	b.grid(segments, sides, func(col, row int) (lmath.Vec3, lmath.Vec3, gfx.TexCoord) {
+		u := float64(col) / float64(segments)
+		v := float64(row) / float64(sides)
+		phi := 2 * math.Pi * u
+		psi := math.Pi/2 - 2*math.Pi*v
+		n := lmath.Vec3{X: math.Cos(psi) * math.Cos(phi), Y: math.Cos(psi) * math.Sin(phi), Z: math.Sin(psi)}
+		p := lmath.Vec3{X: major * math.Cos(phi), Y: major * math.Sin(phi), Z: 0}.Add(n.MulScalar(minor))
+		return p, n, uv(u, v)
+	})
+	return b.mesh()
+}
+
+// Capsule returns a capsule along the Z axis: a cylinder of the given radius
+// capped by two hemispheres, whose total height (including the hemispheres) is
+// the given height, or 2*radius if that is larger. It is made up of the given
+// number of segments (around the Z axis) and rings per hemisphere. The texture
+// is mapped along V by the distance along the surface from the top.
+func Capsule(radius, height float64, segments, rings int) *gfx.Mesh {
+	segments = atLeast(segments, 3)
+	rings = atLeast(rings, 1)
+	half := math.Max(height/2-radius, 0)
+	length := math.Pi*radius + 2*half
+
+	// Each hemisphere has rings+1 rows of vertices, the first row of the
+	// bottom one directly following the last row of the top one.
+	b := newBuilder()
+	b.grid(segments, 2*rings+1, func(col, row int) (lmath.Vec3, lmath.Vec3, gfx.TexCoord) {
+		u := float64(col) / float64(segments)
+		center, polar, dist := half, 0.0, 0.0
+		if row <= rings {
+			polar = math.Pi / 2 * float64(row) / float64(rings)
+			dist = polar * radius
+		} else {
+			center = -half
+			polar = math.Pi/2 + math.Pi/2*float64(row-rings-1)/float64(rings)
+			dist = polar*radius + 2*half
+		}
+		n := spherical(2*math.Pi*u, polar)
+		p := n.MulScalar(radius).Add(lmath.Vec3{X: 0, Y: 0, Z: center})
+		return p, n, uv(u, dist/length)
+	})
+	return b.mesh()
+}
+
+// Icosphere returns a sphere of the given radius, made by subdividing each
+// triangle of an icosahedron into four the given number of times. Compared to
+// a UVSphere, the triangles are of near equal size. It is textured like a
+// UVSphere, with vertices duplicated along the texture seam and at the poles.
+func Icosphere(radius float64, subdivisions int) *gfx.Mesh {
+	subdivisions = atLeast(subdivisions, 0)
+
+	// The 12 vertices of an icosahedron, with a vertex at each pole.
+	dirs := []lmath.Vec3{{X: 0, Y: 0, Z: 1}}
+	for i := 0; i < 10; i++ {
+		polar := math.Atan(0.5)
+		if i%2 == 1 {
+			polar = -polar
+		}
+		dirs = append(dirs, spherical(math.Pi/5*float64(i), math.Pi/2-polar))
+	}
+	dirs = append(dirs, lmath.Vec3{X: 0, Y: 0, Z: -1})
+	var tris [][3]int
+	for i := 0; i < 10; i += 2 {
+		a, b, c, d := 1+i, 2+i, 1+(i+2)%10, 2+(i+2)%10
+		tris = append(tris, [3]int{0, a, c}, [3]int{a, b, c}, [3]int{c, b, d}, [3]int{b, 11, d})
+	}
+	for i, t := range tris {
+		// Wind each face counter-clockwise as seen from the outside.
+		a, b, c := dirs[t[0]], dirs[t[1]], dirs[t[2]]
+		if b.Sub(a).Cross(c.Sub(a)).Dot(a) < 0 {
+			tris[i] = [3]int{t[0], t[2], t[1]}
+		}
+	}
+
+	for s := 0; s < subdivisions; s++ {
+		mid := make(map[[2]int]int)
+		midpoint := func(a, b int) int {
+			if a > b {
+				a, b = b, a
+			}
+			if i, ok := mid[[2]int{a, b}]; ok {
+				return i
+			}
+			d, _ := dirs[a].Add(dirs[b]).Normalized()
+			dirs = append(dirs, d)
+			mid[[2]int{a, b}] = len(dirs) - 1
+			return len(dirs) - 1
+		}
+		next := make([][3]int, 0, 4*len(tris))
+		for _, t := range tris {
+			ab, bc, ca := midpoint(t[0], t[1]), midpoint(t[1], t[2]), midpoint(t[2], t[0])
+			next = append(next, [3]int{t[0], ab, ca}, [3]int{ab, t[1], bc}, [3]int{ca, bc, t[2]}, [3]int{ab, bc, ca})
+		}
+		tris = next
+	}
+
+	// Texture coordinates are found per triangle corner, as vertices on the
+	// seam and at the poles have a different U coordinate in each triangle.
+	b := newBuilder()
+	type key struct {
+		dir int
+		uv  gfx.TexCoord
+	}
+	indices := make(map[key]uint32)
+	for _, t := range tris {
+		var (
+			uvs  [3]gfx.TexCoord
+			pole = -1
+			maxU float32
+		)
+		for k, i := range t {
+			d := dirs[i]
+			if d.X == 0 && d.Y == 0 {
+				pole = k
+			}
+			u := math.Atan2(d.Y, d.X) / (2 * math.Pi)
+			if u < 0 {
+				u++
+			}
+			uvs[k] = uv(u, math.Acos(math.Max(-1, math.Min(1, d.Z)))/math.Pi)
+			if k != pole && uvs[k].U > maxU {
+				maxU = uvs[k].U
+			}
+		}
+		for k := range uvs {
+			// Wrap coordinates across the seam.
+			if k != pole && maxU-uvs[k].U > 0.5 {
+				uvs[k].U++
+			}
+		}
+		if pole >= 0 {
+			// The pole takes the average U of the other two corners.
+			uvs[pole].U = (uvs[(pole+1)%3].U + uvs[(pole+2)%3].U) / 2
+		}
+		var corner [3]uint32
+		for k, i := range t {
+			kk := key{i, uvs[k]}
+			index, ok := indices[kk]
+			if !ok {
+				index = b.vertex(dirs[i].MulScalar(radius), dirs[i], uvs[k])
+				indices[kk] = index
+			}
+			corner[k] = index
+		}
+		b.triangle(corner[0], corner[1], corner[2])
+	}
+	return b.mesh()
+}
+
+// ScreenQuad returns a quad covering the entire canvas when drawn without a
+// camera (that is, it's vertices are in normalized device coordinates from -1
+// to +1 along the X and Y axes). It is textured such that the top-left of the
+// texture is at the top-left of the canvas. It's normals point towards the
+// viewer, i.e. along -Z in normalized device coordinates.
+func ScreenQuad() *gfx.Mesh {
+	b := newBuilder()
+	b.grid(1, 1, func(col, row int) (lmath.Vec3, lmath.Vec3, gfx.TexCoord) {
+		u, v := float64(col), float64(row)
+		return lmath.Vec3{X: 2*u - 1, Y: 1 - 2*v, Z: 0}, lmath.Vec3{X: 0, Y: 0, Z: -1}, uv(u, v)
+	})
+	return b.mesh()
+}
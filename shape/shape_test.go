@@ -0,0 +1,95 @@
+// Copyright 2014 The Azul3D Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package shape
+
+import (
+	"math"
+	"testing"
+
+	"azul3d.org/gfx.v1"
+)
+
+func TestShapes(t *testing.T) {
+	shapes := []struct {
+		name   string
+		m      *gfx.Mesh
+		volume float64 // Expected volume of closed shapes, or zero.
+	}{
+		{"Plane", Plane(2, 1, 4, 2), 0},
+		{"Box", Box(gfx.Vec3{1, 2, 3}, 3), 6},
+		{"UVSphere", UVSphere(1, 64, 32), 4.0 / 3 * math.Pi},
+		{"Icosphere", Icosphere(1, 3), 4.0 / 3 * math.Pi},
+		{"Cylinder", Cylinder(1, 2, 64, 2), 2 * math.Pi},
+		{"Cone", Cone(1, 3, 64, 2), math.Pi},
+		{"Torus", Torus(2, 0.5, 64, 32), 2 * math.Pi * math.Pi * 2 * 0.25},
+		{"Capsule", Capsule(1, 4, 64, 16), 4.0/3*math.Pi + 2*math.Pi},
+		{"ScreenQuad", ScreenQuad(), 0},
+	}
+	for _, s := range shapes {
+		m := s.m
+		if len(m.Normals) != len(m.Vertices) || len(m.TexCoords) != 1 || len(m.TexCoords[0].Slice) != len(m.Vertices) {
+			t.Errorf("%s: mismatched data slices", s.name)
+			continue
+		}
+		if len(m.Indices) == 0 || len(m.Indices)%3 != 0 {
+			t.Errorf("%s: got %d indices", s.name, len(m.Indices))
+			continue
+		}
+
+		var volume float64
+		for i := 0; i < len(m.Indices); i += 3 {
+			a, b, c := m.Indices[i], m.Indices[i+1], m.Indices[i+2]
+			pa, pb, pc := m.Vertices[a].Vec3(), m.Vertices[b].Vec3(), m.Vertices[c].Vec3()
+			n := pb.Sub(pa).Cross(pc.Sub(pa))
+			if n.Dot(n) == 0 {
+				t.Errorf("%s: degenerate triangle %d", s.name, i/3)
+			}
+
+			// Counter-clockwise winding agrees with the vertex normals.
+			sum := m.Normals[a].Vec3().Add(m.Normals[b].Vec3()).Add(m.Normals[c].Vec3())
+			if s.name != "ScreenQuad" && n.Dot(sum) <= 0 {
+				t.Errorf("%s: triangle %d is wound against it's normals", s.name, i/3)
+			}
+			volume += pa.Dot(pb.Cross(pc)) / 6
+		}
+		if s.volume != 0 && math.Abs(volume-s.volume)/s.volume > 0.01 {
+			t.Errorf("%s: got volume %v, want %v", s.name, volume, s.volume)
+		}
+
+		for i, n := range m.Normals {
+			if l := n.Vec3().Length(); math.Abs(l-1) > 1e-5 {
+				t.Errorf("%s: normal %d has length %v", s.name, i, l)
+				break
+			}
+		}
+		for i, uv := range m.TexCoords[0].Slice {
+			if uv.U < 0 || uv.U > 1.5 || uv.V < 0 || uv.V > 1 {
+				t.Errorf("%s: texture coordinate %d out of range: %v", s.name, i, uv)
+				break
+			}
+		}
+	}
+}
+
+func TestConventions(t *testing.T) {
+	// The north pole of a sphere is at +Z, at the top of the texture.
+	m := UVSphere(2, 8, 4)
+	if m.Vertices[0] != (gfx.Vec3{0, 0, 2}) || m.TexCoords[0].Slice[0].V != 0 {
+		t.Fatal("got north pole", m.Vertices[0], m.TexCoords[0].Slice[0])
+	}
+
+	// The top-left of a plane's texture is at -X, +Y.
+	m = Plane(2, 2, 1, 1)
+	if m.Vertices[0] != (gfx.Vec3{-1, 1, 0}) || m.TexCoords[0].Slice[0] != (gfx.TexCoord{0, 0}) {
+		t.Fatal("got plane corner", m.Vertices[0], m.TexCoords[0].Slice[0])
+	}
+
+	// The top-left of a screen quad's texture is at the top-left of the
+	// canvas.
+	m = ScreenQuad()
+	if m.Vertices[0] != (gfx.Vec3{-1, 1, 0}) || m.TexCoords[0].Slice[0] != (gfx.TexCoord{0, 0}) {
+		t.Fatal("got screen quad corner", m.Vertices[0], m.TexCoords[0].Slice[0])
+	}
+}
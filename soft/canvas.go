@@ -0,0 +1,282 @@
+// Copyright 2014 The Azul3D Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package soft
+
+import (
+	"image"
+	"image/color"
+	"sync"
+
+	"azul3d.org/gfx.v1"
+	"azul3d.org/lmath.v1"
+)
+
+// canvas implements the gfx.Canvas interface by rasterizing into in-memory
+// color, depth, and stencil buffers. It's bounds never change.
+type canvas struct {
+	// access guards every field below.
+	access sync.Mutex
+
+	msaa      bool
+	precision gfx.Precision
+	bounds    image.Rectangle
+
+	// The color, depth, and stencil buffers, in row-major order.
+	color   []gfx.Color
+	depth   []float64
+	stencil []uint8
+}
+
+// newCanvas returns a new canvas with the given bounds and precision, whose
+// buffers are cleared to transparent black, a depth of 1.0, and a stencil
+// value of zero.
+func newCanvas(bounds image.Rectangle, p gfx.Precision) *canvas {
+	n := bounds.Dx() * bounds.Dy()
+	c := &canvas{
+		msaa:      true,
+		precision: p,
+		bounds:    bounds,
+		color:     make([]gfx.Color, n),
+		depth:     make([]float64, n),
+		stencil:   make([]uint8, n),
+	}
+	for i := range c.depth {
+		c.depth[i] = 1.0
+	}
+	return c
+}
+
+// offset returns the buffer offset of the in-bounds pixel at x, y.
+func (c *canvas) offset(x, y int) int {
+	return (y-c.bounds.Min.Y)*c.bounds.Dx() + (x - c.bounds.Min.X)
+}
+
+// copyTexels returns a copy of a buffer of the canvas as texels, whose colors
+// are given by the function for each buffer offset. The canvas's access lock
+// is held while copying, such that the copy can be sampled without locking.
+func (c *canvas) copyTexels(at func(i int) gfx.Color) texels {
+	c.access.Lock()
+	defer c.access.Unlock()
+	pix := &texelSlice{
+		w:   c.bounds.Dx(),
+		h:   c.bounds.Dy(),
+		pix: make([]gfx.Color, len(c.color)),
+	}
+	for i := range pix.pix {
+		pix.pix[i] = at(i)
+	}
+	return pix
+}
+
+// resolve implements the texelSource interface, such that the color buffer of
+// a canvas can be sampled by render-to-texture textures.
+func (c *canvas) resolve() texels {
+	return c.copyTexels(func(i int) gfx.Color {
+		return c.color[i]
+	})
+}
+
+// depthTexels exposes the depth buffer of a canvas as grayscale texels.
+type depthTexels struct {
+	*canvas
+}
+
+func (d depthTexels) resolve() texels {
+	return d.copyTexels(func(i int) gfx.Color {
+		v := float32(d.depth[i])
+		return gfx.Color{R: v, G: v, B: v, A: 1}
+	})
+}
+
+// stencilTexels exposes the stencil buffer of a canvas as grayscale texels.
+type stencilTexels struct {
+	*canvas
+}
+
+func (s stencilTexels) resolve() texels {
+	return s.copyTexels(func(i int) gfx.Color {
+		v := float32(s.stencil[i]) / 255
+		return gfx.Color{R: v, G: v, B: v, A: 1}
+	})
+}
+
+func (c *canvas) SetMSAA(enabled bool) {
+	c.access.Lock()
+	c.msaa = enabled
+	c.access.Unlock()
+}
+
+func (c *canvas) MSAA() bool {
+	c.access.Lock()
+	msaa := c.msaa
+	c.access.Unlock()
+	return msaa
+}
+
+func (c *canvas) Precision() gfx.Precision {
+	return c.precision
+}
+
+func (c *canvas) Bounds() image.Rectangle {
+	c.access.Lock()
+	b := c.bounds
+	c.access.Unlock()
+	return b
+}
+
+func (c *canvas) Download(r image.Rectangle, complete chan image.Image) {
+	c.access.Lock()
+	r = clampRect(r, c.bounds)
+	img := image.NewRGBA(r)
+	for y := r.Min.Y; y < r.Max.Y; y++ {
+		for x := r.Min.X; x < r.Max.X; x++ {
+			img.SetRGBA(x, y, toRGBA(c.color[c.offset(x, y)]))
+		}
+	}
+	c.access.Unlock()
+	complete <- img
+}
+
+func (c *canvas) Clear(r image.Rectangle, bg gfx.Color) {
+	c.access.Lock()
+	r = clampRect(r, c.bounds)
+	for y := r.Min.Y; y < r.Max.Y; y++ {
+		for x := r.Min.X; x < r.Max.X; x++ {
+			c.color[c.offset(x, y)] = bg
+		}
+	}
+	c.access.Unlock()
+}
+
+func (c *canvas) ClearDepth(r image.Rectangle, depth float64) {
+	c.access.Lock()
+	r = clampRect(r, c.bounds)
+	depth = clamp01(depth)
+	for y := r.Min.Y; y < r.Max.Y; y++ {
+		for x := r.Min.X; x < r.Max.X; x++ {
+			c.depth[c.offset(x, y)] = depth
+		}
+	}
+	c.access.Unlock()
+}
+
+func (c *canvas) ClearStencil(r image.Rectangle, stencil int) {
+	c.access.Lock()
+	r = clampRect(r, c.bounds)
+	for y := r.Min.Y; y < r.Max.Y; y++ {
+		for x := r.Min.X; x < r.Max.X; x++ {
+			c.stencil[c.offset(x, y)] = uint8(stencil)
+		}
+	}
+	c.access.Unlock()
+}
+
+func (c *canvas) Draw(r image.Rectangle, o *gfx.Object, cam *gfx.Camera) {
+	// Give the object a chance to determine it's bounding box before any mesh
+	// data slices are cleared.
+	o.Bounds()
+
+	var (
+		viewProj lmath.Mat4
+		meshes   []*gfx.Mesh
+	)
+	if cam != nil {
+		cam.RLock()
+		viewProj = cam.View().Mul(cam.Projection.Mat4())
+		meshes = o.SelectLOD(cam)
+		cam.RUnlock()
+	} else {
+		viewProj = lmath.Mat4Identity
+	}
+
+	o.Lock()
+	defer o.Unlock()
+	if cam == nil {
+		meshes = o.Meshes
+	}
+
+	// Reject objects that cannot be drawn.
+	if o.Shader == nil {
+		return
+	}
+	o.Shader.Lock()
+	if len(o.Shader.Error) > 0 {
+		o.Shader.Unlock()
+		return
+	}
+	if !o.Shader.Loaded {
+		loadShader(o.Shader)
+	}
+	o.Shader.Unlock()
+	if len(meshes) == 0 {
+		return
+	}
+
+	d := &drawState{
+		State: o.State,
+		mvp:   o.Transform.Mat4().Mul(viewProj),
+	}
+	if t := o.Texture(gfx.TextureSampler(0)); t != nil {
+		t.Lock()
+		if _, ok := t.NativeTexture.(*nativeTexture); !ok || !t.Loaded {
+			loadTexture(t)
+		}
+		d.tex = newSampler(t)
+		t.Unlock()
+	}
+
+	c.access.Lock()
+	d.viewport = clampRect(r, c.bounds)
+	for _, m := range meshes {
+		m.Lock()
+		if !m.Loaded && len(m.Vertices) == 0 {
+			m.Unlock()
+			continue
+		}
+		if _, ok := m.NativeMesh.(*nativeMesh); !ok || !m.Loaded || m.HasChanged() {
+			loadMesh(m)
+		}
+		c.drawMesh(d, m.NativeMesh.(*nativeMesh))
+		m.Unlock()
+	}
+	c.access.Unlock()
+
+	o.NativeObject = nativeObject{samples: d.samples}
+}
+
+func (c *canvas) QueryWait() {}
+
+func (c *canvas) Render() {}
+
+// toRGBA converts the normalized color into a 32-bit color, clamping each
+// component.
+func toRGBA(c gfx.Color) color.RGBA {
+	return color.RGBA{
+		R: uint8(clamp01f(c.R)*255 + 0.5),
+		G: uint8(clamp01f(c.G)*255 + 0.5),
+		B: uint8(clamp01f(c.B)*255 + 0.5),
+		A: uint8(clamp01f(c.A)*255 + 0.5),
+	}
+}
+
+func clamp01(v float64) float64 {
+	if v < 0 {
+		return 0
+	}
+	if v > 1 {
+		return 1
+	}
+	return v
+}
+
+func clamp01f(v float32) float32 {
+	if v < 0 {
+		return 0
+	}
+	if v > 1 {
+		return 1
+	}
+	return v
+}
@@ -0,0 +1,24 @@
+// Copyright 2014 The Azul3D Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package soft implements a pure-Go software rasterizer that fulfills the
+// gfx.Renderer interface.
+//
+// It is intended for environments without graphics hardware (e.g. continuous
+// integration servers) where rendering code must still produce real pixels
+// that can be downloaded and compared against expected results.
+//
+// Since GLSL programs cannot be executed on the CPU, the shader of an object is
+// only used to decide whether or not the object is drawn (see the Canvas.Draw
+// documentation). Instead each fragment is shaded as the interpolated vertex
+// color (or opaque white if the mesh has no colors) multiplied by a sample of
+// the object's first texture (using the mesh's first texture coordinate set).
+//
+// All of the graphics state is honored: depth testing, stencil testing, face
+// culling, blending, the color write masks and the alpha modes. Because there
+// is no multisampling, AlphaToCoverage falls back to BinaryAlpha (as described
+// by the gfx package) and dithering is ignored. Every kind of primitive is
+// rasterized: points as squares PointSize pixels wide, and lines as rectangles
+// LineWidth pixels wide.
+package soft
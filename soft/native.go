@@ -0,0 +1,208 @@
+// Copyright 2014 The Azul3D Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package soft
+
+import (
+	"image"
+
+	"azul3d.org/gfx.v1"
+)
+
+type nativeObject struct {
+	samples int
+}
+
+func (n nativeObject) Destroy() {}
+func (n nativeObject) SampleCount() int {
+	return n.samples
+}
+
+// nativeMesh holds the renderer's own copy of the mesh data, such that it can
+// still be rasterized once the mesh's data slices have been cleared.
+type nativeMesh struct {
+	primitive gfx.Primitive
+	indices   []uint32
+	vertices  []gfx.Vec3
+	colors    []gfx.Color
+	texCoords [][]gfx.TexCoord
+}
+
+func (n *nativeMesh) Destroy() {}
+
+// loadMesh uploads the data of the mesh into it's native mesh. Only the data
+// slices which are marked as changed are uploaded if the mesh was previously
+// loaded.
+//
+// The mesh's write lock must be held for this function to operate safely.
+func loadMesh(m *gfx.Mesh) {
+	native, ok := m.NativeMesh.(*nativeMesh)
+	if !ok || !m.Loaded {
+		native = new(nativeMesh)
+		m.IndicesChanged = true
+		m.VerticesChanged = true
+		m.ColorsChanged = true
+		for i := range m.TexCoords {
+			m.TexCoords[i].Changed = true
+		}
+	}
+	native.primitive = m.Primitive
+	if m.IndicesChanged {
+		native.indices = append([]uint32(nil), m.Indices...)
+		m.IndicesChanged = false
+	}
+	if m.VerticesChanged {
+		native.vertices = append([]gfx.Vec3(nil), m.Vertices...)
+		m.VerticesChanged = false
+	}
+	if m.ColorsChanged {
+		native.colors = append([]gfx.Color(nil), m.Colors...)
+		m.ColorsChanged = false
+	}
+	if len(native.texCoords) < len(m.TexCoords) {
+		grown := make([][]gfx.TexCoord, len(m.TexCoords))
+		copy(grown, native.texCoords)
+		native.texCoords = grown
+	}
+	for i, set := range m.TexCoords {
+		if set.Changed {
+			native.texCoords[i] = append([]gfx.TexCoord(nil), set.Slice...)
+			m.TexCoords[i].Changed = false
+		}
+	}
+	m.NormalsChanged = false
+	m.TangentsChanged = false
+	m.BaryChanged = false
+	for name, attrib := range m.Attribs {
+		attrib.Changed = false
+		m.Attribs[name] = attrib
+	}
+	m.NativeMesh = native
+	m.Loaded = true
+	m.ClearData()
+}
+
+// texels is a two-dimensional grid of colors that can be sampled by the
+// rasterizer.
+type texels interface {
+	// size returns the width and height of the grid.
+	size() (w, h int)
+
+	// at returns the color at the given (in-bounds) position of the grid.
+	at(x, y int) gfx.Color
+}
+
+// texelSlice is a simple texels implementation backed by a slice.
+type texelSlice struct {
+	w, h int
+	pix  []gfx.Color
+}
+
+func (t *texelSlice) size() (w, h int) {
+	return t.w, t.h
+}
+
+func (t *texelSlice) at(x, y int) gfx.Color {
+	return t.pix[y*t.w+x]
+}
+
+// resolve implements the texelSource interface. The texels of a loaded texture
+// never change, so they are returned as-is.
+func (t *texelSlice) resolve() texels {
+	return t
+}
+
+// texelSource is the source of the texels of a texture.
+type texelSource interface {
+	// resolve returns the current texels of the source. Sources that may
+	// change (e.g. the buffers of a canvas) return a copy, such that it can be
+	// sampled without locking.
+	resolve() texels
+}
+
+type nativeTexture struct {
+	texelSource
+	format gfx.TexFormat
+}
+
+func (n *nativeTexture) Destroy() {}
+func (n *nativeTexture) Download(r image.Rectangle, complete chan image.Image) {
+	tex := n.resolve()
+	w, h := tex.size()
+	r = clampRect(r, image.Rect(0, 0, w, h))
+	img := image.NewRGBA(r)
+	for y := r.Min.Y; y < r.Max.Y; y++ {
+		for x := r.Min.X; x < r.Max.X; x++ {
+			img.Set(x, y, tex.at(x, y))
+		}
+	}
+	complete <- img
+}
+func (n *nativeTexture) ChosenFormat() gfx.TexFormat {
+	return n.format
+}
+
+// chooseFormat returns the format that the renderer stores a texture of the
+// given format in. Compressed formats are stored uncompressed.
+func chooseFormat(f gfx.TexFormat) gfx.TexFormat {
+	if f == gfx.RGB || f == gfx.DXT1 {
+		return gfx.RGB
+	}
+	return gfx.RGBA
+}
+
+// loadTexture converts the source image of the texture into it's native
+// texture.
+//
+// The texture's write lock must be held for this function to operate safely.
+func loadTexture(t *gfx.Texture) {
+	format := chooseFormat(t.Format)
+	native := &nativeTexture{format: format}
+	if t.Source != nil {
+		b := t.Source.Bounds()
+		pix := &texelSlice{
+			w:   b.Dx(),
+			h:   b.Dy(),
+			pix: make([]gfx.Color, b.Dx()*b.Dy()),
+		}
+		for y := 0; y < pix.h; y++ {
+			for x := 0; x < pix.w; x++ {
+				c := gfx.ColorModel.Convert(t.Source.At(b.Min.X+x, b.Min.Y+y)).(gfx.Color)
+				if format == gfx.RGB {
+					c.A = 1
+				}
+				pix.pix[y*pix.w+x] = c
+			}
+		}
+		native.texelSource = pix
+		t.Bounds = image.Rect(0, 0, pix.w, pix.h)
+	} else {
+		native.texelSource = &texelSlice{}
+	}
+	t.NativeTexture = native
+	t.Loaded = true
+	t.ClearData()
+}
+
+type nativeShader struct{}
+
+func (n nativeShader) Destroy() {}
+
+// loadShader marks the shader as loaded. There is nothing to compile as GLSL
+// programs are not executed by this renderer.
+//
+// The shader's write lock must be held for this function to operate safely.
+func loadShader(s *gfx.Shader) {
+	s.NativeShader = nativeShader{}
+	s.Loaded = true
+	s.ClearData()
+}
+
+// clampRect returns r clamped to the bounds b, or b itself if r is empty.
+func clampRect(r, b image.Rectangle) image.Rectangle {
+	if r.Empty() {
+		return b
+	}
+	return r.Intersect(b)
+}
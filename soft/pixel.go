@@ -0,0 +1,217 @@
+// Copyright 2014 The Azul3D Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package soft
+
+import (
+	"math"
+
+	"azul3d.org/gfx.v1"
+)
+
+// fragment is a single shaded fragment produced by rasterization.
+type fragment struct {
+	color gfx.Color
+	depth float64
+}
+
+// shade runs the per-fragment operations (alpha testing, stencil testing,
+// depth testing, blending and the color write masks) for the fragment at x,
+// y.
+//
+// The canvas's access lock must be held for this method to operate safely.
+func (c *canvas) shade(d *drawState, s *gfx.StencilState, x, y int, f fragment) {
+	// Alpha testing. There is no multisampling, so AlphaToCoverage falls back
+	// to BinaryAlpha.
+	switch d.AlphaMode {
+	case gfx.BinaryAlpha, gfx.AlphaToCoverage:
+		if f.color.A < 0.5 {
+			return
+		}
+	}
+
+	i := c.offset(x, y)
+	if d.StencilTest {
+		ref := s.Reference & s.ReadMask
+		existing := uint(c.stencil[i]) & s.ReadMask
+		if !compare(s.Cmp, float64(ref), float64(existing)) {
+			c.stencil[i] = stencilOp(s.Fail, s, c.stencil[i])
+			return
+		}
+	}
+	if d.DepthTest {
+		if !compare(d.DepthCmp, f.depth, c.depth[i]) {
+			if d.StencilTest {
+				c.stencil[i] = stencilOp(s.DepthFail, s, c.stencil[i])
+			}
+			return
+		}
+		if d.DepthWrite {
+			c.depth[i] = f.depth
+		}
+	}
+	if d.StencilTest {
+		c.stencil[i] = stencilOp(s.DepthPass, s, c.stencil[i])
+	}
+	d.samples++
+
+	dst := c.color[i]
+	src := f.color
+	switch d.AlphaMode {
+	case gfx.NoAlpha:
+		// Colors are premultiplied, so transparent parts are already black.
+		src.A = 1
+	case gfx.AlphaBlend:
+		src = blend(d.Blend, src, dst)
+	}
+	if d.WriteRed {
+		dst.R = src.R
+	}
+	if d.WriteGreen {
+		dst.G = src.G
+	}
+	if d.WriteBlue {
+		dst.B = src.B
+	}
+	if d.WriteAlpha {
+		dst.A = src.A
+	}
+	c.color[i] = dst
+}
+
+// compare returns the result of the comparison 'a cmp b'.
+func compare(cmp gfx.Cmp, a, b float64) bool {
+	switch cmp {
+	case gfx.Always:
+		return true
+	case gfx.Never:
+		return false
+	case gfx.Less:
+		return a < b
+	case gfx.LessOrEqual:
+		return a <= b
+	case gfx.Greater:
+		return a > b
+	case gfx.GreaterOrEqual:
+		return a >= b
+	case gfx.Equal:
+		return a == b
+	case gfx.NotEqual:
+		return a != b
+	}
+	return false
+}
+
+// stencilOp returns the new stencil value after performing the operation on
+// the existing stencil value, v.
+func stencilOp(op gfx.StencilOp, s *gfx.StencilState, v uint8) uint8 {
+	var n uint8
+	switch op {
+	case gfx.SKeep:
+		return v
+	case gfx.SZero:
+		n = 0
+	case gfx.SReplace:
+		n = uint8(s.Reference)
+	case gfx.SIncr:
+		n = v
+		if v < math.MaxUint8 {
+			n++
+		}
+	case gfx.SIncrWrap:
+		n = v + 1
+	case gfx.SDecr:
+		n = v
+		if v > 0 {
+			n--
+		}
+	case gfx.SDecrWrap:
+		n = v - 1
+	case gfx.SInvert:
+		n = ^v
+	default:
+		return v
+	}
+	mask := uint8(s.WriteMask)
+	return (v &^ mask) | (n & mask)
+}
+
+// blendFactor returns the blend factor for the given operand.
+func blendFactor(op gfx.BlendOp, src, dst, constant gfx.Color) gfx.Color {
+	switch op {
+	case gfx.BZero:
+		return gfx.Color{}
+	case gfx.BOne:
+		return gfx.Color{R: 1, G: 1, B: 1, A: 1}
+	case gfx.BSrcColor:
+		return src
+	case gfx.BOneMinusSrcColor:
+		return oneMinus(src)
+	case gfx.BDstColor:
+		return dst
+	case gfx.BOneMinusDstColor:
+		return oneMinus(dst)
+	case gfx.BSrcAlpha:
+		return splat(src.A)
+	case gfx.BOneMinusSrcAlpha:
+		return splat(1 - src.A)
+	case gfx.BDstAlpha:
+		return splat(dst.A)
+	case gfx.BOneMinusDstAlpha:
+		return splat(1 - dst.A)
+	case gfx.BConstantColor:
+		return constant
+	case gfx.BOneMinusConstantColor:
+		return oneMinus(constant)
+	case gfx.BConstantAlpha:
+		return splat(constant.A)
+	case gfx.BOneMinusConstantAlpha:
+		return splat(1 - constant.A)
+	case gfx.BSrcAlphaSaturate:
+		f := src.A
+		if 1-dst.A < f {
+			f = 1 - dst.A
+		}
+		return gfx.Color{R: f, G: f, B: f, A: 1}
+	}
+	return gfx.Color{}
+}
+
+// blendEq applies the blend equation to the source and destination values,
+// each already multiplied by their blend factor.
+func blendEq(eq gfx.BlendEq, s, d float32) float32 {
+	switch eq {
+	case gfx.BSub:
+		return clamp01f(s - d)
+	case gfx.BReverseSub:
+		return clamp01f(d - s)
+	}
+	return clamp01f(s + d)
+}
+
+// blend blends the source color over the destination color.
+func blend(b gfx.BlendState, src, dst gfx.Color) gfx.Color {
+	sf := blendFactor(b.SrcRGB, src, dst, b.Color)
+	df := blendFactor(b.DstRGB, src, dst, b.Color)
+	sa := blendFactor(b.SrcAlpha, src, dst, b.Color).A
+	da := blendFactor(b.DstAlpha, src, dst, b.Color).A
+	return gfx.Color{
+		R: blendEq(b.RGBEq, src.R*sf.R, dst.R*df.R),
+		G: blendEq(b.RGBEq, src.G*sf.G, dst.G*df.G),
+		B: blendEq(b.RGBEq, src.B*sf.B, dst.B*df.B),
+		A: blendEq(b.AlphaEq, src.A*sa, dst.A*da),
+	}
+}
+
+func oneMinus(c gfx.Color) gfx.Color {
+	return gfx.Color{R: 1 - c.R, G: 1 - c.G, B: 1 - c.B, A: 1 - c.A}
+}
+
+func splat(v float32) gfx.Color {
+	return gfx.Color{R: v, G: v, B: v, A: v}
+}
+
+func mulColor(a, b gfx.Color) gfx.Color {
+	return gfx.Color{R: a.R * b.R, G: a.G * b.G, B: a.B * b.B, A: a.A * b.A}
+}
@@ -0,0 +1,375 @@
+// Copyright 2014 The Azul3D Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package soft
+
+import (
+	"image"
+	"math"
+
+	"azul3d.org/gfx.v1"
+	"azul3d.org/lmath.v1"
+)
+
+// drawState is the state of a single in-progress draw operation.
+type drawState struct {
+	gfx.State
+
+	// The model-view-projection matrix of the object.
+	mvp lmath.Mat4
+
+	// The rectangle of the canvas being drawn to, already clamped to the
+	// canvas bounds.
+	viewport image.Rectangle
+
+	// The sampler of the first texture, or nil if there is none.
+	tex *sampler
+
+	// The number of samples that passed the depth and stencil tests.
+	samples int
+}
+
+// vertex is a single vertex in clip space along with it's attributes.
+type vertex struct {
+	x, y, z, w float64
+	color      gfx.Color
+	uv         gfx.TexCoord
+}
+
+// lerp linearly interpolates between the vertices a and b.
+func (a vertex) lerp(b vertex, t float64) vertex {
+	tf := float32(t)
+	return vertex{
+		x: a.x + (b.x-a.x)*t,
+		y: a.y + (b.y-a.y)*t,
+		z: a.z + (b.z-a.z)*t,
+		w: a.w + (b.w-a.w)*t,
+		color: gfx.Color{
+			R: a.color.R + (b.color.R-a.color.R)*tf,
+			G: a.color.G + (b.color.G-a.color.G)*tf,
+			B: a.color.B + (b.color.B-a.color.B)*tf,
+			A: a.color.A + (b.color.A-a.color.A)*tf,
+		},
+		uv: gfx.TexCoord{
+			U: a.uv.U + (b.uv.U-a.uv.U)*tf,
+			V: a.uv.V + (b.uv.V-a.uv.V)*tf,
+		},
+	}
+}
+
+// winVertex is a single vertex in window coordinates. Attributes are divided
+// by w such that they can be interpolated with perspective correction.
+type winVertex struct {
+	x, y, z, invW float64
+	color         [4]float64
+	uv            [2]float64
+}
+
+var white = gfx.Color{R: 1, G: 1, B: 1, A: 1}
+
+// transform returns the clip space vertex at index i of the mesh.
+func (d *drawState) transform(m *nativeMesh, i uint32) vertex {
+	p := m.vertices[i]
+	x, y, z := float64(p.X), float64(p.Y), float64(p.Z)
+	v := vertex{
+		x:     x*d.mvp[0][0] + y*d.mvp[1][0] + z*d.mvp[2][0] + d.mvp[3][0],
+		y:     x*d.mvp[0][1] + y*d.mvp[1][1] + z*d.mvp[2][1] + d.mvp[3][1],
+		z:     x*d.mvp[0][2] + y*d.mvp[1][2] + z*d.mvp[2][2] + d.mvp[3][2],
+		w:     x*d.mvp[0][3] + y*d.mvp[1][3] + z*d.mvp[2][3] + d.mvp[3][3],
+		color: white,
+	}
+	if int(i) < len(m.colors) {
+		v.color = m.colors[i]
+	}
+	if len(m.texCoords) > 0 && int(i) < len(m.texCoords[0]) {
+		v.uv = m.texCoords[0][i]
+	}
+	return v
+}
+
+// drawMesh rasterizes each primitive of the given mesh.
+//
+// The canvas's access lock must be held for this method to operate safely.
+func (c *canvas) drawMesh(d *drawState, m *nativeMesh) {
+	n := uint32(len(m.vertices))
+	elements := len(m.vertices)
+	if len(m.indices) > 0 {
+		elements = len(m.indices)
+	}
+	p := m.primitive
+	var v [3]vertex
+primitives:
+	for i := 0; i < p.Count(elements); i++ {
+		for k := 0; k < p.Vertices(); k++ {
+			index := uint32(p.Element(i, k))
+			if len(m.indices) > 0 {
+				index = m.indices[index]
+			}
+			if index >= n {
+				continue primitives
+			}
+			v[k] = d.transform(m, index)
+		}
+		switch p.Vertices() {
+		case 1:
+			c.drawPoint(d, v[0])
+		case 2:
+			c.drawLine(d, v[0], v[1])
+		default:
+			c.drawTriangle(d, v[0], v[1], v[2])
+		}
+	}
+}
+
+// drawPoint rasterizes the clip space point as a square, d.PointSize pixels
+// wide, if it is not clipped by the near plane.
+func (c *canvas) drawPoint(d *drawState, p vertex) {
+	if p.z+p.w < 0 {
+		return
+	}
+	w := d.toWindow(p)
+	if w.z < 0 || w.z > 1 {
+		return
+	}
+	half := float64(d.PointSize) / 2
+	x0, y0, x1, y1 := d.clampBox(w.x-half, w.y-half, w.x+half, w.y+half)
+	stencil := d.StencilFront
+	for y := y0; y < y1; y++ {
+		for x := x0; x < x1; x++ {
+			c.shade(d, &stencil, x, y, d.fragment(w, w, 0))
+		}
+	}
+}
+
+// drawLine clips the clip space line segment against the near plane and
+// rasterizes the result as a rectangle, d.LineWidth pixels wide.
+func (c *canvas) drawLine(d *drawState, a, b vertex) {
+	aDist, bDist := a.z+a.w, b.z+b.w
+	switch {
+	case aDist < 0 && bDist < 0:
+		return
+	case aDist < 0:
+		a = a.lerp(b, aDist/(aDist-bDist))
+	case bDist < 0:
+		b = b.lerp(a, bDist/(bDist-aDist))
+	}
+	wa, wb := d.toWindow(a), d.toWindow(b)
+	dx, dy := wb.x-wa.x, wb.y-wa.y
+	length2 := dx*dx + dy*dy
+	if length2 == 0 || math.IsNaN(length2) {
+		return
+	}
+
+	half := float64(d.LineWidth) / 2
+	x0, y0, x1, y1 := d.clampBox(
+		math.Min(wa.x, wb.x)-half, math.Min(wa.y, wb.y)-half,
+		math.Max(wa.x, wb.x)+half, math.Max(wa.y, wb.y)+half,
+	)
+	length := math.Sqrt(length2)
+	stencil := d.StencilFront
+	for y := y0; y < y1; y++ {
+		py := float64(y) + 0.5
+		for x := x0; x < x1; x++ {
+			px := float64(x) + 0.5
+
+			// Position along the segment, and distance from it's center line.
+			t := ((px-wa.x)*dx + (py-wa.y)*dy) / length2
+			dist := math.Abs((px-wa.x)*dy-(py-wa.y)*dx) / length
+			if t < 0 || t >= 1 || dist > half {
+				continue
+			}
+			frag := d.fragment(wa, wb, t)
+			if frag.depth < 0 || frag.depth > 1 {
+				continue
+			}
+			c.shade(d, &stencil, x, y, frag)
+		}
+	}
+}
+
+// clampBox returns the pixel bounds of the given window-space box, clamped to
+// the viewport.
+func (d *drawState) clampBox(minX, minY, maxX, maxY float64) (x0, y0, x1, y1 int) {
+	vp := d.viewport
+	x0 = int(math.Max(math.Floor(minX+0.5), float64(vp.Min.X)))
+	y0 = int(math.Max(math.Floor(minY+0.5), float64(vp.Min.Y)))
+	x1 = int(math.Min(math.Floor(maxX+0.5), float64(vp.Max.X)))
+	y1 = int(math.Min(math.Floor(maxY+0.5), float64(vp.Max.Y)))
+	return
+}
+
+// fragment returns the fragment at t along the window-space segment from a to
+// b, interpolating with perspective correction.
+func (d *drawState) fragment(a, b winVertex, t float64) fragment {
+	invW := a.invW + (b.invW-a.invW)*t
+	lerp := func(x, y float64) float64 {
+		return (x + (y-x)*t) / invW
+	}
+	f := fragment{
+		depth: a.z + (b.z-a.z)*t,
+		color: gfx.Color{
+			R: float32(lerp(a.color[0], b.color[0])),
+			G: float32(lerp(a.color[1], b.color[1])),
+			B: float32(lerp(a.color[2], b.color[2])),
+			A: float32(lerp(a.color[3], b.color[3])),
+		},
+	}
+	if d.tex != nil {
+		f.color = mulColor(f.color, d.tex.sample(lerp(a.uv[0], b.uv[0]), lerp(a.uv[1], b.uv[1])))
+	}
+	return f
+}
+
+// drawTriangle clips the clip space triangle against the near plane and
+// rasterizes the result.
+func (c *canvas) drawTriangle(d *drawState, a, b, cc vertex) {
+	// Sutherland-Hodgman clipping against the near plane (z >= -w).
+	in := [3]vertex{a, b, cc}
+	var out [4]vertex
+	n := 0
+	for i := range in {
+		cur, next := in[i], in[(i+1)%3]
+		curDist, nextDist := cur.z+cur.w, next.z+next.w
+		if curDist >= 0 {
+			out[n] = cur
+			n++
+		}
+		if (curDist >= 0) != (nextDist >= 0) {
+			out[n] = cur.lerp(next, curDist/(curDist-nextDist))
+			n++
+		}
+	}
+	if n < 3 {
+		return
+	}
+
+	// Convert to window coordinates.
+	var win [4]winVertex
+	for i := 0; i < n; i++ {
+		win[i] = d.toWindow(out[i])
+	}
+
+	// The clipped polygon is convex, so fan triangulate it.
+	for i := 1; i+1 < n; i++ {
+		c.rasterize(d, win[0], win[i], win[i+1])
+	}
+}
+
+// toWindow performs the perspective divide and viewport transformation of the
+// clip space vertex.
+func (d *drawState) toWindow(v vertex) winVertex {
+	invW := 1 / v.w
+	vp := d.viewport
+	return winVertex{
+		x:    float64(vp.Min.X) + (v.x*invW+1)/2*float64(vp.Dx()),
+		y:    float64(vp.Min.Y) + (1-v.y*invW)/2*float64(vp.Dy()),
+		z:    (v.z*invW + 1) / 2,
+		invW: invW,
+		color: [4]float64{
+			float64(v.color.R) * invW,
+			float64(v.color.G) * invW,
+			float64(v.color.B) * invW,
+			float64(v.color.A) * invW,
+		},
+		uv: [2]float64{
+			float64(v.uv.U) * invW,
+			float64(v.uv.V) * invW,
+		},
+	}
+}
+
+// edge returns twice the signed area of the triangle a, b, (px, py).
+func edge(a, b winVertex, px, py float64) float64 {
+	return (b.x-a.x)*(py-a.y) - (b.y-a.y)*(px-a.x)
+}
+
+// topLeft tells if the edge from a to b is a top or left edge of a triangle
+// whose area is positive, following the usual top-left fill rule such that
+// pixels on edges shared by two triangles are only drawn once.
+func topLeft(a, b winVertex) bool {
+	return (a.y == b.y && b.x > a.x) || b.y < a.y
+}
+
+// inside tells if an edge function value is considered inside the triangle.
+func inside(w float64, topLeft bool) bool {
+	return w > 0 || (w == 0 && topLeft)
+}
+
+// rasterize rasterizes a single window-space triangle.
+func (c *canvas) rasterize(d *drawState, v0, v1, v2 winVertex) {
+	area := edge(v0, v1, v2.x, v2.y)
+	if area == 0 || math.IsNaN(area) {
+		return
+	}
+
+	// Window coordinates have Y pointing down, so a counter-clockwise
+	// triangle in normalized device coordinates has a negative area here.
+	front := area < 0
+	switch d.FaceCulling {
+	case gfx.BackFaceCulling:
+		if !front {
+			return
+		}
+	case gfx.FrontFaceCulling:
+		if front {
+			return
+		}
+	}
+	if area < 0 {
+		v1, v2 = v2, v1
+		area = -area
+	}
+	stencil := d.StencilBack
+	if front {
+		stencil = d.StencilFront
+	}
+
+	// Bounding box, clamped to the viewport.
+	minX := math.Floor(math.Min(v0.x, math.Min(v1.x, v2.x)))
+	minY := math.Floor(math.Min(v0.y, math.Min(v1.y, v2.y)))
+	maxX := math.Ceil(math.Max(v0.x, math.Max(v1.x, v2.x)))
+	maxY := math.Ceil(math.Max(v0.y, math.Max(v1.y, v2.y)))
+	vp := d.viewport
+	x0 := int(math.Max(minX, float64(vp.Min.X)))
+	y0 := int(math.Max(minY, float64(vp.Min.Y)))
+	x1 := int(math.Min(maxX, float64(vp.Max.X)))
+	y1 := int(math.Min(maxY, float64(vp.Max.Y)))
+
+	tl0, tl1, tl2 := topLeft(v1, v2), topLeft(v2, v0), topLeft(v0, v1)
+	for y := y0; y < y1; y++ {
+		py := float64(y) + 0.5
+		for x := x0; x < x1; x++ {
+			px := float64(x) + 0.5
+			w0 := edge(v1, v2, px, py)
+			w1 := edge(v2, v0, px, py)
+			w2 := edge(v0, v1, px, py)
+			if !inside(w0, tl0) || !inside(w1, tl1) || !inside(w2, tl2) {
+				continue
+			}
+			w0, w1, w2 = w0/area, w1/area, w2/area
+
+			// Depth is affine in window space; everything else is
+			// interpolated with perspective correction.
+			z := w0*v0.z + w1*v1.z + w2*v2.z
+			if z < 0 || z > 1 {
+				continue
+			}
+			invW := w0*v0.invW + w1*v1.invW + w2*v2.invW
+			var frag fragment
+			frag.depth = z
+			frag.color = gfx.Color{
+				R: float32((w0*v0.color[0] + w1*v1.color[0] + w2*v2.color[0]) / invW),
+				G: float32((w0*v0.color[1] + w1*v1.color[1] + w2*v2.color[1]) / invW),
+				B: float32((w0*v0.color[2] + w1*v1.color[2] + w2*v2.color[2]) / invW),
+				A: float32((w0*v0.color[3] + w1*v1.color[3] + w2*v2.color[3]) / invW),
+			}
+			if d.tex != nil {
+				u := (w0*v0.uv[0] + w1*v1.uv[0] + w2*v2.uv[0]) / invW
+				v := (w0*v0.uv[1] + w1*v1.uv[1] + w2*v2.uv[1]) / invW
+				frag.color = mulColor(frag.color, d.tex.sample(u, v))
+			}
+			c.shade(d, &stencil, x, y, frag)
+		}
+	}
+}
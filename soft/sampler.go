@@ -0,0 +1,112 @@
+// Copyright 2014 The Azul3D Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package soft
+
+import (
+	"math"
+
+	"azul3d.org/gfx.v1"
+)
+
+// sampler samples the texels of a texture according to it's wrap modes and
+// filtering.
+//
+// Mipmaps are not generated, so mipmapped minification filters behave as
+// their non-mipmapped counterparts and the magnification filter is always
+// used.
+type sampler struct {
+	texels
+	wrapU, wrapV gfx.TexWrap
+	border       gfx.Color
+	linear       bool
+}
+
+// newSampler returns a sampler for the loaded texture, or nil if the texture
+// has no texels.
+//
+// The texture's read lock must be held for this function to operate safely.
+func newSampler(t *gfx.Texture) *sampler {
+	tex := t.NativeTexture.(*nativeTexture).resolve()
+	if w, h := tex.size(); w == 0 || h == 0 {
+		return nil
+	}
+	return &sampler{
+		texels: tex,
+		wrapU:  t.WrapU,
+		wrapV:  t.WrapV,
+		border: t.BorderColor,
+		linear: t.MagFilter == gfx.Linear,
+	}
+}
+
+// wrap wraps the texel coordinate i into the range [0, n) according to the
+// wrap mode. If false is returned the border color should be used instead.
+func wrap(mode gfx.TexWrap, i, n int) (int, bool) {
+	switch mode {
+	case gfx.Clamp:
+		if i < 0 {
+			return 0, true
+		}
+		if i >= n {
+			return n - 1, true
+		}
+		return i, true
+	case gfx.BorderColor:
+		return i, i >= 0 && i < n
+	case gfx.Mirror:
+		i %= 2 * n
+		if i < 0 {
+			i += 2 * n
+		}
+		if i >= n {
+			i = 2*n - 1 - i
+		}
+		return i, true
+	}
+	i %= n
+	if i < 0 {
+		i += n
+	}
+	return i, true
+}
+
+// texel returns the wrapped texel at x, y.
+func (s *sampler) texel(x, y int) gfx.Color {
+	w, h := s.size()
+	x, okX := wrap(s.wrapU, x, w)
+	y, okY := wrap(s.wrapV, y, h)
+	if !okX || !okY {
+		return s.border
+	}
+	return s.at(x, y)
+}
+
+// sample samples the texture at the given texture coordinates, whose origin
+// is the top-left of the texture.
+func (s *sampler) sample(u, v float64) gfx.Color {
+	w, h := s.size()
+	x, y := u*float64(w), v*float64(h)
+	if !s.linear {
+		return s.texel(int(math.Floor(x)), int(math.Floor(y)))
+	}
+
+	x, y = x-0.5, y-0.5
+	fx, fy := math.Floor(x), math.Floor(y)
+	tx, ty := float32(x-fx), float32(y-fy)
+	ix, iy := int(fx), int(fy)
+	c00 := s.texel(ix, iy)
+	c10 := s.texel(ix+1, iy)
+	c01 := s.texel(ix, iy+1)
+	c11 := s.texel(ix+1, iy+1)
+	lerp := func(a, b gfx.Color, t float32) gfx.Color {
+		return gfx.Color{
+			R: a.R + (b.R-a.R)*t,
+			G: a.G + (b.G-a.G)*t,
+			B: a.B + (b.B-a.B)*t,
+			A: a.A + (b.A-a.A)*t,
+		}
+	}
+	return lerp(lerp(c00, c10, tx), lerp(c01, c11, tx), ty)
+}
@@ -0,0 +1,165 @@
+// Copyright 2014 The Azul3D Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package soft
+
+import (
+	"image"
+
+	"azul3d.org/clock.v1"
+	"azul3d.org/gfx.v1"
+)
+
+// The formats available for render-to-texture.
+var rttFormats = gfx.RTTFormats{
+	ColorFormats:   []gfx.TexFormat{gfx.RGBA, gfx.RGB},
+	DepthFormats:   []gfx.DSFormat{gfx.Depth16, gfx.Depth24, gfx.Depth32, gfx.Depth24AndStencil8},
+	StencilFormats: []gfx.DSFormat{gfx.Depth24AndStencil8},
+}
+
+type renderer struct {
+	*canvas
+
+	// The graphics clock.
+	clock *clock.Clock
+}
+
+func (r *renderer) Clock() *clock.Clock {
+	return r.clock
+}
+
+func (r *renderer) GPUInfo() gfx.GPUInfo {
+	return gfx.GPUInfo{
+		MaxTextureSize:        -1,
+		AlphaToCoverage:       false,
+		OcclusionQuery:        true,
+		OcclusionQueryBits:    32,
+		Name:                  "Software Rasterizer",
+		Vendor:                "Azul3D",
+		NPOT:                  true,
+		RTTFormats:            rttFormats,
+		GLMajor:               -1,
+		GLMinor:               -1,
+		GLSLMajor:             -1,
+		GLSLMinor:             -1,
+		GLSLMaxVaryingFloats:  -1,
+		GLSLMaxVertexInputs:   -1,
+		GLSLMaxFragmentInputs: -1,
+	}
+}
+
+func (r *renderer) Render() {
+	r.clock.Tick()
+}
+
+func (r *renderer) LoadMesh(m *gfx.Mesh, done chan *gfx.Mesh) {
+	m.Lock()
+	loadMesh(m)
+	m.Unlock()
+	select {
+	case done <- m:
+	default:
+	}
+}
+
+func (r *renderer) LoadTexture(t *gfx.Texture, done chan *gfx.Texture) {
+	t.Lock()
+	loadTexture(t)
+	t.Unlock()
+	select {
+	case done <- t:
+	default:
+	}
+}
+
+func (r *renderer) LoadShader(s *gfx.Shader, done chan *gfx.Shader) {
+	s.Lock()
+	loadShader(s)
+	s.Unlock()
+	select {
+	case done <- s:
+	default:
+	}
+}
+
+func hasTexFormat(formats []gfx.TexFormat, f gfx.TexFormat) bool {
+	for _, v := range formats {
+		if v == f {
+			return true
+		}
+	}
+	return false
+}
+
+func hasDSFormat(formats []gfx.DSFormat, f gfx.DSFormat) bool {
+	for _, v := range formats {
+		if v == f {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *renderer) RenderToTexture(cfg gfx.RTTConfig) gfx.Canvas {
+	if !cfg.Valid() {
+		panic("RenderToTexture(): invalid configuration")
+	}
+	if cfg.ColorFormat != gfx.ZeroTexFormat && !hasTexFormat(rttFormats.ColorFormats, cfg.ColorFormat) {
+		return nil
+	}
+	if cfg.DepthFormat != gfx.ZeroDSFormat && !hasDSFormat(rttFormats.DepthFormats, cfg.DepthFormat) {
+		return nil
+	}
+	if cfg.StencilFormat != gfx.ZeroDSFormat && !hasDSFormat(rttFormats.StencilFormats, cfg.StencilFormat) {
+		return nil
+	}
+
+	bounds := cfg.Bounds
+	if bounds.Empty() {
+		bounds = r.Bounds()
+	}
+	bounds = bounds.Sub(bounds.Min)
+	red, green, blue, alpha := cfg.ColorFormat.Bits()
+	c := newCanvas(bounds, gfx.Precision{
+		RedBits:     red,
+		GreenBits:   green,
+		BlueBits:    blue,
+		AlphaBits:   alpha,
+		DepthBits:   cfg.DepthFormat.DepthBits(),
+		StencilBits: cfg.StencilFormat.StencilBits(),
+	})
+
+	setup := func(t *gfx.Texture, native *nativeTexture) {
+		if t == nil {
+			return
+		}
+		t.Lock()
+		t.Loaded = true
+		t.ClearData()
+		t.Bounds = bounds
+		t.NativeTexture = native
+		t.Unlock()
+	}
+	setup(cfg.Color, &nativeTexture{texelSource: c, format: cfg.ColorFormat})
+	setup(cfg.Depth, &nativeTexture{texelSource: depthTexels{c}, format: gfx.RGBA})
+	setup(cfg.Stencil, &nativeTexture{texelSource: stencilTexels{c}, format: gfx.RGBA})
+	return c
+}
+
+// New returns a new software renderer whose canvas has the given bounds. Its
+// color buffer has 8 bits per component, its depth buffer has 24 bits and its
+// stencil buffer has 8 bits.
+func New(bounds image.Rectangle) gfx.Renderer {
+	return &renderer{
+		canvas: newCanvas(bounds, gfx.Precision{
+			RedBits:     8,
+			GreenBits:   8,
+			BlueBits:    8,
+			AlphaBits:   8,
+			DepthBits:   24,
+			StencilBits: 8,
+		}),
+		clock: clock.New(),
+	}
+}
@@ -0,0 +1,290 @@
+// Copyright 2014 The Azul3D Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package soft
+
+import (
+	"image"
+	"image/color"
+	"testing"
+
+	"azul3d.org/gfx.v1"
+	"azul3d.org/lmath.v1"
+)
+
+// quad returns an object with a single quad (two counter-clockwise triangles)
+// spanning the given rectangle in normalized device coordinates at depth z.
+func quad(x0, y0, x1, y1, z float32, c gfx.Color) *gfx.Object {
+	m := gfx.NewMesh()
+	m.Vertices = []gfx.Vec3{
+		{x0, y0, z}, {x1, y0, z}, {x1, y1, z},
+		{x0, y0, z}, {x1, y1, z}, {x0, y1, z},
+	}
+	m.Colors = []gfx.Color{c, c, c, c, c, c}
+	m.TexCoords = []gfx.TexCoordSet{{Slice: []gfx.TexCoord{
+		{0, 1}, {1, 1}, {1, 0},
+		{0, 1}, {1, 0}, {0, 0},
+	}}}
+	o := gfx.NewObject()
+	o.Shader = gfx.NewShader("test")
+	o.Meshes = []*gfx.Mesh{m}
+	return o
+}
+
+func download(r gfx.Canvas) *image.RGBA {
+	complete := make(chan image.Image, 1)
+	r.Download(image.Rect(0, 0, 0, 0), complete)
+	return (<-complete).(*image.RGBA)
+}
+
+var (
+	red   = gfx.Color{R: 1, A: 1}
+	green = gfx.Color{G: 1, A: 1}
+	black = gfx.Color{A: 1}
+)
+
+func expect(t *testing.T, img *image.RGBA, x, y int, want color.RGBA) {
+	if got := img.RGBAAt(x, y); got != want {
+		t.Errorf("pixel (%d, %d) = %v, want %v", x, y, got, want)
+	}
+}
+
+func TestDraw(t *testing.T) {
+	r := New(image.Rect(0, 0, 8, 8))
+	r.Clear(image.Rect(0, 0, 0, 0), black)
+	r.Draw(image.Rect(0, 0, 0, 0), quad(-1, -1, 1, 1, 0, red), nil)
+	r.Render()
+
+	img := download(r)
+	for y := 0; y < 8; y++ {
+		for x := 0; x < 8; x++ {
+			expect(t, img, x, y, color.RGBA{255, 0, 0, 255})
+		}
+	}
+}
+
+func TestDrawFaceCulling(t *testing.T) {
+	r := New(image.Rect(0, 0, 4, 4))
+	r.Clear(image.Rect(0, 0, 0, 0), black)
+
+	// Swapping the X coordinates makes the triangles clockwise.
+	o := quad(1, -1, -1, 1, 0, red)
+	r.Draw(image.Rect(0, 0, 0, 0), o, nil)
+	expect(t, download(r), 1, 1, color.RGBA{0, 0, 0, 255})
+
+	o.FaceCulling = gfx.FrontFaceCulling
+	r.Draw(image.Rect(0, 0, 0, 0), o, nil)
+	expect(t, download(r), 1, 1, color.RGBA{255, 0, 0, 255})
+}
+
+func TestDrawDepth(t *testing.T) {
+	r := New(image.Rect(0, 0, 4, 4))
+	r.Clear(image.Rect(0, 0, 0, 0), black)
+	r.ClearDepth(image.Rect(0, 0, 0, 0), 1.0)
+	r.Draw(image.Rect(0, 0, 0, 0), quad(-1, -1, 1, 1, -0.5, green), nil)
+	r.Draw(image.Rect(0, 0, 0, 0), quad(-1, -1, 1, 1, 0.5, red), nil)
+	expect(t, download(r), 2, 2, color.RGBA{0, 255, 0, 255})
+}
+
+func TestDrawStencil(t *testing.T) {
+	r := New(image.Rect(0, 0, 4, 4))
+	r.Clear(image.Rect(0, 0, 0, 0), black)
+	r.ClearStencil(image.Rect(0, 0, 0, 0), 0)
+
+	// Write a stencil value of one into the left half, without color.
+	mask := quad(-1, -1, 0, 1, 0, green)
+	mask.WriteRed, mask.WriteGreen, mask.WriteBlue, mask.WriteAlpha = false, false, false, false
+	mask.StencilTest = true
+	mask.StencilFront.Reference = 1
+	mask.StencilFront.DepthPass = gfx.SReplace
+	r.Draw(image.Rect(0, 0, 0, 0), mask, nil)
+
+	// Draw everywhere the stencil value equals one.
+	o := quad(-1, -1, 1, 1, 0, red)
+	o.DepthTest = false
+	o.StencilTest = true
+	o.StencilFront.Reference = 1
+	o.StencilFront.ReadMask = 0xFF
+	o.StencilFront.Cmp = gfx.Equal
+	r.Draw(image.Rect(0, 0, 0, 0), o, nil)
+
+	img := download(r)
+	expect(t, img, 0, 1, color.RGBA{255, 0, 0, 255})
+	expect(t, img, 3, 1, color.RGBA{0, 0, 0, 255})
+	if n := o.NativeObject.SampleCount(); n != 8 {
+		t.Errorf("SampleCount() = %d, want 8", n)
+	}
+}
+
+func TestDrawBlend(t *testing.T) {
+	r := New(image.Rect(0, 0, 2, 2))
+	r.Clear(image.Rect(0, 0, 0, 0), gfx.Color{R: 1, G: 1, B: 1, A: 1})
+
+	// Premultiplied half-transparent red.
+	o := quad(-1, -1, 1, 1, 0, gfx.Color{R: 0.5, A: 0.5})
+	o.AlphaMode = gfx.AlphaBlend
+	r.Draw(image.Rect(0, 0, 0, 0), o, nil)
+	expect(t, download(r), 0, 0, color.RGBA{255, 128, 128, 255})
+}
+
+func TestDrawTexture(t *testing.T) {
+	src := image.NewRGBA(image.Rect(0, 0, 2, 2))
+	src.Set(0, 0, color.RGBA{255, 0, 0, 255})
+	src.Set(1, 0, color.RGBA{0, 255, 0, 255})
+	src.Set(0, 1, color.RGBA{0, 0, 255, 255})
+	src.Set(1, 1, color.RGBA{255, 255, 255, 255})
+	tex := gfx.NewTexture()
+	tex.Source = src
+
+	r := New(image.Rect(0, 0, 4, 4))
+	r.LoadTexture(tex, nil)
+	o := quad(-1, -1, 1, 1, 0, gfx.Color{R: 1, G: 1, B: 1, A: 1})
+	o.Textures = []*gfx.Texture{tex}
+	r.Draw(image.Rect(0, 0, 0, 0), o, nil)
+
+	// The texture origin is the top-left.
+	img := download(r)
+	expect(t, img, 0, 0, color.RGBA{255, 0, 0, 255})
+	expect(t, img, 3, 0, color.RGBA{0, 255, 0, 255})
+	expect(t, img, 0, 3, color.RGBA{0, 0, 255, 255})
+	expect(t, img, 3, 3, color.RGBA{255, 255, 255, 255})
+}
+
+func TestDrawCamera(t *testing.T) {
+	bounds := image.Rect(0, 0, 16, 16)
+	r := New(bounds)
+	r.Clear(image.Rect(0, 0, 0, 0), black)
+
+	cam := gfx.NewCamera()
+	cam.SetPersp(bounds, 75, 0.1, 100)
+
+	// A quad facing the camera, ten units forward along the +Y axis.
+	o := gfx.NewObject()
+	o.Shader = gfx.NewShader("test")
+	m := gfx.NewMesh()
+	m.Vertices = []gfx.Vec3{
+		{-1, 0, -1}, {1, 0, -1}, {1, 0, 1},
+		{-1, 0, -1}, {1, 0, 1}, {-1, 0, 1},
+	}
+	o.Meshes = []*gfx.Mesh{m}
+	o.Transform.SetPos(lmath.Vec3{0, 10, 0})
+	r.Draw(image.Rect(0, 0, 0, 0), o, cam)
+
+	img := download(r)
+	expect(t, img, 8, 8, color.RGBA{255, 255, 255, 255})
+	expect(t, img, 0, 0, color.RGBA{0, 0, 0, 255})
+}
+
+func TestRenderToTexture(t *testing.T) {
+	r := New(image.Rect(0, 0, 8, 8))
+	tex := gfx.NewTexture()
+	rtt := r.RenderToTexture(gfx.RTTConfig{
+		Bounds:      image.Rect(0, 0, 4, 4),
+		Color:       tex,
+		ColorFormat: gfx.RGBA,
+	})
+	if rtt == nil {
+		t.Fatal("RenderToTexture returned nil")
+	}
+	rtt.Clear(image.Rect(0, 0, 0, 0), green)
+	rtt.Render()
+
+	complete := make(chan image.Image, 1)
+	tex.Download(image.Rect(0, 0, 0, 0), complete)
+	img := (<-complete).(*image.RGBA)
+	if img.Bounds() != image.Rect(0, 0, 4, 4) {
+		t.Fatalf("bounds = %v", img.Bounds())
+	}
+	expect(t, img, 1, 1, color.RGBA{0, 255, 0, 255})
+}
+
+func TestRenderToTextureSampled(t *testing.T) {
+	r := New(image.Rect(0, 0, 4, 4))
+	colorTex, depthTex := gfx.NewTexture(), gfx.NewTexture()
+	rtt := r.RenderToTexture(gfx.RTTConfig{
+		Bounds:      image.Rect(0, 0, 4, 4),
+		Color:       colorTex,
+		ColorFormat: gfx.RGBA,
+		Depth:       depthTex,
+		DepthFormat: gfx.Depth24,
+	})
+	rtt.Clear(image.Rect(0, 0, 0, 0), green)
+
+	// A canvas may sample it's own color buffer.
+	o := quad(-1, -1, 1, 1, 0, gfx.Color{R: 1, G: 1, B: 1, A: 1})
+	o.Textures = []*gfx.Texture{colorTex}
+	rtt.Draw(image.Rect(0, 0, 0, 0), o, nil)
+	expect(t, download(rtt), 1, 1, color.RGBA{0, 255, 0, 255})
+
+	// The depth buffer may be sampled while it is cleared.
+	done := make(chan bool)
+	go func() {
+		for i := 0; i < 10; i++ {
+			rtt.ClearDepth(image.Rect(0, 0, 0, 0), float64(i%2))
+		}
+		done <- true
+	}()
+	o = quad(-1, -1, 1, 1, 0, gfx.Color{R: 1, G: 1, B: 1, A: 1})
+	o.Textures = []*gfx.Texture{depthTex}
+	for i := 0; i < 10; i++ {
+		r.Draw(image.Rect(0, 0, 0, 0), o, nil)
+	}
+	<-done
+}
+
+func TestDrawPrimitives(t *testing.T) {
+	r := New(image.Rect(0, 0, 8, 8))
+	r.Clear(image.Rect(0, 0, 0, 0), black)
+
+	// A triangle strip covering the canvas.
+	o := quad(-1, -1, 1, 1, 0, red)
+	m := o.Meshes[0]
+	m.Primitive = gfx.TriangleStrip
+	m.Vertices = []gfx.Vec3{{-1, -1, 0}, {1, -1, 0}, {-1, 1, 0}, {1, 1, 0}}
+	m.Colors = m.Colors[:4]
+	m.TexCoords = nil
+	r.Draw(image.Rect(0, 0, 0, 0), o, nil)
+	img := download(r)
+	for y := 0; y < 8; y++ {
+		for x := 0; x < 8; x++ {
+			expect(t, img, x, y, color.RGBA{255, 0, 0, 255})
+		}
+	}
+	r.Clear(image.Rect(0, 0, 0, 0), black)
+	r.ClearDepth(image.Rect(0, 0, 0, 0), 1)
+
+	// A horizontal line across the center, two pixels wide.
+	o = quad(-1, -1, 1, 1, 0, green)
+	m = o.Meshes[0]
+	m.Primitive = gfx.Lines
+	m.Vertices = []gfx.Vec3{{-1, 0, 0}, {1, 0, 0}}
+	m.Colors = m.Colors[:2]
+	m.TexCoords = nil
+	o.LineWidth = 2
+	r.Draw(image.Rect(0, 0, 0, 0), o, nil)
+
+	// A point two pixels wide, centered at (6, 2) in window coordinates.
+	o = quad(-1, -1, 1, 1, 0, red)
+	m = o.Meshes[0]
+	m.Primitive = gfx.Points
+	m.Vertices = []gfx.Vec3{{0.5, 0.5, 0}}
+	m.Colors = m.Colors[:1]
+	m.TexCoords = nil
+	o.PointSize = 2
+	r.Draw(image.Rect(0, 0, 0, 0), o, nil)
+
+	img = download(r)
+	for x := 0; x < 8; x++ {
+		expect(t, img, x, 0, color.RGBA{0, 0, 0, 255})
+		if x < 5 || x > 6 {
+			expect(t, img, x, 2, color.RGBA{0, 0, 0, 255})
+		}
+		expect(t, img, x, 3, color.RGBA{0, 255, 0, 255})
+		expect(t, img, x, 4, color.RGBA{0, 255, 0, 255})
+		expect(t, img, x, 5, color.RGBA{0, 0, 0, 255})
+	}
+	for _, p := range []image.Point{{5, 1}, {6, 1}, {5, 2}, {6, 2}} {
+		expect(t, img, p.X, p.Y, color.RGBA{255, 0, 0, 255})
+	}
+}
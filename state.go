@@ -51,6 +51,13 @@ type State struct {
 
 	// The stencil state for front and back facing pixels, respectively.
 	StencilFront, StencilBack StencilState
+
+	// The size in pixels of points and the width in pixels of lines, when
+	// rendering meshes whose Primitive is Points, Lines, or LineStrip. Both
+	// must be greater than zero, and graphics hardware may clamp them to an
+	// implementation-defined range (most notably, many only support a
+	// LineWidth of 1).
+	PointSize, LineWidth float32
 }
 
 // Compare compares this state against the other one using DefaultState as a
@@ -102,6 +109,12 @@ func (s State) Compare(other State) bool {
 	if s.Dithering != other.Dithering {
 		return s.Dithering == DefaultState.Dithering
 	}
+	if s.PointSize != other.PointSize {
+		return s.PointSize == DefaultState.PointSize
+	}
+	if s.LineWidth != other.LineWidth {
+		return s.LineWidth == DefaultState.LineWidth
+	}
 	return true
 }
 
@@ -121,4 +134,6 @@ var DefaultState = State{
 	FaceCulling:  BackFaceCulling,
 	StencilFront: DefaultStencilState,
 	StencilBack:  DefaultStencilState,
+	PointSize:    1,
+	LineWidth:    1,
 }
@@ -0,0 +1,111 @@
+// Copyright 2014 The Azul3D Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package stats
+
+import "azul3d.org/gfx.v1"
+
+// StateChanges counts the graphics state changes between consecutive draw
+// operations, broken down by the kind of state that changed. A single draw
+// operation may change several kinds of state.
+type StateChanges struct {
+	// Changes to the alpha mode or blend state.
+	Blend int
+
+	// Changes to DepthTest, DepthWrite, or DepthCmp.
+	Depth int
+
+	// Changes to StencilTest, StencilFront, or StencilBack.
+	Stencil int
+
+	// Changes to the face culling mode.
+	Cull int
+
+	// Changes to the color write masks, dithering, the point size, or the line
+	// width.
+	Other int
+
+	// Changes of the shader program.
+	Shader int
+
+	// Changes to the list of textures or the named texture bindings.
+	Textures int
+}
+
+// Total returns the total number of state changes.
+func (s StateChanges) Total() int {
+	return s.Blend + s.Depth + s.Stencil + s.Cull + s.Other + s.Shader + s.Textures
+}
+
+// Frame holds the statistics of a single frame (i.e. everything between two
+// calls to Render).
+type Frame struct {
+	// The number of draw operations, and the number of triangles and vertices
+	// they submitted.
+	DrawCalls, Triangles, Vertices int
+
+	// The number of line segments and points submitted by draw operations
+	// (see gfx.Mesh.Primitive).
+	Lines, Points int
+
+	// The number of mesh, texture, and shader uploads. An upload is counted
+	// once per resource in a frame, if it is explicitly loaded or used by a
+	// draw operation while not loaded or (for meshes) having data marked as
+	// changed.
+	MeshUploads, TextureUploads, ShaderUploads int
+
+	// The state changes between consecutive draw operations of the frame.
+	StateChanges StateChanges
+}
+
+// drawState is the part of an object that is compared between consecutive
+// draw operations.
+type drawState struct {
+	gfx.State
+	shader   *gfx.Shader
+	textures []*gfx.Texture
+	samplers map[string]*gfx.Texture
+}
+
+// count adds the state changes from a to b to the counts.
+func (s *StateChanges) count(a, b *drawState) {
+	if a.AlphaMode != b.AlphaMode || a.Blend != b.Blend {
+		s.Blend++
+	}
+	if a.DepthTest != b.DepthTest || a.DepthWrite != b.DepthWrite || a.DepthCmp != b.DepthCmp {
+		s.Depth++
+	}
+	if a.StencilTest != b.StencilTest || a.StencilFront != b.StencilFront || a.StencilBack != b.StencilBack {
+		s.Stencil++
+	}
+	if a.FaceCulling != b.FaceCulling {
+		s.Cull++
+	}
+	if a.WriteRed != b.WriteRed || a.WriteGreen != b.WriteGreen || a.WriteBlue != b.WriteBlue || a.WriteAlpha != b.WriteAlpha || a.Dithering != b.Dithering || a.PointSize != b.PointSize || a.LineWidth != b.LineWidth {
+		s.Other++
+	}
+	if a.shader != b.shader {
+		s.Shader++
+	}
+	if len(a.textures) != len(b.textures) {
+		s.Textures++
+		return
+	}
+	for i, t := range a.textures {
+		if b.textures[i] != t {
+			s.Textures++
+			return
+		}
+	}
+	if len(a.samplers) != len(b.samplers) {
+		s.Textures++
+		return
+	}
+	for name, t := range a.samplers {
+		if b.samplers[name] != t {
+			s.Textures++
+			return
+		}
+	}
+}
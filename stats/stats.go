@@ -0,0 +1,338 @@
+// Copyright 2014 The Azul3D Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package stats implements a gfx.Renderer wrapper that collects per-frame
+// rendering statistics.
+//
+// The statistics include the number of draw calls, primitives and vertices,
+// the number of mesh, texture and shader uploads, and the number of graphics
+// state changes between consecutive draw operations. For example, to check
+// whether sorting objects by state helps:
+//  r := stats.New(renderer, 60)
+//  sort.Sort(gfx.ByState(objects))
+//  ... draw the objects using r ...
+//  r.Render()
+//  fmt.Println(r.Last().StateChanges.Total())
+package stats
+
+import (
+	"image"
+	"sync"
+
+	"azul3d.org/clock.v1"
+	"azul3d.org/gfx.v1"
+)
+
+// meshCount is the number of vertices and primitives of a mesh. It is recorded
+// when the mesh is uploaded, as the data slices of a mesh are typically cleared
+// once it is loaded.
+type meshCount struct {
+	vertices, triangles, lines, points int
+}
+
+// collector collects the statistics of every canvas of a renderer.
+type collector struct {
+	access sync.Mutex
+
+	// The frame in progress.
+	frame Frame
+
+	// The state of the previous draw operation in this frame, or nil if there
+	// was none.
+	last *drawState
+
+	// The meshes, textures and shaders whose upload was counted in this
+	// frame, such that each is counted at most once per frame.
+	uploaded map[interface{}]bool
+
+	// Ring buffer of completed frames, next is the index that the next
+	// completed frame is stored at.
+	history []Frame
+	next    int
+	full    bool
+
+	// Counts of the meshes recorded when they were uploaded, kept until the
+	// mesh is uploaded again or drawn with it's data present.
+	meshes map[*gfx.Mesh]meshCount
+}
+
+// upload counts an upload of the given resource into n, unless one was already
+// counted for it in this frame.
+//
+// The collector's access lock must be held for this method to operate safely.
+func (c *collector) upload(resource interface{}, n *int) {
+	if !c.uploaded[resource] {
+		c.uploaded[resource] = true
+		*n++
+	}
+}
+
+// countMesh returns the counts of the mesh. If the mesh has no data (e.g. it
+// was cleared once loaded) the counts recorded when it was uploaded are used.
+// Otherwise the counts are recorded if upload is true, as the data may be
+// cleared by the upload, or are forgotten if upload is false, as the data is
+// still present.
+//
+// The collector's access lock and the mesh's read lock must be held for this
+// method to operate safely.
+func (c *collector) countMesh(m *gfx.Mesh, upload bool) meshCount {
+	if len(m.Vertices) == 0 {
+		return c.meshes[m]
+	}
+	n := meshCount{vertices: len(m.Vertices)}
+	elements := len(m.Vertices)
+	if len(m.Indices) > 0 {
+		elements = len(m.Indices)
+	}
+	switch m.Primitive.Vertices() {
+	case 1:
+		n.points = m.Primitive.Count(elements)
+	case 2:
+		n.lines = m.Primitive.Count(elements)
+	default:
+		n.triangles = m.Primitive.Count(elements)
+	}
+	if upload {
+		c.meshes[m] = n
+	} else {
+		delete(c.meshes, m)
+	}
+	return n
+}
+
+// draw collects the statistics of drawing the given meshes of the object. It
+// properly read-locks the object and it's resources.
+func (c *collector) draw(o *gfx.Object, meshes []*gfx.Mesh) {
+	c.access.Lock()
+	defer c.access.Unlock()
+
+	o.RLock()
+	defer o.RUnlock()
+
+	c.frame.DrawCalls++
+	for _, m := range meshes {
+		m.RLock()
+		upload := !m.Loaded || m.HasChanged()
+		n := c.countMesh(m, upload)
+		if upload {
+			c.upload(m, &c.frame.MeshUploads)
+		}
+		m.RUnlock()
+		c.frame.Vertices += n.vertices
+		c.frame.Triangles += n.triangles
+		c.frame.Lines += n.lines
+		c.frame.Points += n.points
+	}
+	countTexture := func(t *gfx.Texture) {
+		if t == nil {
+			return
+		}
+		t.RLock()
+		if !t.Loaded {
+			c.upload(t, &c.frame.TextureUploads)
+		}
+		t.RUnlock()
+	}
+	for _, t := range o.Textures {
+		countTexture(t)
+	}
+	for _, t := range o.Samplers {
+		countTexture(t)
+	}
+	if o.Shader != nil {
+		o.Shader.RLock()
+		if !o.Shader.Loaded {
+			c.upload(o.Shader, &c.frame.ShaderUploads)
+		}
+		o.Shader.RUnlock()
+	}
+
+	s := &drawState{
+		State:    o.State,
+		shader:   o.Shader,
+		textures: append([]*gfx.Texture(nil), o.Textures...),
+		samplers: make(map[string]*gfx.Texture, len(o.Samplers)),
+	}
+	for name, t := range o.Samplers {
+		s.samplers[name] = t
+	}
+	if c.last != nil {
+		c.frame.StateChanges.count(c.last, s)
+	}
+	c.last = s
+}
+
+// render completes the frame in progress and stores it in the history.
+func (c *collector) render() {
+	c.access.Lock()
+	if len(c.history) > 0 {
+		c.history[c.next] = c.frame
+		c.next = (c.next + 1) % len(c.history)
+		if c.next == 0 {
+			c.full = true
+		}
+	}
+	c.frame = Frame{}
+	c.last = nil
+	c.uploaded = make(map[interface{}]bool)
+	c.access.Unlock()
+}
+
+// Canvas wraps a gfx.Canvas and collects statistics of the draw operations
+// submitted to it.
+type Canvas struct {
+	gfx.Canvas
+	c *collector
+}
+
+// Implements gfx.Canvas interface.
+func (c *Canvas) Draw(r image.Rectangle, o *gfx.Object, cam *gfx.Camera) {
+	c.c.draw(o, drawnMeshes(o, cam))
+	c.Canvas.Draw(r, o, cam)
+}
+
+// NotifyResize implements the gfx.ResizeNotifier interface by forwarding to
+// the wrapped canvas. If the wrapped canvas does not implement it, no
+// notifications are ever sent.
+func (c *Canvas) NotifyResize(ch chan image.Rectangle) {
+	if n, ok := c.Canvas.(gfx.ResizeNotifier); ok {
+		n.NotifyResize(ch)
+	}
+}
+
+// StopNotifyResize implements the gfx.ResizeNotifier interface by forwarding to
+// the wrapped canvas.
+func (c *Canvas) StopNotifyResize(ch chan image.Rectangle) {
+	if n, ok := c.Canvas.(gfx.ResizeNotifier); ok {
+		n.StopNotifyResize(ch)
+	}
+}
+
+// drawnMeshes returns the meshes of the object that are drawn through the
+// camera, that is those of the level of detail selected for it (see the
+// gfx.Object.SelectLOD method). As the selection is stable, the canvas being
+// wrapped selects the same level again.
+func drawnMeshes(o *gfx.Object, cam *gfx.Camera) []*gfx.Mesh {
+	if cam == nil {
+		o.RLock()
+		defer o.RUnlock()
+		return o.Meshes
+	}
+	cam.RLock()
+	defer cam.RUnlock()
+	return o.SelectLOD(cam)
+}
+
+// Renderer wraps a gfx.Renderer and collects the statistics of each frame. The
+// frames are delimited by calls to it's Render method. Draw operations
+// submitted to render-to-texture canvases (see RenderToTexture) are included
+// in the statistics of the renderer's frame.
+type Renderer struct {
+	*Canvas
+	r gfx.Renderer
+}
+
+// Frame returns the statistics of the frame in progress.
+func (r *Renderer) Frame() Frame {
+	r.c.access.Lock()
+	f := r.c.frame
+	r.c.access.Unlock()
+	return f
+}
+
+// Last returns the statistics of the most recently completed frame, or the
+// zero value if there is none (or no history is kept).
+func (r *Renderer) Last() Frame {
+	r.c.access.Lock()
+	defer r.c.access.Unlock()
+	if len(r.c.history) == 0 || (r.c.next == 0 && !r.c.full) {
+		return Frame{}
+	}
+	i := r.c.next - 1
+	if i < 0 {
+		i = len(r.c.history) - 1
+	}
+	return r.c.history[i]
+}
+
+// History returns the statistics of the most recently completed frames, oldest
+// first. At most as many frames as were requested by New are returned.
+func (r *Renderer) History() []Frame {
+	r.c.access.Lock()
+	defer r.c.access.Unlock()
+	if !r.c.full {
+		return append([]Frame(nil), r.c.history[:r.c.next]...)
+	}
+	h := make([]Frame, 0, len(r.c.history))
+	h = append(h, r.c.history[r.c.next:]...)
+	return append(h, r.c.history[:r.c.next]...)
+}
+
+// Implements gfx.Canvas interface.
+func (r *Renderer) Render() {
+	r.c.render()
+	r.r.Render()
+}
+
+// Implements gfx.Renderer interface.
+func (r *Renderer) Clock() *clock.Clock {
+	return r.r.Clock()
+}
+
+// Implements gfx.Renderer interface.
+func (r *Renderer) GPUInfo() gfx.GPUInfo {
+	return r.r.GPUInfo()
+}
+
+// Implements gfx.Renderer interface.
+func (r *Renderer) LoadMesh(m *gfx.Mesh, done chan *gfx.Mesh) {
+	r.c.access.Lock()
+	m.RLock()
+	r.c.countMesh(m, true)
+	m.RUnlock()
+	r.c.upload(m, &r.c.frame.MeshUploads)
+	r.c.access.Unlock()
+	r.r.LoadMesh(m, done)
+}
+
+// Implements gfx.Renderer interface.
+func (r *Renderer) LoadTexture(t *gfx.Texture, done chan *gfx.Texture) {
+	r.c.access.Lock()
+	r.c.upload(t, &r.c.frame.TextureUploads)
+	r.c.access.Unlock()
+	r.r.LoadTexture(t, done)
+}
+
+// Implements gfx.Renderer interface.
+func (r *Renderer) LoadShader(s *gfx.Shader, done chan *gfx.Shader) {
+	r.c.access.Lock()
+	r.c.upload(s, &r.c.frame.ShaderUploads)
+	r.c.access.Unlock()
+	r.r.LoadShader(s, done)
+}
+
+// RenderToTexture implements the gfx.Renderer interface. The returned canvas,
+// if not nil, is a *Canvas whose statistics are collected by this renderer.
+func (r *Renderer) RenderToTexture(cfg gfx.RTTConfig) gfx.Canvas {
+	c := r.r.RenderToTexture(cfg)
+	if c == nil {
+		return nil
+	}
+	return &Canvas{Canvas: c, c: r.c}
+}
+
+// New returns a new renderer that wraps the given one and collects statistics
+// of each frame, keeping the given number of most recently completed frames
+// in it's history.
+func New(r gfx.Renderer, history int) *Renderer {
+	c := &collector{
+		history:  make([]Frame, history),
+		uploaded: make(map[interface{}]bool),
+		meshes:   make(map[*gfx.Mesh]meshCount),
+	}
+	return &Renderer{
+		Canvas: &Canvas{Canvas: r, c: c},
+		r:      r,
+	}
+}
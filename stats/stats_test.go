@@ -0,0 +1,85 @@
+// Copyright 2014 The Azul3D Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package stats
+
+import (
+	"sort"
+	"testing"
+
+	"azul3d.org/gfx.v1"
+)
+
+func drawAll(r *Renderer, objects []*gfx.Object) {
+	for _, o := range objects {
+		r.Draw(r.Bounds(), o, nil)
+	}
+	r.Render()
+}
+
+func TestStats(t *testing.T) {
+	r := New(gfx.Nil(), 2)
+
+	m := gfx.NewMesh()
+	m.Vertices = []gfx.Vec3{{0, 0, 0}, {1, 0, 0}, {0, 1, 0}, {1, 1, 0}}
+	m.Indices = []uint32{0, 1, 2, 2, 1, 3}
+
+	// Objects whose depth state alternates.
+	var objects []*gfx.Object
+	for i := 0; i < 10; i++ {
+		o := gfx.NewObject()
+		o.Meshes = []*gfx.Mesh{m}
+		o.DepthTest = i%2 == 0
+		objects = append(objects, o)
+	}
+
+	drawAll(r, objects)
+	f := r.Last()
+	if f.DrawCalls != 10 || f.Triangles != 20 || f.Vertices != 40 {
+		t.Fatalf("got %d draws, %d triangles, %d vertices", f.DrawCalls, f.Triangles, f.Vertices)
+	}
+	if f.MeshUploads != 1 {
+		t.Fatalf("got %d mesh uploads, want 1", f.MeshUploads)
+	}
+	if f.StateChanges.Depth != 9 || f.StateChanges.Total() != 9 {
+		t.Fatalf("unsorted: got %+v", f.StateChanges)
+	}
+
+	sort.Sort(gfx.ByState(objects))
+	drawAll(r, objects)
+	if got := r.Last().StateChanges.Total(); got != 1 {
+		t.Fatalf("sorted: got %d state changes, want 1", got)
+	}
+
+	// A mesh that is loaded explicitly and then drawn is uploaded once.
+	m2 := gfx.NewMesh()
+	m2.Vertices = []gfx.Vec3{{0, 0, 0}, {1, 0, 0}, {0, 1, 0}}
+	r.LoadMesh(m2, nil)
+	o := gfx.NewObject()
+	o.Meshes = []*gfx.Mesh{m2}
+	drawAll(r, []*gfx.Object{o})
+	if f := r.Last(); f.MeshUploads != 1 || f.Triangles != 1 {
+		t.Fatalf("got %d mesh uploads and %d triangles", f.MeshUploads, f.Triangles)
+	}
+
+	// It's data was cleared once loaded, but it's counts are kept.
+	drawAll(r, []*gfx.Object{o})
+	if f := r.Last(); f.MeshUploads != 0 || f.Triangles != 1 {
+		t.Fatalf("got %d mesh uploads and %d triangles", f.MeshUploads, f.Triangles)
+	}
+
+	// It's counts are kept even when it is not drawn for a few frames.
+	drawAll(r, nil)
+	drawAll(r, nil)
+	drawAll(r, []*gfx.Object{o})
+	if f := r.Last(); f.MeshUploads != 0 || f.Triangles != 1 || f.Vertices != 3 {
+		t.Fatalf("got %d mesh uploads, %d triangles, %d vertices", f.MeshUploads, f.Triangles, f.Vertices)
+	}
+
+	// The history holds the last two frames only.
+	h := r.History()
+	if len(h) != 2 || h[0].DrawCalls != 0 || h[1].DrawCalls != 1 {
+		t.Fatalf("got history %+v", h)
+	}
+}
@@ -81,6 +81,10 @@ type Transform struct {
 
 	// The position, rotation, scaling, and shearing components.
 	pos, rot, scale, shear lmath.Vec3
+
+	// A pointer to the exact local matrix set via SetMat4, used in place of
+	// the components until one of them is changed, or nil.
+	mat *lmath.Mat4
 }
 
 // Equals tells if the two transforms are equal.
@@ -114,6 +118,12 @@ func (t *Transform) Equals(other *Transform) bool {
 	if !t.shear.Equals(other.shear) {
 		goto fail
 	}
+	if (t.mat != nil) != (other.mat != nil) {
+		goto fail
+	}
+	if t.mat != nil && !(*t.mat).Equals(*other.mat) {
+		goto fail
+	}
 
 	t.access.RUnlock()
 	other.access.RUnlock()
@@ -144,22 +154,27 @@ func (t *Transform) build() {
 		t.lastParent = &parentMat
 	}
 
-	// Apply rotation
-	var hpr lmath.Vec3
-	if t.quat != nil {
-		// Use quaternion rotation.
-		hpr = (*t.quat).Hpr(lmath.CoordSysZUpRight)
+	// Build this space's transformation matrix.
+	var built lmath.Mat4
+	if t.mat != nil {
+		// Use the exact matrix.
+		built = *t.mat
 	} else {
-		// Use euler rotation.
-		hpr = t.rot.XyzToHpr().Radians()
-	}
-
-	// Compose upper 3x3 matrics using scale, shear, and HPR components.
-	scaleShearHpr := lmath.Mat3Compose(t.scale, t.shear, hpr, lmath.CoordSysZUpRight)
+		// Apply rotation
+		var hpr lmath.Vec3
+		if t.quat != nil {
+			// Use quaternion rotation.
+			hpr = (*t.quat).Hpr(lmath.CoordSysZUpRight)
+		} else {
+			// Use euler rotation.
+			hpr = t.rot.XyzToHpr().Radians()
+		}
 
-	// Build this space's transformation matrix.
-	built := lmath.Mat4Identity.SetUpperMat3(scaleShearHpr)
-	built = built.SetTranslation(t.pos)
+		// Compose upper 3x3 matrics using scale, shear, and HPR components.
+		scaleShearHpr := lmath.Mat3Compose(t.scale, t.shear, hpr, lmath.CoordSysZUpRight)
+		built = lmath.Mat4Identity.SetUpperMat3(scaleShearHpr)
+		built = built.SetTranslation(t.pos)
+	}
 	t.built = &built
 
 	// Build the local-to-world transformation matrix.
@@ -200,6 +215,44 @@ func (t *Transform) LocalMat4() lmath.Mat4 {
 	return l
 }
 
+// SetMat4 sets the local matrix (see LocalMat4) of this transform to exactly
+// m, which is kept until the position, rotation, scale, or shear is changed.
+//
+// The components of this transform are set to those decomposed from m: it's
+// position, quaternion rotation, and scale (a mirroring matrix is decomposed
+// into a negative scale on the X axis). Shear is not recovered from m, instead
+// the shear component is reset to zero.
+func (t *Transform) SetMat4(m lmath.Mat4) {
+	upper := m.UpperMat3()
+	var (
+		scale [3]float64
+		rot   lmath.Mat3
+	)
+	for i := 0; i < 3; i++ {
+		row := lmath.Vec3{X: upper[i][0], Y: upper[i][1], Z: upper[i][2]}
+		scale[i] = row.Length()
+		if scale[i] != 0 {
+			row = row.DivScalar(scale[i])
+		}
+		rot[i] = [3]float64{row.X, row.Y, row.Z}
+	}
+	if rot.Determinant() < 0 {
+		// Mirrored, negate the X axis.
+		scale[0] = -scale[0]
+		rot[0] = [3]float64{-rot[0][0], -rot[0][1], -rot[0][2]}
+	}
+	q := lmath.QuatFromMat3(rot)
+
+	t.access.Lock()
+	t.built = nil
+	t.mat = &m
+	t.quat = &q
+	t.pos = m.Translation()
+	t.scale = lmath.Vec3{X: scale[0], Y: scale[1], Z: scale[2]}
+	t.shear = lmath.Vec3{}
+	t.access.Unlock()
+}
+
 // SetParent sets a parent transform for this transform to effectively inherit
 // from. This allows creating complex hierarchies of transformations.
 //
@@ -228,8 +281,9 @@ func (t *Transform) Parent() Transformable {
 // whether quaternion or euler rotation will be used by this transform.
 func (t *Transform) SetQuat(q lmath.Quat) {
 	t.access.Lock()
-	if (*t.quat) != q {
+	if t.quat == nil || (*t.quat) != q {
 		t.built = nil
+		t.mat = nil
 		t.quat = &q
 	}
 	t.access.Unlock()
@@ -276,6 +330,7 @@ func (t *Transform) SetRot(r lmath.Vec3) {
 	t.access.Lock()
 	if t.rot != r {
 		t.built = nil
+		t.mat = nil
 		t.quat = nil
 		t.rot = r
 	}
@@ -306,6 +361,7 @@ func (t *Transform) SetPos(p lmath.Vec3) {
 	t.access.Lock()
 	if t.pos != p {
 		t.built = nil
+		t.mat = nil
 		t.pos = p
 	}
 	t.access.Unlock()
@@ -327,6 +383,7 @@ func (t *Transform) SetScale(s lmath.Vec3) {
 	t.access.Lock()
 	if t.scale != s {
 		t.built = nil
+		t.mat = nil
 		t.scale = s
 	}
 	t.access.Unlock()
@@ -345,6 +402,7 @@ func (t *Transform) SetShear(s lmath.Vec3) {
 	t.access.Lock()
 	if t.shear != s {
 		t.built = nil
+		t.mat = nil
 		t.shear = s
 	}
 	t.access.Unlock()
@@ -370,6 +428,7 @@ func (t *Transform) Reset() {
 	t.rot = lmath.Vec3Zero
 	t.scale = lmath.Vec3One
 	t.shear = lmath.Vec3Zero
+	t.mat = nil
 	t.access.Unlock()
 }
 
@@ -400,6 +459,10 @@ func (t *Transform) Copy() *Transform {
 		quatCpy := *t.quat
 		cpy.quat = &quatCpy
 	}
+	if t.mat != nil {
+		matCpy := *t.mat
+		cpy.mat = &matCpy
+	}
 	t.access.RUnlock()
 	return cpy
 }
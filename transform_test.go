@@ -122,3 +122,54 @@ func TestTransformRotToWorld(t *testing.T) {
 		t.Fail()
 	}
 }
+
+func TestTransformSetQuat(t *testing.T) {
+	// A new transform uses euler rotation, so it has no quaternion yet.
+	tf := NewTransform()
+	q := lmath.Quat{W: 0, X: 1, Y: 0, Z: 0}
+	tf.SetQuat(q)
+	if !tf.IsQuat() {
+		t.Fatal("expected quaternion rotation after SetQuat")
+	}
+	if got := tf.Quat(); got != q {
+		t.Fatalf("got %v, want %v", got, q)
+	}
+}
+
+func TestTransformSetMat4(t *testing.T) {
+	a := NewTransform()
+	a.SetPos(lmath.Vec3{X: 1, Y: 2, Z: 3})
+	a.SetScale(lmath.Vec3{X: -2, Y: 3, Z: 4})
+	m := a.LocalMat4()
+
+	b := NewTransform()
+	b.SetShear(lmath.Vec3{X: 1})
+	b.SetMat4(m)
+	if !b.IsQuat() {
+		t.Fatal("expected quaternion rotation after SetMat4")
+	}
+	if got := b.Pos(); !got.Equals(a.Pos()) {
+		t.Fatalf("got position %v, want %v", got, a.Pos())
+	}
+	if got := b.Scale(); !got.Equals(a.Scale()) {
+		t.Fatalf("got scale %v, want %v", got, a.Scale())
+	}
+	if got := b.Shear(); got != (lmath.Vec3{}) {
+		t.Fatalf("got shear %v, want none", got)
+	}
+	if got := b.LocalMat4(); !got.Equals(m) {
+		t.Fatalf("got matrix %v, want %v", got, m)
+	}
+
+	// A sheared matrix is kept exactly, until a component is changed.
+	sheared := lmath.Mat4Identity
+	sheared[0][1] = 2
+	b.SetMat4(sheared)
+	if got := b.LocalMat4(); !got.Equals(sheared) {
+		t.Fatalf("got matrix %v, want %v", got, sheared)
+	}
+	b.SetPos(lmath.Vec3{X: 1})
+	if got := b.LocalMat4(); got[0][1] != 0 || got[3][0] != 1 {
+		t.Fatalf("got matrix %v after SetPos", got)
+	}
+}
@@ -39,6 +39,48 @@ func ConvertMat4(m lmath.Mat4) Mat4 {
 	}
 }
 
+// Mat3 represents a 32-bit floating point 3x3 matrix for compatability with
+// graphics hardware.
+// lmath.Mat3 should be used anywhere that an explicit 32-bit type is not
+// needed.
+type Mat3 [3][3]float32
+
+// Mat3 converts this 32-bit Mat3 to a 64-bit lmath.Mat3 matrix.
+func (m Mat3) Mat3() lmath.Mat3 {
+	return lmath.Mat3{
+		[3]float64{float64(m[0][0]), float64(m[0][1]), float64(m[0][2])},
+		[3]float64{float64(m[1][0]), float64(m[1][1]), float64(m[1][2])},
+		[3]float64{float64(m[2][0]), float64(m[2][1]), float64(m[2][2])},
+	}
+}
+
+// ConvertMat3 converts the 64-bit lmath.Mat3 to a 32-bit Mat3 matrix.
+func ConvertMat3(m lmath.Mat3) Mat3 {
+	return Mat3{
+		[3]float32{float32(m[0][0]), float32(m[0][1]), float32(m[0][2])},
+		[3]float32{float32(m[1][0]), float32(m[1][1]), float32(m[1][2])},
+		[3]float32{float32(m[2][0]), float32(m[2][1]), float32(m[2][2])},
+	}
+}
+
+// Vec2 represents a 32-bit floating point two-component vector for
+// compatability with graphics hardware.
+// lmath.Vec2 should be used anywhere that an explicit 32-bit type is not
+// needed.
+type Vec2 struct {
+	X, Y float32
+}
+
+// Vec2 converts this 32-bit Vec2 to a 64-bit lmath.Vec2 vector.
+func (v Vec2) Vec2() lmath.Vec2 {
+	return lmath.Vec2{X: float64(v.X), Y: float64(v.Y)}
+}
+
+// ConvertVec2 converts the 64-bit lmath.Vec2 to a 32-bit Vec2 vector.
+func ConvertVec2(v lmath.Vec2) Vec2 {
+	return Vec2{X: float32(v.X), Y: float32(v.Y)}
+}
+
 // Vec3 represents a 32-bit floating point three-component vector for
 // compatability with graphics hardware.
 // lmath.Vec3 should be used anywhere that an explicit 32-bit type is not
@@ -67,10 +109,61 @@ type Vec4 struct {
 
 // Vec4 converts this 32-bit Vec4 to a 64-bit lmath.Vec4 vector.
 func (v Vec4) Vec4() lmath.Vec4 {
-	return lmath.Vec4{X: float64(v.X), Y: float64(v.Y), Z: float64(v.Z)}
+	return lmath.Vec4{X: float64(v.X), Y: float64(v.Y), Z: float64(v.Z), W: float64(v.W)}
 }
 
 // ConvertVec4 converts the 64-bit lmath.Vec4 to a 32-bit Vec4 vector.
 func ConvertVec4(v lmath.Vec4) Vec4 {
 	return Vec4{X: float32(v.X), Y: float32(v.Y), Z: float32(v.Z), W: float32(v.W)}
 }
+
+// IVec2 represents a 32-bit integer two-component vector for compatability
+// with graphics hardware (e.g. a GLSL ivec2).
+type IVec2 struct {
+	X, Y int32
+}
+
+// Vec2 converts this integer vector to a 64-bit lmath.Vec2 vector.
+func (v IVec2) Vec2() lmath.Vec2 {
+	return lmath.Vec2{X: float64(v.X), Y: float64(v.Y)}
+}
+
+// ConvertIVec2 converts the 64-bit lmath.Vec2 to a 32-bit integer vector. Each
+// component is truncated toward zero.
+func ConvertIVec2(v lmath.Vec2) IVec2 {
+	return IVec2{X: int32(v.X), Y: int32(v.Y)}
+}
+
+// IVec3 represents a 32-bit integer three-component vector for compatability
+// with graphics hardware (e.g. a GLSL ivec3).
+type IVec3 struct {
+	X, Y, Z int32
+}
+
+// Vec3 converts this integer vector to a 64-bit lmath.Vec3 vector.
+func (v IVec3) Vec3() lmath.Vec3 {
+	return lmath.Vec3{X: float64(v.X), Y: float64(v.Y), Z: float64(v.Z)}
+}
+
+// ConvertIVec3 converts the 64-bit lmath.Vec3 to a 32-bit integer vector. Each
+// component is truncated toward zero.
+func ConvertIVec3(v lmath.Vec3) IVec3 {
+	return IVec3{X: int32(v.X), Y: int32(v.Y), Z: int32(v.Z)}
+}
+
+// IVec4 represents a 32-bit integer four-component vector for compatability
+// with graphics hardware (e.g. a GLSL ivec4).
+type IVec4 struct {
+	X, Y, Z, W int32
+}
+
+// Vec4 converts this integer vector to a 64-bit lmath.Vec4 vector.
+func (v IVec4) Vec4() lmath.Vec4 {
+	return lmath.Vec4{X: float64(v.X), Y: float64(v.Y), Z: float64(v.Z), W: float64(v.W)}
+}
+
+// ConvertIVec4 converts the 64-bit lmath.Vec4 to a 32-bit integer vector. Each
+// component is truncated toward zero.
+func ConvertIVec4(v lmath.Vec4) IVec4 {
+	return IVec4{X: int32(v.X), Y: int32(v.Y), Z: int32(v.Z), W: int32(v.W)}
+}
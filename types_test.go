@@ -0,0 +1,77 @@
+// Copyright 2014 The Azul3D Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package gfx
+
+import (
+	"testing"
+
+	"azul3d.org/lmath.v1"
+)
+
+func TestVecRoundTrip(t *testing.T) {
+	v2 := lmath.Vec2{X: 1.5, Y: -2}
+	if got := ConvertVec2(v2).Vec2(); got != v2 {
+		t.Errorf("Vec2: got %v, want %v", got, v2)
+	}
+	v3 := lmath.Vec3{X: 1.5, Y: -2, Z: 3.25}
+	if got := ConvertVec3(v3).Vec3(); got != v3 {
+		t.Errorf("Vec3: got %v, want %v", got, v3)
+	}
+	v4 := lmath.Vec4{X: 1.5, Y: -2, Z: 3.25, W: 4}
+	if got := ConvertVec4(v4).Vec4(); got != v4 {
+		t.Errorf("Vec4: got %v, want %v", got, v4)
+	}
+}
+
+func TestIVecRoundTrip(t *testing.T) {
+	if got := ConvertIVec2(lmath.Vec2{X: 1.9, Y: -2.9}); got != (IVec2{1, -2}) {
+		t.Errorf("IVec2: got %v, not truncated toward zero", got)
+	}
+	v2 := lmath.Vec2{X: 1, Y: -2}
+	if got := ConvertIVec2(v2).Vec2(); got != v2 {
+		t.Errorf("IVec2: got %v, want %v", got, v2)
+	}
+	v3 := lmath.Vec3{X: 1, Y: -2, Z: 3}
+	if got := ConvertIVec3(v3).Vec3(); got != v3 {
+		t.Errorf("IVec3: got %v, want %v", got, v3)
+	}
+	v4 := lmath.Vec4{X: 1, Y: -2, Z: 3, W: 4}
+	if got := ConvertIVec4(v4).Vec4(); got != v4 {
+		t.Errorf("IVec4: got %v, want %v", got, v4)
+	}
+}
+
+func TestMatRoundTrip(t *testing.T) {
+	m3 := lmath.Mat3{
+		{1, 2, 3},
+		{4, 5, 6},
+		{7, 8, 9.5},
+	}
+	if got := ConvertMat3(m3).Mat3(); got != m3 {
+		t.Errorf("Mat3: got %v, want %v", got, m3)
+	}
+	m4 := lmath.Mat4{
+		{1, 2, 3, 4},
+		{5, 6, 7, 8},
+		{9, 10, 11, 12},
+		{13, 14, 15, 16.5},
+	}
+	if got := ConvertMat4(m4).Mat4(); got != m4 {
+		t.Errorf("Mat4: got %v, want %v", got, m4)
+	}
+}
+
+func TestVertexAttribCopy(t *testing.T) {
+	data := [][]Vec2{{{1, 2}}, {{3, 4}}}
+	cpy := VertexAttrib{Data: data, Changed: true}.Copy()
+	data[0][0].X = 0
+	c, ok := cpy.Data.([][]Vec2)
+	if !ok || len(c) != 2 || c[0][0].X != 1 || cpy.Changed {
+		t.Fatal("got copy", cpy)
+	}
+	if cpy := (VertexAttrib{Data: []int{1}}).Copy(); cpy.Data != nil {
+		t.Fatal("copied unsupported data", cpy.Data)
+	}
+}
@@ -0,0 +1,248 @@
+// Copyright 2014 The Azul3D Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package validate
+
+import (
+	"fmt"
+	"reflect"
+
+	"azul3d.org/gfx.v1"
+)
+
+// checker checks the values involved in a single operation and reports any
+// violations.
+type checker struct {
+	op     string
+	object *gfx.Object
+	info   gfx.GPUInfo
+	report func(err *Error)
+}
+
+func (c *checker) errorf(value interface{}, format string, args ...interface{}) {
+	c.report(&Error{
+		Op:     c.op,
+		Object: c.object,
+		Value:  value,
+		Msg:    fmt.Sprintf(format, args...),
+	})
+}
+
+// shader checks the inputs of the shader.
+//
+// The shader's read lock must be held for this method to operate safely.
+func (c *checker) shader(s *gfx.Shader) {
+	for name, v := range s.Inputs {
+		if !gfx.ValidInput(v) {
+			c.errorf(s, "shader %q: input %q has unsupported type %T", s.Name, name, v)
+		}
+	}
+}
+
+// mesh checks the data slices of the mesh. The mesh is identified in error
+// messages by the given name.
+//
+// The mesh's read lock must be held for this method to operate safely.
+func (c *checker) mesh(name string, m *gfx.Mesh) {
+	n := len(m.Vertices)
+	if n == 0 {
+		// Either the mesh is empty or the data was cleared after loading.
+		return
+	}
+	if m.Primitive > gfx.LineStrip {
+		c.errorf(m, "%s: invalid Primitive %v", name, m.Primitive)
+	}
+	if len(m.Indices) > 0 {
+		c.primitives(m, name, "Indices", len(m.Indices))
+		for i, index := range m.Indices {
+			if int(index) >= n {
+				c.errorf(m, "%s: Indices[%d] == %d is out of range of %d vertices", name, i, index, n)
+				break
+			}
+		}
+	} else {
+		c.primitives(m, name, "Vertices", n)
+	}
+	if len(m.Bary) > 0 && m.Primitive.Vertices() != 3 {
+		c.errorf(m, "%s: barycentric coordinates used with %v", name, m.Primitive)
+	}
+
+	length := func(field string, l int) {
+		if l != 0 && l != n {
+			c.errorf(m, "%s: len(%s) == %d, want len(Vertices) == %d", name, field, l, n)
+		}
+	}
+	length("Normals", len(m.Normals))
+	length("Tangents", len(m.Tangents))
+	length("Colors", len(m.Colors))
+	length("Bary", len(m.Bary))
+	for i, set := range m.TexCoords {
+		length(fmt.Sprintf("TexCoords[%d].Slice", i), len(set.Slice))
+	}
+	for attr, a := range m.Attribs {
+		if !gfx.ValidAttrib(a.Data) {
+			c.errorf(m, "%s: attribute %q has unsupported type %T", name, attr, a.Data)
+			continue
+		}
+		v := reflect.ValueOf(a.Data)
+		if v.Type().Elem().Kind() != reflect.Slice {
+			length(fmt.Sprintf("Attribs[%q].Data", attr), v.Len())
+			continue
+		}
+		for i := 0; i < v.Len(); i++ {
+			length(fmt.Sprintf("Attribs[%q].Data[%d]", attr, i), v.Index(i).Len())
+		}
+	}
+}
+
+// primitives checks that the given number of vertices (or indices) of the mesh
+// form whole primitives. The slice is identified in error messages by the given
+// field name.
+func (c *checker) primitives(m *gfx.Mesh, name, field string, n int) {
+	p := m.Primitive
+	switch p {
+	case gfx.Triangles, gfx.Lines:
+		if n%p.Vertices() != 0 {
+			c.errorf(m, "%s: len(%s) == %d is not a multiple of %d (%v)", name, field, n, p.Vertices(), p)
+		}
+	case gfx.TriangleStrip, gfx.TriangleFan, gfx.LineStrip:
+		if p.Count(n) == 0 {
+			c.errorf(m, "%s: len(%s) == %d is too few to form a primitive (%v)", name, field, n, p)
+		}
+	}
+}
+
+// texture checks the parameters of the texture. The texture is identified in
+// error messages by the given name.
+//
+// The texture's read lock must be held for this method to operate safely.
+func (c *checker) texture(name string, t *gfx.Texture) {
+	if t.MagFilter.Mipmapped() {
+		c.errorf(t, "%s: mipmapped filter %v used as MagFilter", name, t.MagFilter)
+	}
+	if t.MinFilter > gfx.LinearMipmapLinear {
+		c.errorf(t, "%s: invalid MinFilter %v", name, t.MinFilter)
+	}
+	if t.WrapU > gfx.Mirror || t.WrapV > gfx.Mirror {
+		c.errorf(t, "%s: invalid wrap mode (%v, %v)", name, t.WrapU, t.WrapV)
+	}
+	if t.Format > gfx.DXT5 {
+		c.errorf(t, "%s: invalid Format %v", name, t.Format)
+	}
+	if t.Source != nil && !t.Bounds.Empty() && t.Source.Bounds().Size() != t.Bounds.Size() {
+		c.errorf(t, "%s: Bounds %v does not match the source image bounds %v", name, t.Bounds, t.Source.Bounds())
+	}
+}
+
+// state checks the render state of the object.
+func (c *checker) state(o *gfx.Object, s gfx.State) {
+	if s.AlphaMode > gfx.AlphaToCoverage {
+		c.errorf(o, "invalid AlphaMode %v", s.AlphaMode)
+	}
+	if s.AlphaMode == gfx.AlphaToCoverage && !c.info.AlphaToCoverage {
+		c.errorf(o, "AlphaToCoverage used but GPUInfo.AlphaToCoverage == false")
+	}
+	if s.Blend.DstRGB == gfx.BSrcAlphaSaturate {
+		c.errorf(o, "BSrcAlphaSaturate used as Blend.DstRGB")
+	}
+	ops := []gfx.BlendOp{s.Blend.SrcRGB, s.Blend.DstRGB, s.Blend.SrcAlpha, s.Blend.DstAlpha}
+	for _, op := range ops {
+		if op > gfx.BSrcAlphaSaturate {
+			c.errorf(o, "invalid blend operand %d", op)
+		}
+	}
+	if s.Blend.RGBEq > gfx.BReverseSub || s.Blend.AlphaEq > gfx.BReverseSub {
+		c.errorf(o, "invalid blend equation (%d, %d)", s.Blend.RGBEq, s.Blend.AlphaEq)
+	}
+	if s.DepthCmp > gfx.NotEqual {
+		c.errorf(o, "invalid DepthCmp %d", s.DepthCmp)
+	}
+	if s.FaceCulling > gfx.NoFaceCulling {
+		c.errorf(o, "invalid FaceCulling %v", s.FaceCulling)
+	}
+	stencil := func(face string, st gfx.StencilState) {
+		if st.Cmp > gfx.NotEqual {
+			c.errorf(o, "%s: invalid Cmp %d", face, st.Cmp)
+		}
+		for _, op := range []gfx.StencilOp{st.Fail, st.DepthFail, st.DepthPass} {
+			if op > gfx.SInvert {
+				c.errorf(o, "%s: invalid stencil operation %d", face, op)
+			}
+		}
+	}
+	stencil("StencilFront", s.StencilFront)
+	stencil("StencilBack", s.StencilBack)
+}
+
+// draw checks the object and it's shader, meshes, and textures. It properly
+// read-locks each of them.
+func (c *checker) draw(o *gfx.Object) {
+	o.RLock()
+	defer o.RUnlock()
+
+	c.state(o, o.State)
+	if o.Transform == nil {
+		c.errorf(o, "nil Transform")
+	}
+	if o.Shader != nil {
+		o.Shader.RLock()
+		c.shader(o.Shader)
+		o.Shader.RUnlock()
+	}
+	for name, v := range o.InputOverrides {
+		if !gfx.ValidInput(v) {
+			c.errorf(o, "object input %q has unsupported type %T", name, v)
+		}
+	}
+	var points, lines bool
+	meshes := func(name string, meshes []*gfx.Mesh) {
+		for i, m := range meshes {
+			if m == nil {
+				c.errorf(o, "%s[%d] is nil", name, i)
+				continue
+			}
+			m.RLock()
+			c.mesh(fmt.Sprintf("%s[%d]", name, i), m)
+			points = points || m.Primitive == gfx.Points
+			lines = lines || m.Primitive == gfx.Lines || m.Primitive == gfx.LineStrip
+			m.RUnlock()
+		}
+	}
+	meshes("Meshes", o.Meshes)
+	for i, lod := range o.LODs {
+		if lod.Distance < 0 || lod.Coverage < 0 {
+			c.errorf(o, "LODs[%d]: negative threshold (Distance %v, Coverage %v)", i, lod.Distance, lod.Coverage)
+		} else if lod.Distance == 0 && lod.Coverage == 0 {
+			c.errorf(o, "LODs[%d]: neither Distance nor Coverage is set, the level is never used", i)
+		}
+		meshes(fmt.Sprintf("LODs[%d].Meshes", i), lod.Meshes)
+	}
+	if o.LODHysteresis < 0 || o.LODHysteresis >= 1 {
+		c.errorf(o, "LODHysteresis %v is not in the range of 0.0 to 1.0", o.LODHysteresis)
+	}
+	if points && !(o.PointSize > 0) {
+		c.errorf(o, "PointSize %v used to draw points is not greater than zero", o.PointSize)
+	}
+	if lines && !(o.LineWidth > 0) {
+		c.errorf(o, "LineWidth %v used to draw lines is not greater than zero", o.LineWidth)
+	}
+	for i, t := range o.Textures {
+		if t == nil {
+			c.errorf(o, "Textures[%d] is nil", i)
+			continue
+		}
+		t.RLock()
+		c.texture(fmt.Sprintf("Textures[%d]", i), t)
+		t.RUnlock()
+	}
+	for name, t := range o.Samplers {
+		if t == nil {
+			c.errorf(o, "Samplers[%q] is nil", name)
+			continue
+		}
+		t.RLock()
+		c.texture(fmt.Sprintf("Samplers[%q]", name), t)
+		t.RUnlock()
+	}
+}
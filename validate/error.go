@@ -0,0 +1,40 @@
+// Copyright 2014 The Azul3D Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package validate
+
+import (
+	"fmt"
+
+	"azul3d.org/gfx.v1"
+)
+
+// Error describes a single violation of the gfx.Canvas or gfx.Renderer
+// contracts.
+type Error struct {
+	// The name of the operation during which the violation was detected, e.g.
+	// "Draw" or "LoadMesh".
+	Op string
+
+	// The object being drawn, or nil if the operation was not a draw
+	// operation.
+	Object *gfx.Object
+
+	// The value which violates the contract, one of:
+	//  *gfx.Object
+	//  *gfx.Mesh
+	//  *gfx.Texture
+	//  *gfx.Shader
+	//  gfx.RTTConfig
+	//  float64 (the depth value passed to ClearDepth)
+	Value interface{}
+
+	// A description of the violation.
+	Msg string
+}
+
+// Error implements the error interface.
+func (e *Error) Error() string {
+	return fmt.Sprintf("validate: %s: %s", e.Op, e.Msg)
+}
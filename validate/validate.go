@@ -0,0 +1,159 @@
+// Copyright 2014 The Azul3D Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package validate implements a gfx.Renderer wrapper that enforces the rules of
+// the gfx.Canvas and gfx.Renderer contracts.
+//
+// Renderers silently ignore most invalid input (e.g. vertex attributes of
+// unsupported types), which makes mistakes hard to find. The validating
+// renderer instead checks every value passed to it and reports each violation
+// as an *Error describing the operation and the object involved, before
+// passing the operation on to the wrapped renderer:
+//  r := validate.New(renderer, func(err *validate.Error) {
+//      log.Println(err, err.Object)
+//  })
+//
+// Checks include (but are not limited to):
+//  Mesh data slices whose length does not match the Vertices slice.
+//  Mesh indices that are out of range.
+//  Mesh vertices (or indices) that do not form whole primitives.
+//  Points or lines drawn with a PointSize or LineWidth of zero.
+//  Levels of detail without a switch distance or screen coverage.
+//  Mipmapped filters used as a texture's MagFilter.
+//  BSrcAlphaSaturate used as a blend state's DstRGB.
+//  Invalid render-to-texture configurations (see RTTConfig.Valid).
+//  Shader inputs and vertex attributes of unsupported types.
+//  AlphaToCoverage used when GPUInfo.AlphaToCoverage is false.
+package validate
+
+import (
+	"image"
+
+	"azul3d.org/clock.v1"
+	"azul3d.org/gfx.v1"
+)
+
+// Canvas wraps a gfx.Canvas and validates each operation submitted to it.
+type Canvas struct {
+	gfx.Canvas
+	info   gfx.GPUInfo
+	report func(err *Error)
+}
+
+func (c *Canvas) checker(op string, o *gfx.Object) *checker {
+	return &checker{op: op, object: o, info: c.info, report: c.report}
+}
+
+// Implements gfx.Canvas interface.
+func (c *Canvas) ClearDepth(r image.Rectangle, depth float64) {
+	if depth < 0 || depth > 1 {
+		c.checker("ClearDepth", nil).errorf(depth, "depth %v is not in the range of 0.0 to 1.0", depth)
+	}
+	c.Canvas.ClearDepth(r, depth)
+}
+
+// Implements gfx.Canvas interface.
+func (c *Canvas) Draw(r image.Rectangle, o *gfx.Object, cam *gfx.Camera) {
+	if o == nil {
+		c.checker("Draw", nil).errorf(o, "nil object")
+		return
+	}
+	c.checker("Draw", o).draw(o)
+	c.Canvas.Draw(r, o, cam)
+}
+
+// NotifyResize implements the gfx.ResizeNotifier interface by forwarding to
+// the wrapped canvas. If the wrapped canvas does not implement it, no
+// notifications are ever sent.
+func (c *Canvas) NotifyResize(ch chan image.Rectangle) {
+	if n, ok := c.Canvas.(gfx.ResizeNotifier); ok {
+		n.NotifyResize(ch)
+	}
+}
+
+// StopNotifyResize implements the gfx.ResizeNotifier interface by forwarding to
+// the wrapped canvas.
+func (c *Canvas) StopNotifyResize(ch chan image.Rectangle) {
+	if n, ok := c.Canvas.(gfx.ResizeNotifier); ok {
+		n.StopNotifyResize(ch)
+	}
+}
+
+// Renderer wraps a gfx.Renderer and validates each operation submitted to it.
+type Renderer struct {
+	*Canvas
+	r gfx.Renderer
+}
+
+// Implements gfx.Renderer interface.
+func (r *Renderer) Clock() *clock.Clock {
+	return r.r.Clock()
+}
+
+// Implements gfx.Renderer interface.
+func (r *Renderer) GPUInfo() gfx.GPUInfo {
+	return r.r.GPUInfo()
+}
+
+// Implements gfx.Renderer interface.
+func (r *Renderer) LoadMesh(m *gfx.Mesh, done chan *gfx.Mesh) {
+	m.RLock()
+	r.checker("LoadMesh", nil).mesh("mesh", m)
+	m.RUnlock()
+	r.r.LoadMesh(m, done)
+}
+
+// Implements gfx.Renderer interface.
+func (r *Renderer) LoadTexture(t *gfx.Texture, done chan *gfx.Texture) {
+	t.RLock()
+	r.checker("LoadTexture", nil).texture("texture", t)
+	t.RUnlock()
+	r.r.LoadTexture(t, done)
+}
+
+// Implements gfx.Renderer interface.
+func (r *Renderer) LoadShader(s *gfx.Shader, done chan *gfx.Shader) {
+	s.RLock()
+	r.checker("LoadShader", nil).shader(s)
+	s.RUnlock()
+	r.r.LoadShader(s, done)
+}
+
+// RenderToTexture implements the gfx.Renderer interface. If the configuration
+// is not valid an error is reported and nil is returned (instead of causing a
+// panic). The returned canvas, if not nil, is a *Canvas that validates it's
+// operations as well.
+func (r *Renderer) RenderToTexture(cfg gfx.RTTConfig) gfx.Canvas {
+	if !cfg.Valid() {
+		r.checker("RenderToTexture", nil).errorf(cfg, "invalid configuration (see RTTConfig.Valid)")
+		return nil
+	}
+	c := r.r.RenderToTexture(cfg)
+	if c == nil {
+		return nil
+	}
+	return &Canvas{
+		Canvas: c,
+		info:   r.info,
+		report: r.report,
+	}
+}
+
+// New returns a new validating renderer that wraps the given one. The report
+// function is invoked with each violation, if it is nil a panic occurs instead.
+func New(r gfx.Renderer, report func(err *Error)) *Renderer {
+	if report == nil {
+		report = func(err *Error) {
+			panic(err)
+		}
+	}
+	return &Renderer{
+		Canvas: &Canvas{
+			Canvas: r,
+			info:   r.GPUInfo(),
+			report: report,
+		},
+		r: r,
+	}
+}
@@ -0,0 +1,122 @@
+// Copyright 2014 The Azul3D Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package validate
+
+import (
+	"image"
+	"testing"
+
+	"azul3d.org/gfx.v1"
+)
+
+func collect() (*[]*Error, func(err *Error)) {
+	var errs []*Error
+	return &errs, func(err *Error) {
+		errs = append(errs, err)
+	}
+}
+
+func TestValidDraw(t *testing.T) {
+	errs, report := collect()
+	r := New(gfx.Nil(), report)
+
+	m := gfx.NewMesh()
+	m.Vertices = []gfx.Vec3{{0, 0, 0}, {1, 0, 0}, {0, 1, 0}}
+	m.Colors = []gfx.Color{{}, {}, {}}
+	m.Attribs["Weights"] = gfx.VertexAttrib{Data: [][]float32{{1, 2, 3}, {4, 5, 6}}}
+
+	o := gfx.NewObject()
+	o.Shader = gfx.NewShader("valid")
+	o.Shader.Inputs["Tint"] = gfx.Vec4{1, 1, 1, 1}
+	o.Meshes = []*gfx.Mesh{m}
+	r.Draw(image.Rect(0, 0, 0, 0), o, nil)
+
+	if len(*errs) != 0 {
+		t.Fatal("unexpected errors:", *errs)
+	}
+}
+
+func TestInvalidDraw(t *testing.T) {
+	errs, report := collect()
+	r := New(gfx.Nil(), report)
+
+	m := gfx.NewMesh()
+	m.Vertices = []gfx.Vec3{{0, 0, 0}, {1, 0, 0}, {0, 1, 0}}
+	m.Indices = []uint32{0, 1, 3}
+	m.Colors = []gfx.Color{{}, {}}
+	m.Attribs["Bad"] = gfx.VertexAttrib{Data: []int{1, 2, 3}}
+
+	tex := gfx.NewTexture()
+	tex.MagFilter = gfx.LinearMipmapLinear
+
+	o := gfx.NewObject()
+	o.Shader = gfx.NewShader("invalid")
+	o.Shader.Inputs["Bad"] = 1.0
+	o.InputOverrides["AlsoBad"] = 1
+	o.Meshes = []*gfx.Mesh{m}
+	o.Textures = []*gfx.Texture{tex}
+	o.AlphaMode = gfx.AlphaToCoverage
+	o.Blend.DstRGB = gfx.BSrcAlphaSaturate
+	r.Draw(image.Rect(0, 0, 0, 0), o, nil)
+
+	// Indices, Colors, Bad attribute, MagFilter, AlphaToCoverage (the nil
+	// renderer supports it, so no error), DstRGB, the Bad input and the
+	// AlsoBad object input.
+	if len(*errs) != 7 {
+		for _, err := range *errs {
+			t.Log(err)
+		}
+		t.Fatalf("got %d errors, want 7", len(*errs))
+	}
+	for _, err := range *errs {
+		if err.Op != "Draw" || err.Object != o {
+			t.Error("error does not identify the operation and object:", err)
+		}
+	}
+}
+
+func TestPrimitives(t *testing.T) {
+	errs, report := collect()
+	r := New(gfx.Nil(), report)
+
+	points := gfx.NewMesh()
+	points.Primitive = gfx.Points
+	points.Vertices = []gfx.Vec3{{0, 0, 0}, {1, 0, 0}}
+
+	strip := gfx.NewMesh()
+	strip.Primitive = gfx.TriangleStrip
+	strip.Vertices = []gfx.Vec3{{0, 0, 0}, {1, 0, 0}, {0, 1, 0}, {1, 1, 0}}
+
+	o := gfx.NewObject()
+	o.Shader = gfx.NewShader("primitives")
+	o.Meshes = []*gfx.Mesh{points, strip}
+	r.Draw(image.Rect(0, 0, 0, 0), o, nil)
+	if len(*errs) != 0 {
+		t.Fatal("unexpected errors:", *errs)
+	}
+
+	// Three vertices do not form whole lines, and lines may not be zero
+	// pixels wide.
+	lines := gfx.NewMesh()
+	lines.Primitive = gfx.Lines
+	lines.Vertices = []gfx.Vec3{{0, 0, 0}, {1, 0, 0}, {0, 1, 0}}
+	o.Meshes = []*gfx.Mesh{lines}
+	o.LineWidth = 0
+	r.Draw(image.Rect(0, 0, 0, 0), o, nil)
+	if len(*errs) != 2 {
+		t.Fatal("got errors:", *errs)
+	}
+}
+
+func TestInvalidRTT(t *testing.T) {
+	errs, report := collect()
+	r := New(gfx.Nil(), report)
+	if c := r.RenderToTexture(gfx.RTTConfig{}); c != nil {
+		t.Error("expected a nil canvas")
+	}
+	if len(*errs) != 1 || (*errs)[0].Op != "RenderToTexture" {
+		t.Fatal("expected a single RenderToTexture error, got", *errs)
+	}
+}